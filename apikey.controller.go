@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/apikey"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/config"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/respository"
+	"github.com/labstack/echo/v4"
+)
+
+// APIKeyController is the controller for the API key admin API
+type APIKeyController struct {
+	manager    *apikey.Manager
+	pagination config.PaginationConfig // Default and maximum page sizes of list requests
+}
+
+// NewAPIKeyController creates a new API key controller
+func NewAPIKeyController(
+	manager *apikey.Manager,
+	pagination config.PaginationConfig,
+) *APIKeyController {
+	return &APIKeyController{manager: manager, pagination: pagination}
+}
+
+// Register registers the API key routes on the group
+func (ac *APIKeyController) Register(group *echo.Group) {
+	group.POST("", ac.CreateAPIKey).Name = "apikey.create"
+	group.GET("", ac.GetAllAPIKeys).Name = "apikey.list"
+	group.POST("/:id/rotate", ac.RotateAPIKey).Name = "apikey.rotate"
+	group.POST("/:id/revoke", ac.RevokeAPIKey).Name = "apikey.revoke"
+}
+
+// CreateAPIKey issues a new API key
+//
+// POST /api/v1/admin/api-keys
+func (ac *APIKeyController) CreateAPIKey(c echo.Context) error {
+	var body CreateAPIKeyRequest
+	if err := c.Bind(&body); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "invalid request body",
+		})
+	}
+
+	// Validate the request body
+	if err := body.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{"error": err})
+	}
+
+	// Issue the key
+	key, plain, err := ac.manager.Create(body.Name, body.Scopes, body.ExpiresAt)
+	if err != nil && errors.Is(err, apikey.ErrInvalidScope) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err != nil {
+		return err
+	}
+
+	// Return the key, this is the only time the plain text key is shown
+	return c.JSON(http.StatusCreated, APIKeyResponse{Key: plain, APIKey: key})
+}
+
+// GetAllAPIKeys retrieves all API keys, without their secrets
+//
+// GET /api/v1/admin/api-keys
+func (ac *APIKeyController) GetAllAPIKeys(c echo.Context) error {
+	// Get the page and limit query parameters
+	page, limit, err := parsePagination(c, ac.pagination)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	// Retrieve the keys
+	keys, total := ac.manager.List(page, limit)
+
+	// Return the list response
+	return c.JSON(http.StatusOK, ListResponse{Page: page, Limit: limit, Total: total, Data: keys})
+}
+
+// RotateAPIKey replaces the secret of an API key
+//
+// POST /api/v1/admin/api-keys/:id/rotate
+func (ac *APIKeyController) RotateAPIKey(c echo.Context) error {
+	// Get the key ID from the URL
+	id, err := models.ParseID(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid api key ID"})
+	}
+
+	// Rotate the key
+	key, plain, err := ac.manager.Rotate(id)
+	if err != nil && errors.Is(err, respository.ErrRecordNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "api key not found"})
+	}
+	if err != nil && errors.Is(err, apikey.ErrRevokedKey) {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "api key is revoked"})
+	}
+	if err != nil {
+		return err
+	}
+
+	// Return the key, this is the only time the new plain text key is shown
+	return c.JSON(http.StatusOK, APIKeyResponse{Key: plain, APIKey: key})
+}
+
+// RevokeAPIKey permanently disables an API key
+//
+// POST /api/v1/admin/api-keys/:id/revoke
+func (ac *APIKeyController) RevokeAPIKey(c echo.Context) error {
+	// Get the key ID from the URL
+	id, err := models.ParseID(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid api key ID"})
+	}
+
+	// Revoke the key
+	key, err := ac.manager.Revoke(id)
+	if err != nil && errors.Is(err, respository.ErrRecordNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "api key not found"})
+	}
+	if err != nil {
+		return err
+	}
+
+	// Return the revoked key
+	return c.JSON(http.StatusOK, key)
+}
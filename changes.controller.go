@@ -0,0 +1,173 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/config"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/events"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+	"github.com/labstack/echo/v4"
+)
+
+// Operations of the change records
+const (
+	ChangeUpsert = "upsert" // The employee was created or updated
+	ChangeDelete = "delete" // The employee was deleted, the record is a tombstone
+)
+
+// ChangesController is the controller of the changes feed used by incremental sync clients
+//
+// The feed is read from the events retained by the broker.
+type ChangesController struct {
+	broker     *events.Broker
+	pagination config.PaginationConfig    // Default and maximum number of changes per page
+	present    Presenter[models.Employee] // Converts employees into response bodies
+}
+
+// NewChangesController creates a new changes controller
+func NewChangesController(
+	broker *events.Broker,
+	pagination config.PaginationConfig,
+	present Presenter[models.Employee],
+) *ChangesController {
+	return &ChangesController{broker: broker, pagination: pagination, present: present}
+}
+
+// Register registers the changes route on the group
+func (cc *ChangesController) Register(group *echo.Group) {
+	group.GET("", cc.GetChanges).Name = "change.list"
+}
+
+// GetChanges retrieves the changes following the since cursor, oldest first
+//
+// Clients pass the returned next_since on their next call. 410 Gone means the
+// changes are no longer retained, or the cursor was returned before a restart:
+// the client must reload the employees and then sync from the next_since of the
+// error response.
+//
+// GET /api/v1/changes?since=<epoch>.<seq>&limit=N
+func (cc *ChangesController) GetChanges(c echo.Context) error {
+	// Get the since and limit query parameters
+	epoch, since, limit, err := cc.parseQuery(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	// Read one more change than requested to know whether there are more
+	changes, err := cc.broker.Since(since, limit+1)
+	if epoch != cc.broker.Epoch() {
+		err = events.ErrGone // The sequence numbers may have restarted since
+	}
+	if err != nil && errors.Is(err, events.ErrGone) {
+		return c.JSON(http.StatusGone, map[string]any{
+			"error": fmt.Sprintf(
+				"changes after %s are no longer retained, resync",
+				c.QueryParam("since"),
+			),
+			"next_since": cc.cursor(cc.broker.Seq()),
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	hasMore := len(changes) > limit
+	if hasMore {
+		changes = changes[:limit]
+	}
+
+	// Create the change records, tombstones only carry the ID
+	response := ChangesResponse{
+		Changes:   make([]ChangeResponse, 0, len(changes)),
+		NextSince: cc.cursor(since),
+		HasMore:   hasMore,
+	}
+	for _, event := range changes {
+		change := ChangeResponse{
+			Seq:  event.Seq,
+			Op:   ChangeUpsert,
+			ID:   event.Employee.ID,
+			Time: event.Time,
+		}
+		if event.Type == events.TypeDeleted {
+			change.Op = ChangeDelete
+		} else {
+			change.Employee = cc.present(c, event.Employee)
+		}
+		response.Changes = append(response.Changes, change)
+		response.NextSince = cc.cursor(event.Seq)
+	}
+
+	// Return the changes
+	return c.JSON(http.StatusOK, response)
+}
+
+// cursor returns the cursor of the sequence number, <epoch>.<seq>
+func (cc *ChangesController) cursor(seq uint64) string {
+	return formatCursor(cc.broker, seq)
+}
+
+// formatCursor returns the cursor of a sequence number of the broker, <epoch>.<seq>
+//
+// The change cursors and the event IDs are cursors, so that neither is
+// trusted after a restart of the sequence numbers.
+func formatCursor(broker *events.Broker, seq uint64) string {
+	return broker.Epoch() + "." + strconv.FormatUint(seq, 10)
+}
+
+// parseCursor parses a cursor returned by formatCursor into its epoch and
+// sequence number
+//
+// Bare sequence numbers have no epoch, and are never trusted but 0, the start
+// of every epoch, which is given the current epoch of the broker.
+func parseCursor(broker *events.Broker, value string) (string, uint64, error) {
+	epoch, seqStr, ok := strings.Cut(value, ".")
+	if !ok {
+		epoch, seqStr = "", value
+	}
+	seq, err := strconv.ParseUint(seqStr, 10, 64)
+	if err != nil {
+		return "", 0, err
+	}
+	if epoch == "" && seq == 0 {
+		epoch = broker.Epoch()
+	}
+	return epoch, seq, nil
+}
+
+// parseQuery parses the since cursor and the limit query parameters
+//
+// since defaults to the sequence number 0 of the current epoch, i.e. every
+// change, which is also what a bare 0 means. The limit follows the same rules
+// as the page size of list requests.
+func (cc *ChangesController) parseQuery(c echo.Context) (string, uint64, int, error) {
+	epoch, since := cc.broker.Epoch(), uint64(0)
+	if sinceStr := c.QueryParam("since"); sinceStr != "" {
+		var err error
+		if epoch, since, err = parseCursor(cc.broker, sinceStr); err != nil {
+			return "", 0, 0, errors.New(
+				"invalid since cursor, pass the next_since of the last call",
+			)
+		}
+	}
+
+	limit := cc.pagination.DefaultPageSize
+	if limitStr := c.QueryParam("limit"); limitStr != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitStr); err != nil {
+			return "", 0, 0, errors.New("invalid limit number")
+		}
+	}
+	if limit <= 0 {
+		return "", 0, 0, errors.New("limit number should be greater than 0")
+	}
+	if limit > cc.pagination.MaxPageSize {
+		limit = cc.pagination.MaxPageSize // Cap the page size
+	}
+
+	return epoch, since, limit, nil
+}
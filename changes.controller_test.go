@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/config"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/events"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// getChanges calls the changes feed of the broker with the query and decodes the response
+func getChanges(t *testing.T, broker *events.Broker, query string) (int, ChangesResponse) {
+	app := echo.New()
+	NewChangesController(
+		broker,
+		config.PaginationConfig{DefaultPageSize: 2, MaxPageSize: 3},
+		presentAsIs,
+	).Register(app.Group("/changes"))
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/changes"+query, nil))
+
+	var response ChangesResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	return rec.Code, response
+}
+
+// changeOps returns the sequence numbers and operations of the changes
+func changeOps(changes []ChangeResponse) []string {
+	result := make([]string, 0, len(changes))
+	for _, change := range changes {
+		result = append(result, fmt.Sprintf("%d:%s", change.Seq, change.Op))
+	}
+	return result
+}
+
+func TestChangesController_Paging(t *testing.T) {
+	broker := events.NewBroker(10)
+	publishEvents(broker, events.TypeCreated, events.TypeUpdated, events.TypeDeleted)
+	cursor := func(seq string) string { return broker.Epoch() + "." + seq }
+
+	// Pages of the default size, oldest first
+	code, response := getChanges(t, broker, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"1:upsert", "2:upsert"}, changeOps(response.Changes))
+	assert.True(t, response.HasMore, "more changes should be available")
+	assert.Equal(t, cursor("2"), response.NextSince)
+
+	// Deletions are tombstones with the ID only
+	_, response = getChanges(t, broker, "?since="+response.NextSince)
+	assert.Equal(t, []string{"3:delete"}, changeOps(response.Changes))
+	assert.Equal(t, "1", string(response.Changes[0].ID))
+	assert.Nil(t, response.Changes[0].Employee, "tombstones should not carry the employee")
+	assert.False(t, response.HasMore)
+	assert.Equal(t, cursor("3"), response.NextSince)
+
+	// Caught up clients keep their cursor
+	_, response = getChanges(t, broker, "?since="+response.NextSince)
+	assert.Empty(t, response.Changes)
+	assert.Equal(t, cursor("3"), response.NextSince)
+
+	// A bare 0 is the start of the current epoch
+	code, response = getChanges(t, broker, "?since=0")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"1:upsert", "2:upsert"}, changeOps(response.Changes))
+	assert.Equal(t, cursor("2"), response.NextSince)
+
+	// The limit is capped to the max page size
+	publishEvents(broker, events.TypeCreated)
+	_, response = getChanges(t, broker, "?limit=10")
+	assert.Len(t, response.Changes, 3)
+	assert.True(t, response.HasMore)
+}
+
+func TestChangesController_Gone(t *testing.T) {
+	broker := events.NewBroker(2)
+	publishEvents(broker, events.TypeCreated, events.TypeUpdated, events.TypeDeleted)
+	resync := broker.Epoch() + ".3"
+
+	// The first change is no longer retained
+	code, response := getChanges(t, broker, "?since="+broker.Epoch()+".0")
+	assert.Equal(t, http.StatusGone, code)
+	assert.Equal(t, resync, response.NextSince, "clients should resync from the last change")
+
+	// Cursors of another process are not trusted, even if the change is retained
+	code, response = getChanges(t, broker, "?since=lp0a3b.2")
+	assert.Equal(t, http.StatusGone, code)
+	assert.Equal(t, resync, response.NextSince)
+	code, _ = getChanges(t, broker, "?since=2")
+	assert.Equal(t, http.StatusGone, code, "bare sequence numbers should not be trusted")
+	code, response = getChanges(t, broker, "?since=0")
+	assert.Equal(t, http.StatusGone, code, "the first change is no longer retained")
+	assert.Equal(t, resync, response.NextSince)
+
+	code, _ = getChanges(t, broker, "?since="+broker.Epoch()+".first")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = getChanges(t, broker, "?limit=0")
+	assert.Equal(t, http.StatusBadRequest, code, "limits should be greater than 0")
+}
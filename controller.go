@@ -0,0 +1,417 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/config"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/respository"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// tracer starts the spans of the request steps, it uses the global tracer provider
+var tracer = otel.Tracer("github.com/iamganeshagrawal/go-crud-api-assignment")
+
+// Form is a request body that can be validated and converted into an entity
+type Form[T any] interface {
+	Validate() error
+	ToModel() T
+}
+
+// CRUDController is a generic controller handling the standard create, read,
+// update, delete and list requests for any entity stored in a Repository
+//
+// T is the entity type and F is the request body used for create and update.
+type CRUDController[T models.Entity[T], F Form[T]] struct {
+	name       string                    // Singular resource name, e.g. "employee"
+	repo       respository.Repository[T] // Repository the entities are stored in
+	present    Presenter[T]              // Converts entities into response bodies
+	pagination config.PaginationConfig   // Default and maximum page sizes of list requests
+}
+
+// Presenter converts an entity into the response body sent to the caller,
+// e.g. to hide fields the caller is not allowed to see
+type Presenter[T any] func(c echo.Context, entity T) any
+
+// NewCRUDController creates a new generic controller for the named resource
+func NewCRUDController[T models.Entity[T], F Form[T]](
+	name string,
+	repo respository.Repository[T],
+) *CRUDController[T, F] {
+	return &CRUDController[T, F]{
+		name: name,
+		repo: repo,
+		present: func(_ echo.Context, entity T) any {
+			return entity
+		},
+		pagination: config.Default().Pagination,
+	}
+}
+
+// WithPresenter sets the presenter applied to every entity in the responses
+func (cc *CRUDController[T, F]) WithPresenter(present Presenter[T]) *CRUDController[T, F] {
+	cc.present = present
+	return cc
+}
+
+// WithPagination sets the default and maximum page sizes of list requests
+func (cc *CRUDController[T, F]) WithPagination(
+	pagination config.PaginationConfig,
+) *CRUDController[T, F] {
+	cc.pagination = pagination
+	return cc
+}
+
+// Register registers the standard five routes on the group
+//
+// Routes are named "<name>.create", "<name>.get", "<name>.update",
+// "<name>.delete" and "<name>.list".
+func (cc *CRUDController[T, F]) Register(group *echo.Group) {
+	group.PUT("/:id", cc.Update).Name = cc.name + ".update"
+	group.DELETE("/:id", cc.Delete).Name = cc.name + ".delete"
+	group.GET("/:id", cc.GetByID).Name = cc.name + ".get"
+	group.POST("", cc.Create).Name = cc.name + ".create"
+	group.GET("", cc.GetAll).Name = cc.name + ".list"
+}
+
+// Create creates a new entity
+//
+// POST /<resources>
+func (cc *CRUDController[T, F]) Create(c echo.Context) error {
+	var body F
+	if err := traced(c, cc.name+".bind", func() error { return c.Bind(&body) }); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "invalid request body",
+		})
+	}
+
+	// Validate the request body
+	if err := traced(c, cc.name+".validate", body.Validate); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{"error": err})
+	}
+
+	// Create an entity from the request body
+	entity, err := cc.repoFor(c).Create(body.ToModel())
+	if err != nil && errors.Is(err, respository.ErrDuplicate) {
+		return cc.conflict(c, err)
+	}
+	if err != nil && errors.Is(err, respository.ErrRuleViolation) {
+		return cc.unprocessable(c, err)
+	}
+	if err != nil && errors.Is(err, context.DeadlineExceeded) {
+		return cc.timeout(c)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Return the created entity
+	return c.JSON(http.StatusCreated, cc.present(c, entity))
+}
+
+// GetByID retrieves an entity by ID, as it was at the as_of time when given
+//
+// GET /<resources>/:id
+func (cc *CRUDController[T, F]) GetByID(c echo.Context) error {
+	// Get the entity ID from the URL
+	id, err := cc.parseID(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	asOf, err := cc.parseAsOf(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	// Retrieve the entity from the repository, or from its past versions
+	var entity T
+	if asOf.IsZero() {
+		entity, err = cc.repoFor(c).GetByID(id)
+	} else {
+		entity, err = respository.HistoryOf[T](cc.repoFor(c)).GetByIDAsOf(id, asOf)
+	}
+	if err != nil && isHistoryError(err) {
+		return cc.asOfRejected(c, err)
+	}
+	if err != nil && errors.Is(err, respository.ErrRecordNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": cc.name + " not found"})
+	}
+	if err != nil && errors.Is(err, context.DeadlineExceeded) {
+		return cc.timeout(c)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Return the entity
+	return c.JSON(http.StatusOK, cc.present(c, entity))
+}
+
+// Update updates an entity by ID
+//
+// PUT /<resources>/:id
+func (cc *CRUDController[T, F]) Update(c echo.Context) error {
+	// Get the entity ID from the URL
+	id, err := cc.parseID(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	var body F
+	if err := traced(c, cc.name+".bind", func() error { return c.Bind(&body) }); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "invalid request body",
+		})
+	}
+
+	// Validate the request body
+	if err := traced(c, cc.name+".validate", body.Validate); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{"error": err})
+	}
+
+	// Update the entity in the repository
+	entity, err := cc.repoFor(c).Update(id, body.ToModel())
+	if err != nil && errors.Is(err, respository.ErrRecordNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": cc.name + " not found"})
+	}
+	if err != nil && errors.Is(err, respository.ErrDuplicate) {
+		return cc.conflict(c, err)
+	}
+	if err != nil && errors.Is(err, respository.ErrRuleViolation) {
+		return cc.unprocessable(c, err)
+	}
+	if err != nil && errors.Is(err, context.DeadlineExceeded) {
+		return cc.timeout(c)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Return the updated entity
+	// We can also return 204 No Content if we don't want to return the updated entity
+	return c.JSON(http.StatusOK, cc.present(c, entity))
+}
+
+// Delete deletes an entity by ID
+//
+// DELETE /<resources>/:id
+func (cc *CRUDController[T, F]) Delete(c echo.Context) error {
+	// Get the entity ID from the URL
+	id, err := cc.parseID(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	// Delete the entity from the repository
+	err = cc.repoFor(c).Delete(id)
+	if err != nil && errors.Is(err, respository.ErrRecordNotFound) {
+		// Return 404 if the entity is not found
+		// Or we can treat this as a successful deletion as well
+		// and return 204 instead of 404
+		return c.JSON(http.StatusNotFound, map[string]string{"error": cc.name + " not found"})
+	}
+	if err != nil && errors.Is(err, respository.ErrRuleViolation) {
+		return cc.unprocessable(c, err)
+	}
+	if err != nil && errors.Is(err, context.DeadlineExceeded) {
+		return cc.timeout(c)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Return 204 if the entity is successfully deleted
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetAll retrieves all entities, as they were at the as_of time when given
+//
+// GET /<resources>
+func (cc *CRUDController[T, F]) GetAll(c echo.Context) error {
+	// Get the page and limit query parameters
+	page, limit, err := parsePagination(c, cc.pagination)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	asOf, err := cc.parseAsOf(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	// Retrieve entities from the repository, or from their past versions
+	var entities []T
+	var total int
+	if asOf.IsZero() {
+		entities, total = cc.repoFor(c).GetAll(page, limit)
+	} else {
+		entities, total, err = respository.HistoryOf[T](cc.repoFor(c)).GetAllAsOf(asOf, page, limit)
+	}
+	if err != nil && isHistoryError(err) {
+		return cc.asOfRejected(c, err)
+	}
+	if err != nil && errors.Is(err, context.DeadlineExceeded) {
+		return cc.timeout(c)
+	}
+	if err != nil {
+		return err
+	}
+
+	data := make([]any, 0, len(entities))
+	for _, entity := range entities {
+		data = append(data, cc.present(c, entity))
+	}
+
+	// Create a list response
+	response := ListResponse{
+		Page:  page,
+		Limit: limit,
+		Total: total,
+		Data:  data,
+	}
+
+	// Return the list response
+	return c.JSON(http.StatusOK, response)
+}
+
+// traced runs a step of the request in a child span of the request span
+func traced(c echo.Context, name string, step func() error) error {
+	_, span := tracer.Start(c.Request().Context(), name)
+	defer span.End()
+
+	err := step()
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// repoFor returns the repository bound to the context of the request
+func (cc *CRUDController[T, F]) repoFor(c echo.Context) respository.Repository[T] {
+	return respository.BindContext(c.Request().Context(), cc.repo)
+}
+
+// conflict returns 409 Conflict with the details of a unique constraint violation
+func (cc *CRUDController[T, F]) conflict(c echo.Context, err error) error {
+	var dupErr *respository.DuplicateError
+	if !errors.As(err, &dupErr) {
+		return c.JSON(http.StatusConflict, map[string]string{"error": cc.name + " already exists"})
+	}
+
+	return c.JSON(http.StatusConflict, map[string]any{
+		"error":       fmt.Sprintf("%s with this %s already exists", cc.name, dupErr.Field),
+		"field":       dupErr.Field,
+		"existing_id": dupErr.ExistingID,
+	})
+}
+
+// unprocessable returns 422 Unprocessable Entity with the details of a vetoed write
+func (cc *CRUDController[T, F]) unprocessable(c echo.Context, err error) error {
+	var ruleErr *respository.RuleError
+	if !errors.As(err, &ruleErr) {
+		return c.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+	}
+
+	body := map[string]any{"error": ruleErr.Message, "rule": ruleErr.Rule}
+	if ruleErr.Field != "" {
+		body["field"] = ruleErr.Field
+	}
+	return c.JSON(http.StatusUnprocessableEntity, body)
+}
+
+// timeout returns 504 Gateway Timeout when the repository did not answer in time
+func (cc *CRUDController[T, F]) timeout(c echo.Context) error {
+	return c.JSON(
+		http.StatusGatewayTimeout,
+		map[string]string{"error": cc.name + " storage timed out"},
+	)
+}
+
+// parseID parses the entity ID from the URL
+func (cc *CRUDController[T, F]) parseID(c echo.Context) (models.ID, error) {
+	id, err := models.ParseID(c.Param("id"))
+	if err != nil {
+		return "", fmt.Errorf("invalid %s ID", cc.name)
+	}
+	return id, nil
+}
+
+// parseAsOf parses the as_of query parameter, the zero time when absent
+func (cc *CRUDController[T, F]) parseAsOf(c echo.Context) (time.Time, error) {
+	asOfStr := c.QueryParam("as_of")
+	if asOfStr == "" {
+		return time.Time{}, nil
+	}
+	asOf, err := time.Parse(time.RFC3339, asOfStr)
+	if err != nil || asOf.IsZero() {
+		return time.Time{}, errors.New("invalid as_of, expected an RFC3339 timestamp")
+	}
+	return asOf, nil
+}
+
+// asOfRejected returns 400 Bad Request for an as_of read of versions the
+// repository does not retain, and 410 Gone for versions it pruned
+func (cc *CRUDController[T, F]) asOfRejected(c echo.Context, err error) error {
+	if errors.Is(err, respository.ErrHistoryPruned) {
+		return c.JSON(http.StatusGone, map[string]string{"error": "as_of is too old: " + err.Error()})
+	}
+	return c.JSON(
+		http.StatusBadRequest,
+		map[string]string{"error": fmt.Sprintf("as_of is not supported for %s", cc.name)},
+	)
+}
+
+// isHistoryError reports whether the as_of read failed for versions not retained
+func isHistoryError(err error) bool {
+	return errors.Is(err, respository.ErrHistoryNotRetained) ||
+		errors.Is(err, respository.ErrHistoryPruned)
+}
+
+// parsePagination parses the page and limit query parameters
+//
+// The page defaults to 1 and the limit defaults to the default page size.
+// A limit above the maximum page size is capped to it, a limit less than or
+// equal to 0 is rejected rather than silently truncated to a page.
+func parsePagination(c echo.Context, pagination config.PaginationConfig) (int, int, error) {
+	pageStr := c.QueryParam("page")
+	limitStr := c.QueryParam("limit")
+
+	// Set default page to 1
+	if pageStr == "" {
+		pageStr = "1"
+	}
+
+	// Set default limit to the default page size
+	if limitStr == "" {
+		limitStr = strconv.Itoa(pagination.DefaultPageSize)
+	}
+
+	// Convert page and limit to integer
+	page, err := strconv.Atoi(pageStr)
+	if err != nil {
+		return 0, 0, errors.New("invalid page number")
+	}
+	if page <= 0 {
+		return 0, 0, errors.New("page number should be greater than 0")
+	}
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		return 0, 0, errors.New("invalid limit number")
+	}
+	if limit <= 0 {
+		return 0, 0, errors.New("limit number should be greater than 0")
+	}
+	if limit > pagination.MaxPageSize {
+		limit = pagination.MaxPageSize // Cap the page size
+	}
+
+	return page, limit, nil
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/chaos"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/config"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/respository"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCRUDController_StorageFaults pins the status of every employee route for
+// every fault the storage can fail with, through the cache as in main
+func TestCRUDController_StorageFaults(t *testing.T) {
+	const body = `{"name": "Ganesh", "email": "ganesh@example.com", "position": "Engineer", "salary": 1000}`
+
+	routes := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodPost, "/employees", body},
+		{http.MethodGet, "/employees/:id", ""},
+		{http.MethodPut, "/employees/:id", body},
+		{http.MethodDelete, "/employees/:id", ""},
+		{http.MethodGet, "/employees", ""},
+		{http.MethodGet, "/employees/:id?as_of=2999-01-01T00:00:00Z", ""},
+		{http.MethodGet, "/employees?as_of=2999-01-01T00:00:00Z", ""},
+	}
+	tests := []struct {
+		fault    chaos.Fault
+		statuses []int // Statuses of the routes, in order
+	}{
+		{
+			// Slow storage only delays the responses
+			chaos.FaultLatency,
+			[]int{
+				http.StatusCreated,
+				http.StatusOK,
+				http.StatusOK,
+				http.StatusNoContent,
+				http.StatusOK,
+				http.StatusOK,
+				http.StatusOK,
+			},
+		},
+		{
+			// Creations are never expected to miss a record
+			chaos.FaultNotFound,
+			[]int{
+				http.StatusInternalServerError,
+				http.StatusNotFound,
+				http.StatusNotFound,
+				http.StatusNotFound,
+				http.StatusOK,
+				http.StatusNotFound,
+				http.StatusInternalServerError,
+			},
+		},
+		{
+			chaos.FaultError,
+			[]int{
+				http.StatusInternalServerError,
+				http.StatusInternalServerError,
+				http.StatusInternalServerError,
+				http.StatusInternalServerError,
+				http.StatusOK,
+				http.StatusInternalServerError,
+				http.StatusInternalServerError,
+			},
+		},
+		{
+			chaos.FaultTimeout,
+			[]int{
+				http.StatusGatewayTimeout,
+				http.StatusGatewayTimeout,
+				http.StatusGatewayTimeout,
+				http.StatusGatewayTimeout,
+				http.StatusOK,
+				http.StatusGatewayTimeout,
+				http.StatusGatewayTimeout,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.fault), func(t *testing.T) {
+			for i, route := range routes {
+				store := respository.NewEmployeeInMemoryRepository()
+				store.SetHistory(respository.NewHistory[models.Employee]())
+				employee, err := store.CreateEmployee(
+					"Harshit",
+					"harshit@example.com",
+					"Engineer",
+					1000,
+				)
+				assert.Nil(t, err)
+
+				// The employee is cached, faults must not be hidden by cache hits
+				injector := chaos.NewInjector()
+				decorated, _ := decorateStorage(store, 10, time.Minute, injector)
+				_, err = decorated.GetEmployeeByID(employee.ID)
+				assert.Nil(t, err)
+				err = injector.Configure(chaos.Settings{
+					Enabled: true,
+					Rules: []chaos.Rule{
+						{Method: chaos.AnyMethod, Fault: tt.fault, Probability: 1, LatencyMS: 1},
+					},
+				})
+				assert.Nil(t, err)
+
+				app := echo.New()
+				NewCRUDController[models.Employee, CreateEmployeeRequest](
+					"employee",
+					respository.AsRepository(decorated),
+				).Register(app.Group("/employees"))
+
+				path := strings.Replace(route.path, ":id", string(employee.ID), 1)
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+				req := httptest.NewRequest(route.method, path, strings.NewReader(route.body))
+				req = req.WithContext(ctx)
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				app.ServeHTTP(rec, req)
+				cancel()
+
+				assert.Equal(t, tt.statuses[i], rec.Code, "%s %s", route.method, route.path)
+			}
+		})
+	}
+}
+
+// TestCRUDController_AsOf pins the point-in-time reads of the employee routes
+func TestCRUDController_AsOf(t *testing.T) {
+	store := respository.NewEmployeeInMemoryRepository()
+	history := respository.NewHistory[models.Employee]()
+	store.SetHistory(history)
+
+	beforeCreate := time.Now()
+	employee, err := store.CreateEmployee("Harshit", "harshit@example.com", "Engineer", 1000)
+	assert.Nil(t, err)
+	_, err = store.CreateEmployee("Ganesh", "ganesh@example.com", "Engineer", 1000)
+	assert.Nil(t, err)
+	beforeUpdate := time.Now()
+	_, err = store.UpdateEmployee(employee.ID, "Harshit", "harshit@example.com", "Lead", 1500)
+	assert.Nil(t, err)
+	assert.Nil(t, store.DeleteEmployee(employee.ID))
+	afterDelete := time.Now()
+
+	newApp := func(repo respository.IEmployeeRepository) *echo.Echo {
+		app := echo.New()
+		NewCRUDController[models.Employee, CreateEmployeeRequest](
+			"employee",
+			respository.AsRepository(repo),
+		).Register(app.Group("/employees"))
+		return app
+	}
+	get := func(app *echo.Echo, path string, asOf string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path+"?as_of="+asOf, nil)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		return rec
+	}
+	at := func(t time.Time) string { return t.UTC().Format(time.RFC3339Nano) }
+	app := newApp(store)
+	path := "/employees/" + string(employee.ID)
+
+	rec := get(app, path, at(beforeCreate))
+	assert.Equal(t, http.StatusNotFound, rec.Code, "employee should not exist before its creation")
+	rec = get(app, path, at(beforeUpdate))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"position":"Engineer"`)
+	rec = get(app, path, at(afterDelete))
+	assert.Equal(t, http.StatusNotFound, rec.Code, "employee should not exist after its deletion")
+
+	// Lists keep their pagination
+	rec = get(app, "/employees", at(beforeUpdate)+"&page=2&limit=1")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{
+		"page": 2,
+		"limit": 1,
+		"total": 2,
+		"data": [{"id": "2", "name": "Ganesh", "email": "ganesh@example.com", "position": "Engineer", "salary": 1000}]
+	}`, rec.Body.String())
+
+	rec = get(app, path, "yesterday")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = get(newApp(respository.NewEmployeeInMemoryRepository()), path, at(beforeUpdate))
+	assert.Equal(t, http.StatusBadRequest, rec.Code, "as_of should need retained versions")
+
+	// Versions pruned are no longer read
+	history.Prune(beforeUpdate)
+	rec = get(app, path, at(beforeUpdate))
+	assert.Equal(t, http.StatusOK, rec.Code, "versions current at the pruning should be kept")
+	rec = get(app, "/employees", at(beforeCreate))
+	assert.Equal(t, http.StatusGone, rec.Code, "pruned versions should be gone")
+	assert.Contains(t, rec.Body.String(), "as_of is too old")
+	rec = get(app, path, at(beforeCreate))
+	assert.Equal(t, http.StatusGone, rec.Code)
+}
+
+func TestCRUDController_Pagination(t *testing.T) {
+	store := respository.NewEmployeeInMemoryRepository()
+	for _, name := range []string{"Harshit", "Ganesh", "Rahul"} {
+		_, err := store.CreateEmployee(name, "", "Engineer", 1000)
+		assert.Nil(t, err)
+	}
+
+	app := echo.New()
+	NewCRUDController[models.Employee, CreateEmployeeRequest](
+		"employee",
+		respository.AsRepository(store),
+	).WithPagination(
+		config.PaginationConfig{DefaultPageSize: 1, MaxPageSize: 2},
+	).Register(app.Group("/employees"))
+	list := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/employees"+query, nil))
+		return rec
+	}
+
+	// Limits above the max page size are capped, the total tells the pages left
+	rec := list("?limit=50")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"limit":2,"total":3`)
+
+	// Limits of 0 or less used to mean every employee, they are rejected
+	for _, limit := range []string{"0", "-1"} {
+		rec = list("?limit=" + limit)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, "limit %s should be rejected", limit)
+	}
+}
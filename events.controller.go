@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/events"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+	"github.com/labstack/echo/v4"
+)
+
+// EventsController is the controller streaming the employee changes as Server-Sent Events
+type EventsController struct {
+	broker    *events.Broker
+	heartbeat time.Duration              // Interval between heartbeats of idle streams
+	present   Presenter[models.Employee] // Converts employees into event payloads
+}
+
+// NewEventsController creates a new events controller
+func NewEventsController(
+	broker *events.Broker,
+	heartbeat time.Duration,
+	present Presenter[models.Employee],
+) *EventsController {
+	return &EventsController{broker: broker, heartbeat: heartbeat, present: present}
+}
+
+// Register registers the event stream route on the employee group
+func (ec *EventsController) Register(group *echo.Group) {
+	group.GET("/events", ec.Stream).Name = "employee.events"
+}
+
+// Stream streams the employee changes until the client disconnects
+//
+// Event IDs are cursors like those of the changes, <epoch>.<seq>. Streams resume
+// after the event given by the Last-Event-ID header (or the last_event_id query
+// parameter), and can be filtered with one or more type query parameters, e.g.
+// ?type=created,deleted. 410 Gone means the missed events are no longer
+// retained, or the ID was sent before a restart.
+//
+// GET /api/v1/employees/events
+func (ec *EventsController) Stream(c echo.Context) error {
+	// Parse the event type filter
+	types, err := parseEventTypes(c.QueryParams()["type"])
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	// Parse the ID of the last event received, new streams start with the next event
+	epoch, seq := ec.broker.Epoch(), ec.broker.Seq()
+	lastEventID := c.Request().Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.QueryParam("last_event_id")
+	}
+	if lastEventID != "" {
+		if epoch, seq, err = parseCursor(ec.broker, lastEventID); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid event ID"})
+		}
+	}
+
+	// Subscribe, the client must reload the employees when the events it missed are gone
+	var (
+		replay []events.Event
+		sub    *events.Subscription
+	)
+	err = events.ErrGone // The sequence numbers may have restarted since the ID was sent
+	if epoch == ec.broker.Epoch() {
+		replay, sub, err = ec.broker.Subscribe(seq)
+	}
+	if err != nil && errors.Is(err, events.ErrGone) {
+		return c.JSON(http.StatusGone, map[string]string{
+			"error": fmt.Sprintf(
+				"events after %s are no longer retained, reload the employees",
+				lastEventID,
+			),
+		})
+	}
+	if err != nil {
+		return err
+	}
+	defer sub.Close()
+
+	// The stream outlives the write timeout of the server
+	res := c.Response()
+	_ = http.NewResponseController(res).SetWriteDeadline(time.Time{})
+
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set("X-Accel-Buffering", "no") // Disable proxy buffering
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	// Send the missed events, then the new ones as they are published
+	send := func(event events.Event) error {
+		if len(types) > 0 && !slices.Contains(types, event.Type) {
+			return nil
+		}
+		return ec.write(c, event)
+	}
+	for _, event := range replay {
+		if err := send(event); err != nil {
+			return nil // The client is gone
+		}
+	}
+
+	heartbeat := time.NewTicker(ec.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case event, ok := <-sub.Events():
+			if !ok {
+				return nil // Dropped or shutting down, the client resumes from its last event
+			}
+			if err := send(event); err != nil {
+				return nil
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(res, ": heartbeat\n\n"); err != nil {
+				return nil
+			}
+			res.Flush()
+		}
+	}
+}
+
+// write sends the event to the client
+func (ec *EventsController) write(c echo.Context, event events.Event) error {
+	data, err := json.Marshal(EventResponse{
+		Seq:      event.Seq,
+		Type:     event.Type,
+		Time:     event.Time,
+		Employee: ec.present(c, event.Employee),
+	})
+	if err != nil {
+		return err
+	}
+
+	res := c.Response()
+	_, err = fmt.Fprintf(
+		res,
+		"id: %s\nevent: %s\ndata: %s\n\n",
+		formatCursor(ec.broker, event.Seq),
+		event.Type,
+		data,
+	)
+	if err != nil {
+		return err
+	}
+	res.Flush()
+	return nil
+}
+
+// parseEventTypes parses the type query parameters, which can be repeated or comma separated
+func parseEventTypes(params []string) ([]events.Type, error) {
+	var types []events.Type
+	for _, param := range params {
+		for _, name := range strings.Split(param, ",") {
+			eventType, err := events.ParseType(strings.TrimSpace(name))
+			if err != nil {
+				return nil, err
+			}
+			types = append(types, eventType)
+		}
+	}
+	return types, nil
+}
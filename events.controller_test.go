@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/events"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// presentAsIs presents employees without changes
+func presentAsIs(_ echo.Context, employee models.Employee) any {
+	return employee
+}
+
+// publishEvents publishes an event of each type following the last one
+func publishEvents(broker *events.Broker, types ...events.Type) {
+	for _, eventType := range types {
+		seq := broker.Seq() + 1
+		broker.Publish(context.Background(), events.Event{
+			Seq:      seq,
+			Type:     eventType,
+			Employee: models.Employee{ID: "1", Name: "Harshit"},
+		})
+	}
+}
+
+// newEventsServer serves the event stream of the broker
+func newEventsServer(
+	t *testing.T,
+	broker *events.Broker,
+	heartbeat time.Duration,
+) *httptest.Server {
+	app := echo.New()
+	NewEventsController(broker, heartbeat, presentAsIs).Register(app.Group("/employees"))
+	server := httptest.NewServer(app)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// stream opens the event stream, with the Last-Event-ID header when set, and
+// returns the response and a function reading the next frame
+func stream(
+	t *testing.T,
+	server *httptest.Server,
+	query string,
+	lastEventID string,
+) (*http.Response, func() string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodGet,
+		server.URL+"/employees/events"+query,
+		nil,
+	)
+	assert.Nil(t, err)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+
+	reader := bufio.NewReader(resp.Body)
+	next := func() string {
+		var frame strings.Builder
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("stream ended: %v", err)
+			}
+			if line == "\n" {
+				return frame.String()
+			}
+			frame.WriteString(line)
+		}
+	}
+	return resp, next
+}
+
+func TestEventsController_Resume(t *testing.T) {
+	broker := events.NewBroker(10)
+	publishEvents(broker, events.TypeCreated, events.TypeUpdated, events.TypeDeleted)
+	server := newEventsServer(t, broker, time.Minute)
+	id := func(seq string) string { return broker.Epoch() + "." + seq }
+
+	// The events following the last one received are replayed, then the new ones streamed
+	resp, next := stream(t, server, "", id("1"))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get(echo.HeaderContentType))
+	assert.True(t, strings.HasPrefix(next(), "id: "+id("2")+"\nevent: updated\ndata: {\"seq\":2,"))
+	assert.True(t, strings.HasPrefix(next(), "id: "+id("3")+"\nevent: deleted\n"))
+	publishEvents(broker, events.TypeCreated)
+	assert.True(t, strings.HasPrefix(next(), "id: "+id("4")+"\nevent: created\n"))
+
+	// The query parameter resumes too
+	_, next = stream(t, server, "?last_event_id="+id("3"), "")
+	assert.True(t, strings.HasPrefix(next(), "id: "+id("4")+"\n"))
+
+	// A bare 0 resumes from the first event
+	_, next = stream(t, server, "", "0")
+	assert.True(t, strings.HasPrefix(next(), "id: "+id("1")+"\n"))
+}
+
+func TestEventsController_Gone(t *testing.T) {
+	broker := events.NewBroker(2)
+	publishEvents(broker, events.TypeCreated, events.TypeUpdated, events.TypeDeleted)
+	server := newEventsServer(t, broker, time.Minute)
+
+	// The first event is no longer retained
+	resp, _ := stream(t, server, "", broker.Epoch()+".0")
+	assert.Equal(t, http.StatusGone, resp.StatusCode)
+
+	// Events never published by this process are gone too, e.g. after a restart
+	resp, _ = stream(t, server, "", broker.Epoch()+".10")
+	assert.Equal(t, http.StatusGone, resp.StatusCode)
+
+	// IDs of another process are not trusted, even if the event is retained
+	resp, _ = stream(t, server, "", "lp0a3b.2")
+	assert.Equal(t, http.StatusGone, resp.StatusCode)
+	resp, _ = stream(t, server, "", "2")
+	assert.Equal(t, http.StatusGone, resp.StatusCode, "bare sequence numbers should not be trusted")
+
+	resp, _ = stream(t, server, "", "first")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestEventsController_TypeFilter(t *testing.T) {
+	broker := events.NewBroker(10)
+	publishEvents(broker, events.TypeCreated, events.TypeUpdated, events.TypeDeleted)
+	server := newEventsServer(t, broker, time.Minute)
+
+	// Repeated and comma separated types are combined
+	_, next := stream(t, server, "?type=created,deleted", "0")
+	assert.True(t, strings.HasPrefix(next(), "id: "+broker.Epoch()+".1\nevent: created\n"))
+	assert.True(t, strings.HasPrefix(next(), "id: "+broker.Epoch()+".3\nevent: deleted\n"))
+	publishEvents(broker, events.TypeUpdated, events.TypeCreated)
+	assert.True(
+		t,
+		strings.HasPrefix(next(), "id: "+broker.Epoch()+".5\nevent: created\n"),
+		"updates should be skipped",
+	)
+
+	resp, _ := stream(t, server, "?type=created&type=renamed", "")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestEventsController_Heartbeat(t *testing.T) {
+	broker := events.NewBroker(10)
+	server := newEventsServer(t, broker, 10*time.Millisecond)
+
+	// Idle streams get comment lines, ignored by clients
+	_, next := stream(t, server, "", "")
+	assert.Equal(t, ": heartbeat\n", next())
+	publishEvents(broker, events.TypeCreated)
+	frame := next()
+	for frame == ": heartbeat\n" {
+		frame = next()
+	}
+	assert.True(t, strings.HasPrefix(frame, "id: "+broker.Epoch()+".1\nevent: created\n"))
+}
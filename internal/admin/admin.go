@@ -0,0 +1,265 @@
+// Package admin provides the operational endpoints served on the admin listener
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"expvar"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/chaos"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/logging"
+	"github.com/labstack/echo/v4"
+)
+
+// Compactor compacts a store, e.g. to release memory or rewrite its files
+type Compactor interface {
+	Compact(ctx context.Context) error
+}
+
+// CompactorFunc adapts a function to the Compactor interface
+type CompactorFunc func(ctx context.Context) error
+
+func (f CompactorFunc) Compact(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Admin is the echo application of the admin listener
+//
+// It always serves pprof under /debug/pprof and expvar under /debug/vars, the
+// other endpoints are added with the Handle methods.
+type Admin struct {
+	app *echo.Echo
+}
+
+// New creates the admin application
+//
+// When the token is not empty every request must carry it as a bearer token,
+// otherwise the listener must only be reachable from trusted hosts.
+func New(token string) *Admin {
+	app := echo.New()
+	app.HideBanner = true
+	app.HidePort = true
+	if token != "" {
+		app.Use(bearerToken(token))
+	}
+
+	// Profiling and runtime variables
+	// The index links are relative, so it is only served with a trailing slash
+	app.GET("/debug/pprof", func(c echo.Context) error {
+		return c.Redirect(http.StatusMovedPermanently, "/debug/pprof/")
+	})
+	app.GET("/debug/pprof/", echo.WrapHandler(http.HandlerFunc(pprof.Index))).Name = "admin.pprof"
+	app.GET("/debug/pprof/cmdline", echo.WrapHandler(http.HandlerFunc(pprof.Cmdline)))
+	app.GET("/debug/pprof/profile", echo.WrapHandler(http.HandlerFunc(pprof.Profile)))
+	app.GET("/debug/pprof/symbol", echo.WrapHandler(http.HandlerFunc(pprof.Symbol)))
+	app.POST("/debug/pprof/symbol", echo.WrapHandler(http.HandlerFunc(pprof.Symbol)))
+	app.GET("/debug/pprof/trace", echo.WrapHandler(http.HandlerFunc(pprof.Trace)))
+	app.GET("/debug/pprof/:profile", func(c echo.Context) error {
+		pprof.Handler(c.Param("profile")).ServeHTTP(c.Response(), c.Request())
+		return nil
+	})
+	app.GET("/debug/vars", echo.WrapHandler(expvar.Handler())).Name = "admin.expvar"
+
+	return &Admin{app: app}
+}
+
+// Echo returns the echo application, to be run by a server
+func (a *Admin) Echo() *echo.Echo {
+	return a.app
+}
+
+// HandleRoutes serves the route inventory of the public application
+//
+// GET /routes
+func (a *Admin) HandleRoutes(public *echo.Echo) {
+	a.app.GET("/routes", func(c echo.Context) error {
+		// Skip the catch-all routes echo adds for groups with middleware
+		routes := make([]*echo.Route, 0)
+		for _, route := range public.Routes() {
+			if route.Method != echo.RouteNotFound {
+				routes = append(routes, route)
+			}
+		}
+		sort.Slice(routes, func(i, j int) bool {
+			if routes[i].Path != routes[j].Path {
+				return routes[i].Path < routes[j].Path
+			}
+			return routes[i].Method < routes[j].Method
+		})
+		return c.JSON(http.StatusOK, routes)
+	}).Name = "admin.routes"
+}
+
+// HandleConfig serves the effective configuration returned by the function,
+// which must mask the secrets
+//
+// GET /config
+func (a *Admin) HandleConfig(config func() any) {
+	a.app.GET("/config", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, config())
+	}).Name = "admin.config"
+}
+
+// HandleMetrics serves the metrics
+//
+// GET /metrics
+func (a *Admin) HandleMetrics(handler echo.HandlerFunc) {
+	a.app.GET("/metrics", handler).Name = "admin.metrics"
+}
+
+// HandleHealth serves the detailed readiness checks, e.g. health.Registry.Details
+//
+// GET /healthz/ready
+func (a *Admin) HandleHealth(handler echo.HandlerFunc) {
+	a.app.GET("/healthz/ready", handler).Name = "admin.health"
+}
+
+// HandleLogLevel serves and changes the level of the logger at runtime
+//
+// GET /log-level
+// PUT /log-level {"level": "debug"}
+func (a *Admin) HandleLogLevel(level *slog.LevelVar) {
+	a.app.GET("/log-level", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, logLevelBody{Level: levelName(level.Level())})
+	}).Name = "admin.loglevel.get"
+
+	a.app.PUT("/log-level", func(c echo.Context) error {
+		var body logLevelBody
+		if err := c.Bind(&body); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		}
+
+		parsed, err := logging.ParseLevel(body.Level)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		previous := level.Level()
+		level.Set(parsed)
+		slog.Info("log level changed", "from", levelName(previous), "to", levelName(parsed))
+
+		return c.JSON(http.StatusOK, logLevelBody{Level: levelName(parsed)})
+	}).Name = "admin.loglevel.update"
+}
+
+// HandleCompaction triggers the compaction of the named stores, one after the other
+//
+// POST /compact
+func (a *Admin) HandleCompaction(compactors map[string]Compactor) {
+	names := make([]string, 0, len(compactors))
+	for name := range compactors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	a.app.POST("/compact", func(c echo.Context) error {
+		results := make(map[string]compactionResult, len(names))
+		status := http.StatusOK
+		for _, name := range names {
+			start := time.Now()
+			err := compactors[name].Compact(c.Request().Context())
+			result := compactionResult{DurationMS: float64(time.Since(start).Microseconds()) / 1000}
+			if err != nil {
+				result.Error = err.Error()
+				status = http.StatusInternalServerError
+			}
+			results[name] = result
+		}
+		return c.JSON(status, results)
+	}).Name = "admin.compact"
+}
+
+// HandleBackfill triggers the backfill of the migration, it returns the number
+// of copied employees
+//
+// POST /migration/backfill
+func (a *Admin) HandleBackfill(backfill func(ctx context.Context) (int, error)) {
+	a.app.POST("/migration/backfill", func(c echo.Context) error {
+		start := time.Now()
+		copied, err := backfill(c.Request().Context())
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusOK, backfillResult{
+			Employees:  copied,
+			DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+		})
+	}).Name = "admin.backfill"
+}
+
+// HandleChaos serves and changes the faults injected into the employee
+// repository at runtime
+//
+// GET /chaos
+// PUT /chaos {"enabled": true, "rules": [{"method": "*", "fault": "error", "probability": 0.1}]}
+func (a *Admin) HandleChaos(injector *chaos.Injector) {
+	a.app.GET("/chaos", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, injector.Settings())
+	}).Name = "admin.chaos.get"
+
+	a.app.PUT("/chaos", func(c echo.Context) error {
+		var settings chaos.Settings
+		if err := c.Bind(&settings); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		}
+
+		if err := injector.Configure(settings); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		slog.Warn("chaos settings changed", "enabled", settings.Enabled, "rules", len(settings.Rules))
+
+		return c.JSON(http.StatusOK, injector.Settings())
+	}).Name = "admin.chaos.update"
+}
+
+// logLevelBody is the request and response body of the log level endpoints
+type logLevelBody struct {
+	Level string `json:"level"`
+}
+
+// compactionResult is the outcome of the compaction of one store
+type compactionResult struct {
+	DurationMS float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// backfillResult is the outcome of a backfill
+type backfillResult struct {
+	Employees  int     `json:"employees"`
+	DurationMS float64 `json:"duration_ms"`
+}
+
+// levelName returns the configuration name of the level
+func levelName(level slog.Level) string {
+	for name, l := range logging.Levels {
+		if l == level {
+			return name
+		}
+	}
+	return strings.ToLower(level.String())
+}
+
+// bearerToken returns a middleware that rejects requests without the token
+func bearerToken(token string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			given, ok := strings.CutPrefix(
+				c.Request().Header.Get(echo.HeaderAuthorization),
+				"Bearer ",
+			)
+			if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer realm="admin"`)
+				return c.JSON(
+					http.StatusUnauthorized,
+					map[string]string{"error": "unauthorized: admin token required"},
+				)
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/chaos"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// do sends a request to the admin application with the token, if any
+func do(a *Admin, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if token != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	a.Echo().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAdmin_Auth(t *testing.T) {
+	a := New("s3cret")
+
+	rec := do(a, http.MethodGet, "/debug/vars", "", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "requests without token should be rejected")
+	assert.Equal(t, `Bearer realm="admin"`, rec.Header().Get(echo.HeaderWWWAuthenticate))
+
+	rec = do(a, http.MethodGet, "/debug/vars", "wrong", "")
+	assert.Equal(
+		t,
+		http.StatusUnauthorized,
+		rec.Code,
+		"requests with a wrong token should be rejected",
+	)
+
+	rec = do(a, http.MethodGet, "/debug/vars", "s3cret", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "memstats", "expvar should be served")
+
+	// Without a token the listener is open
+	rec = do(New(""), http.MethodGet, "/debug/vars", "", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestAdmin_Pprof(t *testing.T) {
+	a := New("")
+
+	rec := do(a, http.MethodGet, "/debug/pprof", "", "")
+	assert.Equal(t, http.StatusMovedPermanently, rec.Code)
+
+	rec = do(a, http.MethodGet, "/debug/pprof/", "", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "goroutine", "index should list the profiles")
+
+	rec = do(a, http.MethodGet, "/debug/pprof/goroutine?debug=1", "", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "goroutine profile")
+}
+
+func TestAdmin_Routes(t *testing.T) {
+	public := echo.New()
+	public.Group("/api/v1", func(next echo.HandlerFunc) echo.HandlerFunc { return next })
+	public.GET("/api/v1/employees", func(c echo.Context) error { return nil }).Name = "employee.list"
+	public.POST("/api/v1/employees", func(c echo.Context) error { return nil }).Name = "employee.create"
+
+	a := New("")
+	a.HandleRoutes(public)
+	rec := do(a, http.MethodGet, "/routes", "", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var routes []echo.Route
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &routes))
+	assert.Len(t, routes, 2, "only the public routes should be listed")
+	assert.Equal(t, "employee.list", routes[0].Name, "routes should be sorted")
+}
+
+func TestAdmin_Config(t *testing.T) {
+	a := New("")
+	a.HandleConfig(func() any { return map[string]string{"jwt_secret": "******"} })
+
+	rec := do(a, http.MethodGet, "/config", "", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"jwt_secret":"******"}`, rec.Body.String())
+}
+
+func TestAdmin_LogLevel(t *testing.T) {
+	level := new(slog.LevelVar)
+	a := New("")
+	a.HandleLogLevel(level)
+
+	rec := do(a, http.MethodGet, "/log-level", "", "")
+	assert.JSONEq(t, `{"level":"info"}`, rec.Body.String())
+
+	rec = do(a, http.MethodPut, "/log-level", "", `{"level":"debug"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"level":"debug"}`, rec.Body.String())
+	assert.Equal(t, slog.LevelDebug, level.Level(), "level should be changed")
+
+	rec = do(a, http.MethodPut, "/log-level", "", `{"level":"loud"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, slog.LevelDebug, level.Level(), "level should be kept")
+}
+
+func TestAdmin_Compaction(t *testing.T) {
+	var compacted []string
+	a := New("")
+	a.HandleCompaction(map[string]Compactor{
+		"employee": CompactorFunc(func(context.Context) error {
+			compacted = append(compacted, "employee")
+			return nil
+		}),
+		"apikey": CompactorFunc(func(context.Context) error {
+			compacted = append(compacted, "apikey")
+			return errors.New("disk full")
+		}),
+	})
+
+	rec := do(a, http.MethodPost, "/compact", "", "")
+	assert.Equal(t, http.StatusInternalServerError, rec.Code, "a failed compaction should fail")
+	assert.Equal(t, []string{"apikey", "employee"}, compacted, "every store should be compacted")
+
+	var results map[string]compactionResult
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &results))
+	assert.Equal(t, "disk full", results["apikey"].Error)
+	assert.Empty(t, results["employee"].Error)
+}
+
+func TestAdmin_Backfill(t *testing.T) {
+	failing := false
+	a := New("")
+	a.HandleBackfill(func(context.Context) (int, error) {
+		if failing {
+			return 0, errors.New("secondary unavailable")
+		}
+		return 3, nil
+	})
+
+	rec := do(a, http.MethodPost, "/migration/backfill", "", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var result backfillResult
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Equal(t, 3, result.Employees, "copied employees should be counted")
+
+	failing = true
+	rec = do(a, http.MethodPost, "/migration/backfill", "", "")
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), "secondary unavailable")
+}
+
+func TestAdmin_Chaos(t *testing.T) {
+	injector := chaos.NewInjector()
+	a := New("")
+	a.HandleChaos(injector)
+
+	rec := do(a, http.MethodGet, "/chaos", "", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"enabled": false, "rules": []}`, rec.Body.String())
+
+	rec = do(
+		a,
+		http.MethodPut,
+		"/chaos",
+		"",
+		`{"enabled": true, "rules": [{"method": "*", "fault": "error", "probability": 0.5}]}`,
+	)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, injector.Settings().Enabled, "settings should be applied")
+	assert.Len(t, injector.Settings().Rules, 1)
+
+	rec = do(
+		a,
+		http.MethodPut,
+		"/chaos",
+		"",
+		`{"enabled": true, "rules": [{"method": "*", "fault": "boom", "probability": 1}]}`,
+	)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `unknown fault \"boom\"`)
+	assert.Equal(
+		t,
+		chaos.FaultError,
+		injector.Settings().Rules[0].Fault,
+		"invalid settings should be ignored",
+	)
+}
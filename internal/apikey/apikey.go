@@ -0,0 +1,229 @@
+// Package apikey manages the API keys used by service-to-service clients
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/respository"
+)
+
+// keyPrefix marks the plain text keys issued by this service
+const keyPrefix = "ek"
+
+var (
+	ErrInvalidKey   = errors.New("invalid api key")
+	ErrExpiredKey   = errors.New("api key is expired")
+	ErrRevokedKey   = errors.New("api key is revoked")
+	ErrInvalidScope = errors.New("invalid scope")
+)
+
+// encoding is used for the random parts of the keys, it is URL and header safe
+var encoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// Manager creates, rotates, revokes and verifies API keys
+//
+// Plain text keys have the form "ek_<prefix>_<secret>". Only the SHA-256 hash
+// of the full key is stored, the prefix is used to look the key up.
+type Manager struct {
+	repo    respository.IAPIKeyRepository
+	scopes  []string // Scopes that can be granted to keys
+	now     func() time.Time
+	persist func() error // Saves the keys, nil when they are not persisted
+}
+
+// NewManager creates a new API key manager granting only the given scopes
+func NewManager(repo respository.IAPIKeyRepository, scopes []string) *Manager {
+	return &Manager{repo: repo, scopes: scopes, now: time.Now}
+}
+
+// SetPersist makes create, rotate and revoke save the keys with persist before
+// they return, so that a revoked key is never accepted again after a crash.
+// Failing to save is reported as an error, the change stays in memory.
+//
+// It must be called before the manager is used.
+func (m *Manager) SetPersist(persist func() error) {
+	m.persist = persist
+}
+
+// Create issues a new API key and returns it along with the plain text key,
+// which cannot be recovered later
+func (m *Manager) Create(
+	name string,
+	scopes []string,
+	expiresAt *time.Time,
+) (models.APIKey, string, error) {
+	if err := m.validateScopes(scopes); err != nil {
+		return models.APIKey{}, "", err
+	}
+
+	prefix, plain, hash, err := generate()
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+
+	key, err := m.repo.Create(models.APIKey{
+		Name:      name,
+		Prefix:    prefix,
+		Hash:      hash,
+		Scopes:    slices.Clone(scopes),
+		CreatedAt: m.now().UTC(),
+		ExpiresAt: expiresAt,
+	})
+	if err == nil {
+		err = m.save("create", key.ID)
+	}
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+
+	return key, plain, nil
+}
+
+// List retrieves all API keys, including revoked and expired ones
+func (m *Manager) List(page int, limit int) ([]models.APIKey, int) {
+	return m.repo.GetAll(page, limit)
+}
+
+// Rotate replaces the secret (and prefix) of an API key and returns the new plain
+// text key. The previous key stops working immediately.
+func (m *Manager) Rotate(id models.ID) (models.APIKey, string, error) {
+	prefix, plain, hash, err := generate()
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+
+	key, err := m.repo.UpdateFunc(id, func(current models.APIKey) (models.APIKey, error) {
+		if current.Revoked() {
+			return current, fmt.Errorf("api key with ID %s rotate failed: %w", id, ErrRevokedKey)
+		}
+		current.Prefix = prefix
+		current.Hash = hash
+		current.LastUsedAt = nil
+		return current, nil
+	})
+	if err == nil {
+		err = m.save("rotate", id)
+	}
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+
+	return key, plain, nil
+}
+
+// Revoke permanently disables an API key. Revoking a revoked key is a no-op.
+func (m *Manager) Revoke(id models.ID) (models.APIKey, error) {
+	key, err := m.repo.UpdateFunc(id, func(current models.APIKey) (models.APIKey, error) {
+		if !current.Revoked() {
+			now := m.now().UTC()
+			current.RevokedAt = &now
+		}
+		return current, nil
+	})
+	if err == nil {
+		err = m.save("revoke", id)
+	}
+	if err != nil {
+		return models.APIKey{}, err
+	}
+
+	return key, nil
+}
+
+// Authenticate verifies a plain text key and records its use
+func (m *Manager) Authenticate(plain string) (models.APIKey, error) {
+	prefix, ok := parsePrefix(plain)
+	if !ok {
+		return models.APIKey{}, ErrInvalidKey
+	}
+
+	key, err := m.repo.GetByPrefix(prefix)
+	if err != nil {
+		return models.APIKey{}, ErrInvalidKey
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashKey(plain)), []byte(key.Hash)) != 1 {
+		return models.APIKey{}, ErrInvalidKey
+	}
+
+	now := m.now().UTC()
+	if key.Revoked() {
+		return models.APIKey{}, ErrRevokedKey
+	}
+	if key.Expired(now) {
+		return models.APIKey{}, ErrExpiredKey
+	}
+
+	// Track the last use of the key, the key may have been rotated meanwhile
+	return m.repo.UpdateFunc(key.ID, func(current models.APIKey) (models.APIKey, error) {
+		if current.Hash != key.Hash {
+			return current, ErrInvalidKey
+		}
+		current.LastUsedAt = &now
+		return current, nil
+	})
+}
+
+// save persists the keys after the operation on the key, if they are persisted
+func (m *Manager) save(op string, id models.ID) error {
+	if m.persist == nil {
+		return nil
+	}
+	if err := m.persist(); err != nil {
+		return fmt.Errorf("api key with ID %s %s not persisted: %w", id, op, err)
+	}
+	return nil
+}
+
+func (m *Manager) validateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return fmt.Errorf("%w: at least one scope is required", ErrInvalidScope)
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(m.scopes, scope) {
+			return fmt.Errorf("%w: %q, allowed scopes are %v", ErrInvalidScope, scope, m.scopes)
+		}
+	}
+	return nil
+}
+
+// generate creates a new random key and returns its prefix, plain text and hash
+func generate() (string, string, string, error) {
+	random := make([]byte, 5+32) // 8 characters of prefix and 52 characters of secret
+	if _, err := rand.Read(random); err != nil {
+		return "", "", "", fmt.Errorf("generating api key: %w", err)
+	}
+
+	prefix := encoding.EncodeToString(random[:5])
+	secret := encoding.EncodeToString(random[5:])
+	plain := keyPrefix + "_" + prefix + "_" + secret
+
+	return prefix, plain, hashKey(plain), nil
+}
+
+// parsePrefix extracts the lookup prefix of a plain text key
+func parsePrefix(plain string) (string, bool) {
+	parts := strings.Split(plain, "_")
+	if len(parts) != 3 || parts[0] != keyPrefix || parts[1] == "" || parts[2] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// hashKey returns the hex encoded SHA-256 of a plain text key
+//
+// A fast hash is fine here because keys carry 256 bits of randomness.
+func hashKey(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,145 @@
+package apikey
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/auth"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/idgen"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/respository"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestManager() *Manager {
+	return NewManager(
+		respository.NewAPIKeyInMemoryRepository(idgen.NewSequential()),
+		[]string{"employee.list", "employee.get", "employee.create"},
+	)
+}
+
+func TestManager_CreateAndAuthenticate(t *testing.T) {
+	manager := newTestManager()
+
+	// Create a key
+	key, plain, err := manager.Create("payroll", []string{"employee.list"}, nil)
+	assert.Nil(t, err, "error should be nil")
+	assert.True(t, strings.HasPrefix(plain, "ek_"+key.Prefix+"_"), "key should embed the prefix")
+	assert.NotContains(t, key.Hash, plain, "only the hash should be stored")
+	assert.Nil(t, key.LastUsedAt, "key should not be used yet")
+
+	// Authenticate with the key
+	used, err := manager.Authenticate(plain)
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, key.ID, used.ID, "ID should be %s", key.ID)
+	assert.NotNil(t, used.LastUsedAt, "last use should be tracked")
+
+	// Authenticate with a tampered key
+	_, err = manager.Authenticate(plain + "x")
+	assert.ErrorIs(t, err, ErrInvalidKey, "error should be ErrInvalidKey")
+	_, err = manager.Authenticate("not-a-key")
+	assert.ErrorIs(t, err, ErrInvalidKey, "error should be ErrInvalidKey")
+
+	// Create a key with an unknown scope
+	_, _, err = manager.Create("payroll", []string{"apikey.create"}, nil)
+	assert.ErrorIs(t, err, ErrInvalidScope, "error should be ErrInvalidScope")
+}
+
+func TestManager_RotateRevokeAndExpire(t *testing.T) {
+	manager := newTestManager()
+	key, plain, _ := manager.Create("payroll", []string{"employee.list"}, nil)
+
+	// Rotate the key, the old key stops working
+	rotated, newPlain, err := manager.Rotate(key.ID)
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, key.ID, rotated.ID, "ID should be kept")
+	assert.NotEqual(t, plain, newPlain, "key should change")
+
+	_, err = manager.Authenticate(plain)
+	assert.ErrorIs(t, err, ErrInvalidKey, "old key should be invalid")
+	_, err = manager.Authenticate(newPlain)
+	assert.Nil(t, err, "new key should be valid")
+
+	// Revoke the key
+	revoked, err := manager.Revoke(key.ID)
+	assert.Nil(t, err, "error should be nil")
+	assert.True(t, revoked.Revoked(), "key should be revoked")
+
+	_, err = manager.Authenticate(newPlain)
+	assert.ErrorIs(t, err, ErrRevokedKey, "error should be ErrRevokedKey")
+	_, _, err = manager.Rotate(key.ID)
+	assert.ErrorIs(t, err, ErrRevokedKey, "revoked key should not be rotated")
+
+	// Expired keys are rejected
+	expiresAt := time.Now().Add(time.Hour)
+	_, expiring, _ := manager.Create("importer", []string{"employee.create"}, &expiresAt)
+	manager.now = func() time.Time { return expiresAt.Add(time.Second) }
+	_, err = manager.Authenticate(expiring)
+	assert.ErrorIs(t, err, ErrExpiredKey, "error should be ErrExpiredKey")
+
+	// Listing includes every key
+	keys, total := manager.List(1, 10)
+	assert.Equal(t, 2, total, "total should be 2")
+	assert.Len(t, keys, 2, "keys should be 2")
+}
+
+func TestManager_Persist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api_keys.json")
+	repo := respository.NewAPIKeyInMemoryRepository(idgen.NewSequential())
+	manager := NewManager(repo, []string{"employee.list"})
+	manager.SetPersist(func() error { return respository.SaveSnapshotFile(path, repo) })
+
+	// Revoke a key, nothing else saves the keys
+	key, plain, _ := manager.Create("payroll", []string{"employee.list"}, nil)
+	_, err := manager.Revoke(key.ID)
+	assert.Nil(t, err, "error should be nil")
+
+	// Restart from the saved keys, the key is still revoked
+	restarted := respository.NewAPIKeyInMemoryRepository(idgen.NewSequential())
+	assert.Nil(t, respository.LoadSnapshotFile(path, restarted), "error should be nil")
+	_, err = NewManager(restarted, []string{"employee.list"}).Authenticate(plain)
+	assert.ErrorIs(t, err, ErrRevokedKey, "error should be ErrRevokedKey")
+
+	// Failing to save is reported
+	manager.SetPersist(func() error { return os.ErrPermission })
+	_, _, err = manager.Create("importer", []string{"employee.list"}, nil)
+	assert.ErrorIs(t, err, os.ErrPermission, "error should be os.ErrPermission")
+}
+
+func TestMiddleware(t *testing.T) {
+	manager := newTestManager()
+	_, plain, _ := manager.Create("payroll", []string{"employee.list"}, nil)
+
+	serve := func(authorization string) (int, *auth.Claims) {
+		var claims *auth.Claims
+		handler := Middleware(manager)(func(c echo.Context) error {
+			claims, _ = auth.ClaimsFromContext(c)
+			return c.NoContent(http.StatusNoContent)
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/employees", nil)
+		req.Header.Set(echo.HeaderAuthorization, authorization)
+		rec := httptest.NewRecorder()
+		_ = handler(echo.New().NewContext(req, rec))
+		return rec.Code, claims
+	}
+
+	// A valid key puts its scopes on the context
+	status, claims := serve("ApiKey " + plain)
+	assert.Equal(t, http.StatusNoContent, status, "status should be 204")
+	assert.Equal(t, []string{"employee.list"}, claims.Scopes, "scopes should be on the context")
+
+	// An invalid key is rejected
+	status, _ = serve("ApiKey ek_abcdefgh_secret")
+	assert.Equal(t, http.StatusUnauthorized, status, "status should be 401")
+
+	// Other schemes are left to the next middleware
+	status, claims = serve("Bearer token")
+	assert.Equal(t, http.StatusNoContent, status, "status should be 204")
+	assert.Nil(t, claims, "claims should not be set")
+}
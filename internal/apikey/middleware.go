@@ -0,0 +1,45 @@
+package apikey
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/auth"
+	"github.com/labstack/echo/v4"
+)
+
+// Middleware returns a middleware that authenticates requests carrying an
+// "Authorization: ApiKey <key>" header
+//
+// Authenticated requests get auth.Claims with the subject "apikey:<id>" and the
+// scopes of the key, so that auth.Authorize can check them. Requests using any
+// other scheme are passed through untouched to the next authentication middleware.
+func Middleware(manager *Manager) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			scheme, plain, _ := strings.Cut(c.Request().Header.Get(echo.HeaderAuthorization), " ")
+			if !strings.EqualFold(scheme, "ApiKey") {
+				return next(c)
+			}
+
+			key, err := manager.Authenticate(strings.TrimSpace(plain))
+			if err != nil {
+				message := ErrInvalidKey.Error()
+				if errors.Is(err, ErrExpiredKey) || errors.Is(err, ErrRevokedKey) {
+					message = err.Error()
+				}
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, `ApiKey realm="api"`)
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": message})
+			}
+
+			c.Set(auth.ClaimsContextKey, &auth.Claims{
+				RegisteredClaims: jwt.RegisteredClaims{Subject: "apikey:" + key.ID.String()},
+				Scopes:           key.Scopes,
+			})
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Package auth provides the authentication middleware of the API
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// ClaimsContextKey is the echo context key holding the *Claims of an authenticated request
+const ClaimsContextKey = "auth.claims"
+
+// Claims are the JWT claims of an authenticated request
+type Claims struct {
+	jwt.RegisteredClaims
+	Roles  []Role   `json:"roles,omitempty"` // Roles granted to the caller, see Policy
+	Scopes []string `json:"-"`               // Route names granted directly, e.g. by an API key
+}
+
+// JWTConfig configures the JWT bearer authentication middleware
+//
+// At least one of HMACSecret and JWKS must be set. Tokens signed with HS256 are
+// verified with HMACSecret, tokens signed with RS256 or ES256 are verified with
+// the JWKS key matching their "kid" header.
+type JWTConfig struct {
+	HMACSecret []byte        // Shared secret for HS256 tokens
+	JWKS       JWKS          // Public keys for RS256 and ES256 tokens
+	Issuer     string        // Required "iss" claim, not checked when empty
+	Audience   string        // Required "aud" claim, not checked when empty
+	Leeway     time.Duration // Allowed clock skew when checking "exp", "nbf" and "iat"
+}
+
+// JWT returns a middleware that authenticates requests with a JWT bearer token
+//
+// The token must be signed with a configured key, must not be expired and must
+// match the configured issuer and audience. The verified claims are stored on
+// the echo context, see ClaimsFromContext. Requests already authenticated by a
+// previous middleware, e.g. with an API key, are passed through.
+func JWT(config JWTConfig) (echo.MiddlewareFunc, error) {
+	if len(config.HMACSecret) == 0 && len(config.JWKS) == 0 {
+		return nil, errors.New("jwt: either an HMAC secret or a JWKS is required")
+	}
+
+	var methods []string
+	if len(config.HMACSecret) > 0 {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if len(config.JWKS) > 0 {
+		methods = append(methods, jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg())
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(config.Leeway),
+	}
+	if config.Issuer != "" {
+		options = append(options, jwt.WithIssuer(config.Issuer))
+	}
+	if config.Audience != "" {
+		options = append(options, jwt.WithAudience(config.Audience))
+	}
+	parser := jwt.NewParser(options...)
+
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		if token.Method.Alg() == jwt.SigningMethodHS256.Alg() {
+			return config.HMACSecret, nil
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		key, ok := config.JWKS[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key ID %q", kid)
+		}
+
+		// Make sure the key type matches the algorithm, e.g. no RS256 with an EC key
+		switch key.(type) {
+		case *rsa.PublicKey:
+			if token.Method.Alg() != jwt.SigningMethodRS256.Alg() {
+				return nil, fmt.Errorf("key %q cannot verify %s tokens", kid, token.Method.Alg())
+			}
+		case *ecdsa.PublicKey:
+			if token.Method.Alg() != jwt.SigningMethodES256.Alg() {
+				return nil, fmt.Errorf("key %q cannot verify %s tokens", kid, token.Method.Alg())
+			}
+		}
+		return key, nil
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if _, ok := ClaimsFromContext(c); ok {
+				return next(c)
+			}
+
+			// Extract the bearer token from the Authorization header
+			scheme, tokenString, _ := strings.Cut(
+				c.Request().Header.Get(echo.HeaderAuthorization),
+				" ",
+			)
+			if !strings.EqualFold(scheme, "Bearer") || tokenString == "" {
+				return unauthorized(c, "missing bearer token")
+			}
+
+			// Verify the signature and validate the claims
+			claims := &Claims{}
+			if _, err := parser.ParseWithClaims(tokenString, claims, keyFunc); err != nil {
+				return unauthorized(c, "invalid token: "+tokenError(err))
+			}
+
+			// Put the claims on the echo context
+			c.Set(ClaimsContextKey, claims)
+
+			return next(c)
+		}
+	}, nil
+}
+
+// ClaimsFromContext returns the claims of an authenticated request
+func ClaimsFromContext(c echo.Context) (*Claims, bool) {
+	claims, ok := c.Get(ClaimsContextKey).(*Claims)
+	return claims, ok
+}
+
+// unauthorized returns 401 Unauthorized with a bearer challenge
+func unauthorized(c echo.Context, message string) error {
+	c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer realm="api"`)
+	return c.JSON(http.StatusUnauthorized, map[string]string{"error": message})
+}
+
+// tokenError returns a client-safe description of a token validation error
+func tokenError(err error) string {
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return "token is expired"
+	case errors.Is(err, jwt.ErrTokenNotValidYet):
+		return "token is not valid yet"
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		return "invalid issuer"
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return "invalid audience"
+	case errors.Is(err, jwt.ErrTokenRequiredClaimMissing):
+		return "required claim is missing"
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid), errors.Is(err, jwt.ErrTokenUnverifiable):
+		return "signature is invalid"
+	default:
+		return "malformed token"
+	}
+}
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+var testSecret = []byte("test-secret")
+
+// mintToken signs the claims with the given method, key and optional key ID
+func mintToken(
+	t *testing.T,
+	method jwt.SigningMethod,
+	key interface{},
+	kid string,
+	claims jwt.Claims,
+) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	assert.Nil(t, err, "error should be nil")
+	return signed
+}
+
+func validClaims() jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{
+		Subject:   "user-1",
+		Issuer:    "https://issuer.example.com",
+		Audience:  jwt.ClaimStrings{"employees-api"},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}
+}
+
+// writeJWKS writes the public keys as a JWKS file and returns its path
+func writeJWKS(t *testing.T, rsaKey *rsa.PublicKey, ecKey *ecdsa.PublicKey) string {
+	t.Helper()
+
+	encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	doc := map[string]any{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA",
+				"kid": "rsa-1",
+				"n":   encode(rsaKey.N.Bytes()),
+				"e":   encode(big.NewInt(int64(rsaKey.E)).Bytes()),
+			},
+			{
+				"kty": "EC",
+				"kid": "ec-1",
+				"crv": "P-256",
+				"x":   encode(ecKey.X.FillBytes(make([]byte, 32))),
+				"y":   encode(ecKey.Y.FillBytes(make([]byte, 32))),
+			},
+		},
+	}
+	data, _ := json.Marshal(doc)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.Nil(t, os.WriteFile(path, data, 0o600), "error should be nil")
+	return path
+}
+
+// serve runs a request with the token through the middleware
+func serve(mw echo.MiddlewareFunc, token string) (*httptest.ResponseRecorder, *Claims) {
+	var claims *Claims
+	handler := mw(func(c echo.Context) error {
+		claims, _ = ClaimsFromContext(c)
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/employees", nil)
+	if token != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	_ = handler(echo.New().NewContext(req, rec))
+
+	return rec, claims
+}
+
+func TestJWT_HS256(t *testing.T) {
+	mw, err := JWT(JWTConfig{
+		HMACSecret: testSecret,
+		Issuer:     "https://issuer.example.com",
+		Audience:   "employees-api",
+	})
+	assert.Nil(t, err, "error should be nil")
+
+	// Valid token
+	rec, claims := serve(mw, mintToken(t, jwt.SigningMethodHS256, testSecret, "", validClaims()))
+	assert.Equal(t, http.StatusNoContent, rec.Code, "status should be 204")
+	assert.Equal(t, "user-1", claims.Subject, "claims should be on the context")
+
+	// Missing token
+	rec, _ = serve(mw, "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "status should be 401")
+	assert.Contains(t, rec.Header().Get(echo.HeaderWWWAuthenticate), "Bearer")
+
+	// Wrong secret
+	rec, _ = serve(mw, mintToken(t, jwt.SigningMethodHS256, []byte("other"), "", validClaims()))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "status should be 401")
+
+	// Expired token
+	expired := validClaims()
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	rec, _ = serve(mw, mintToken(t, jwt.SigningMethodHS256, testSecret, "", expired))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "status should be 401")
+	assert.Contains(t, rec.Body.String(), "token is expired")
+
+	// Token without expiry
+	noExpiry := validClaims()
+	noExpiry.ExpiresAt = nil
+	rec, _ = serve(mw, mintToken(t, jwt.SigningMethodHS256, testSecret, "", noExpiry))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "status should be 401")
+
+	// Wrong issuer
+	wrongIssuer := validClaims()
+	wrongIssuer.Issuer = "https://evil.example.com"
+	rec, _ = serve(mw, mintToken(t, jwt.SigningMethodHS256, testSecret, "", wrongIssuer))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "status should be 401")
+	assert.Contains(t, rec.Body.String(), "invalid issuer")
+
+	// Wrong audience
+	wrongAudience := validClaims()
+	wrongAudience.Audience = jwt.ClaimStrings{"other-api"}
+	rec, _ = serve(mw, mintToken(t, jwt.SigningMethodHS256, testSecret, "", wrongAudience))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "status should be 401")
+	assert.Contains(t, rec.Body.String(), "invalid audience")
+}
+
+func TestJWT_JWKS(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	jwks, err := LoadJWKS(writeJWKS(t, &rsaKey.PublicKey, &ecKey.PublicKey))
+	assert.Nil(t, err, "error should be nil")
+	assert.Len(t, jwks, 2, "JWKS should contain 2 keys")
+
+	mw, err := JWT(JWTConfig{JWKS: jwks, Audience: "employees-api"})
+	assert.Nil(t, err, "error should be nil")
+
+	// RS256 and ES256 tokens signed with the JWKS keys
+	rec, _ := serve(mw, mintToken(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", validClaims()))
+	assert.Equal(t, http.StatusNoContent, rec.Code, "status should be 204 for RS256")
+
+	rec, _ = serve(mw, mintToken(t, jwt.SigningMethodES256, ecKey, "ec-1", validClaims()))
+	assert.Equal(t, http.StatusNoContent, rec.Code, "status should be 204 for ES256")
+
+	// Unknown key ID
+	rec, _ = serve(mw, mintToken(t, jwt.SigningMethodRS256, rsaKey, "rsa-2", validClaims()))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "status should be 401")
+
+	// HS256 is rejected when no shared secret is configured
+	rec, _ = serve(mw, mintToken(t, jwt.SigningMethodHS256, testSecret, "", validClaims()))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "status should be 401")
+
+	// An unconfigured middleware cannot be created
+	_, err = JWT(JWTConfig{})
+	assert.NotNil(t, err, "error should not be nil")
+}
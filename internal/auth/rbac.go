@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/routes"
+	"github.com/labstack/echo/v4"
+)
+
+// Role is a role granted to the caller through the "roles" claim
+type Role string
+
+// Supported roles
+const (
+	RoleAdmin  Role = "admin"  // Full access, including deletes and operational routes
+	RoleHR     Role = "hr"     // Manages employees, including salaries
+	RoleViewer Role = "viewer" // Read-only access without salaries
+)
+
+// Permissions that are not tied to a route
+const (
+	// PermissionEmployeeSalary allows reading the salary of employees
+	PermissionEmployeeSalary = "employee.salary"
+)
+
+// Policy maps permissions to the roles that are granted them
+//
+// Route permissions use the echo route name, e.g. "employee.create".
+type Policy map[string][]Role
+
+// DefaultPolicy is the access control policy of the API
+var DefaultPolicy = Policy{
+	"apikey.create":          {RoleAdmin},
+	"apikey.list":            {RoleAdmin},
+	"apikey.rotate":          {RoleAdmin},
+	"apikey.revoke":          {RoleAdmin},
+	"webhook.create":         {RoleAdmin},
+	"webhook.list":           {RoleAdmin},
+	"webhook.get":            {RoleAdmin},
+	"webhook.delete":         {RoleAdmin},
+	"webhook.deliveries":     {RoleAdmin},
+	"webhook.redeliver":      {RoleAdmin},
+	"ratelimit.get":          {RoleAdmin},
+	"ratelimit.update":       {RoleAdmin},
+	"employee.list":          {RoleAdmin, RoleHR, RoleViewer},
+	"employee.get":           {RoleAdmin, RoleHR, RoleViewer},
+	"employee.events":        {RoleAdmin, RoleHR, RoleViewer},
+	"change.list":            {RoleAdmin, RoleHR, RoleViewer},
+	"employee.create":        {RoleAdmin, RoleHR},
+	"employee.update":        {RoleAdmin, RoleHR},
+	"employee.delete":        {RoleAdmin},
+	PermissionEmployeeSalary: {RoleAdmin, RoleHR},
+}
+
+// Allows reports whether any of the roles is granted the permission
+func (p Policy) Allows(permission string, roles []Role) bool {
+	for _, role := range roles {
+		if slices.Contains(p[permission], role) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize returns a middleware that enforces the policy on the matched route
+//
+// It must run after an authentication middleware. Requests whose roles are not
+// granted the route name are rejected with 403 Forbidden. Callers without roles,
+// such as API keys, must instead have the route name in their scopes.
+// Requests that did not match a route are passed through so that echo can
+// answer 404 or 405.
+func Authorize(policy Policy) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			route := routes.Name(c)
+			if route == "" {
+				return next(c)
+			}
+
+			claims, _ := ClaimsFromContext(c)
+			switch {
+			case claims == nil || len(claims.Roles) == 0 && len(claims.Scopes) == 0:
+				return forbidden(
+					c,
+					fmt.Sprintf("no role granted, %s requires one of %v", route, policy[route]),
+				)
+			case len(claims.Roles) > 0 && !policy.Allows(route, claims.Roles):
+				return forbidden(
+					c,
+					fmt.Sprintf("roles %v are not allowed to call %s", claims.Roles, route),
+				)
+			case len(claims.Roles) == 0 && !slices.Contains(claims.Scopes, route):
+				return forbidden(
+					c,
+					fmt.Sprintf("scopes %v do not include %s", claims.Scopes, route),
+				)
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// Can reports whether the caller of the request is granted the permission,
+// either through its roles or directly through its scopes
+func Can(c echo.Context, policy Policy, permission string) bool {
+	claims, ok := ClaimsFromContext(c)
+	if !ok {
+		return false
+	}
+	return policy.Allows(permission, claims.Roles) || slices.Contains(claims.Scopes, permission)
+}
+
+// forbidden returns 403 Forbidden with the reason
+func forbidden(c echo.Context, reason string) error {
+	return c.JSON(http.StatusForbidden, map[string]string{"error": "forbidden: " + reason})
+}
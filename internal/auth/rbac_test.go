@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// newRBACApp creates an echo app with employee routes protected by the default policy,
+// where the roles and scopes of the caller are taken from the X-Roles and X-Scopes test headers
+func newRBACApp() *echo.Echo {
+	app := echo.New()
+
+	withRoles := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims := &Claims{}
+			for _, role := range c.Request().Header.Values("X-Roles") {
+				claims.Roles = append(claims.Roles, Role(role))
+			}
+			claims.Scopes = c.Request().Header.Values("X-Scopes")
+			c.Set(ClaimsContextKey, claims)
+			return next(c)
+		}
+	}
+
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusNoContent) }
+	group := app.Group("/employees", withRoles, Authorize(DefaultPolicy))
+	group.GET("", ok).Name = "employee.list"
+	group.POST("", ok).Name = "employee.create"
+	group.DELETE("/:id", ok).Name = "employee.delete"
+	group.GET("/unlisted", ok).Name = "employee.unlisted"
+
+	return app
+}
+
+func TestAuthorize(t *testing.T) {
+	app := newRBACApp()
+
+	testCases := []struct {
+		method string
+		path   string
+		roles  []string
+		status int
+	}{
+		{http.MethodGet, "/employees", []string{"viewer"}, http.StatusNoContent},
+		{http.MethodPost, "/employees", []string{"viewer"}, http.StatusForbidden},
+		{http.MethodPost, "/employees", []string{"hr"}, http.StatusNoContent},
+		{http.MethodDelete, "/employees/1", []string{"hr"}, http.StatusForbidden},
+		{http.MethodDelete, "/employees/1", []string{"viewer", "admin"}, http.StatusNoContent},
+		{http.MethodGet, "/employees", nil, http.StatusForbidden},
+		// Routes missing from the policy are denied
+		{http.MethodGet, "/employees/unlisted", []string{"admin"}, http.StatusForbidden},
+		// Unmatched routes are left to echo
+		{http.MethodGet, "/employees/1/unknown", []string{"viewer"}, http.StatusNotFound},
+		{http.MethodPatch, "/employees", []string{"viewer"}, http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		for _, role := range tc.roles {
+			req.Header.Add("X-Roles", role)
+		}
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+
+		assert.Equal(t, tc.status, rec.Code, "%s %s with roles %v", tc.method, tc.path, tc.roles)
+	}
+}
+
+func TestAuthorize_Reason(t *testing.T) {
+	app := newRBACApp()
+
+	req := httptest.NewRequest(http.MethodPost, "/employees", nil)
+	req.Header.Add("X-Roles", "viewer")
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code, "status should be 403")
+	assert.JSONEq(
+		t,
+		`{"error":"forbidden: roles [viewer] are not allowed to call employee.create"}`,
+		rec.Body.String(),
+	)
+}
+
+func TestPolicy_Allows(t *testing.T) {
+	assert.True(t, DefaultPolicy.Allows(PermissionEmployeeSalary, []Role{RoleHR}))
+	assert.False(t, DefaultPolicy.Allows(PermissionEmployeeSalary, []Role{RoleViewer}))
+	assert.False(t, DefaultPolicy.Allows("employee.unknown", []Role{RoleAdmin}))
+}
+
+func TestAuthorize_Scopes(t *testing.T) {
+	app := newRBACApp()
+
+	testCases := []struct {
+		method string
+		scopes []string
+		status int
+	}{
+		{http.MethodGet, []string{"employee.list"}, http.StatusNoContent},
+		{http.MethodPost, []string{"employee.list"}, http.StatusForbidden},
+		{http.MethodPost, []string{"employee.list", "employee.create"}, http.StatusNoContent},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest(tc.method, "/employees", nil)
+		for _, scope := range tc.scopes {
+			req.Header.Add("X-Scopes", scope)
+		}
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+
+		assert.Equal(t, tc.status, rec.Code, "%s /employees with scopes %v", tc.method, tc.scopes)
+	}
+}
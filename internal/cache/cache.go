@@ -0,0 +1,234 @@
+// Package cache caches the employees read by ID in front of slower repositories
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/respository"
+	"golang.org/x/sync/singleflight"
+)
+
+// Ensure type implements the interface
+var _ respository.IEmployeeRepository = (*CachedEmployeeRepository)(nil)
+
+var _ respository.ContextBinder[respository.IEmployeeRepository] = (*CachedEmployeeRepository)(
+	nil,
+)
+
+var _ respository.HistoryReader[models.Employee] = (*CachedEmployeeRepository)(nil)
+
+// Stats are the counters of a cache since it was created
+type Stats struct {
+	Hits          uint64 `json:"hits"`
+	Misses        uint64 `json:"misses"`        // Including the expired entries
+	Evictions     uint64 `json:"evictions"`     // Least recently used entries dropped when full
+	Invalidations uint64 `json:"invalidations"` // Writes through the cache
+	Size          int    `json:"size"`
+}
+
+// entry is a cached employee
+type entry struct {
+	id        models.ID
+	employee  models.Employee
+	expiresAt time.Time
+}
+
+// lru is the state shared by the context bound copies of a cache
+type lru struct {
+	mu         sync.Mutex
+	capacity   int
+	ttl        time.Duration
+	entries    map[models.ID]*list.Element // Elements of order, holding an *entry
+	order      *list.List                  // Most recently used first
+	generation uint64                      // Incremented by every invalidation
+	stats      Stats
+	loads      singleflight.Group // Coalesces the concurrent misses of an ID
+	now        func() time.Time
+}
+
+// CachedEmployeeRepository is an IEmployeeRepository decorator caching the
+// employees read by ID in a bounded LRU, each for a limited time
+//
+// Writes through the decorator invalidate the employee, and reads never fill
+// the cache with an employee read before an invalidation, so that no stale
+// employee is returned once a write returned. Writes that bypass the decorator
+// are only seen once the entries expire.
+type CachedEmployeeRepository struct {
+	next  respository.IEmployeeRepository
+	cache *lru
+}
+
+// CacheEmployeeRepository wraps the repository with a cache of the given
+// number of employees, each kept for the TTL
+func CacheEmployeeRepository(
+	next respository.IEmployeeRepository,
+	capacity int,
+	ttl time.Duration,
+) *CachedEmployeeRepository {
+	return &CachedEmployeeRepository{
+		next: next,
+		cache: &lru{
+			capacity: max(capacity, 1),
+			ttl:      ttl,
+			entries:  make(map[models.ID]*list.Element),
+			order:    list.New(),
+			now:      time.Now,
+		},
+	}
+}
+
+// WithContext binds the wrapped repository to the context, the cache is shared
+func (r *CachedEmployeeRepository) WithContext(
+	ctx context.Context,
+) respository.IEmployeeRepository {
+	return &CachedEmployeeRepository{next: respository.BindContext(ctx, r.next), cache: r.cache}
+}
+
+// Stats returns the counters of the cache
+func (r *CachedEmployeeRepository) Stats() Stats {
+	r.cache.mu.Lock()
+	defer r.cache.mu.Unlock()
+
+	stats := r.cache.stats
+	stats.Size = r.cache.order.Len()
+	return stats
+}
+
+func (r *CachedEmployeeRepository) CreateEmployee(
+	name string,
+	email string,
+	position string,
+	salary float64,
+) (models.Employee, error) {
+	// New employees are not cached, they are not read yet
+	return r.next.CreateEmployee(name, email, position, salary)
+}
+
+func (r *CachedEmployeeRepository) GetEmployeeByID(id models.ID) (models.Employee, error) {
+	employee, generation, ok := r.cache.get(id)
+	if ok {
+		return employee, nil
+	}
+
+	// Concurrent misses share the read of the first one
+	result, err, _ := r.cache.loads.Do(string(id), func() (any, error) {
+		employee, err := r.next.GetEmployeeByID(id)
+		if err == nil {
+			r.cache.set(id, employee, generation)
+		}
+		return employee, err
+	})
+	if err != nil {
+		return models.Employee{}, err
+	}
+	return result.(models.Employee), nil
+}
+
+func (r *CachedEmployeeRepository) UpdateEmployee(
+	id models.ID,
+	name string,
+	email string,
+	position string,
+	salary float64,
+) (models.Employee, error) {
+	defer r.cache.invalidate(id)
+	return r.next.UpdateEmployee(id, name, email, position, salary)
+}
+
+func (r *CachedEmployeeRepository) DeleteEmployee(id models.ID) error {
+	defer r.cache.invalidate(id)
+	return r.next.DeleteEmployee(id)
+}
+
+func (r *CachedEmployeeRepository) GetAllEmployees(
+	page int,
+	limit int,
+) ([]models.Employee, int) {
+	return r.next.GetAllEmployees(page, limit)
+}
+
+// GetByIDAsOf reads the past version from the wrapped repository, past
+// versions are not cached
+func (r *CachedEmployeeRepository) GetByIDAsOf(
+	id models.ID,
+	asOf time.Time,
+) (models.Employee, error) {
+	return respository.HistoryOf[models.Employee](r.next).GetByIDAsOf(id, asOf)
+}
+
+// GetAllAsOf reads the past versions from the wrapped repository, past
+// versions are not cached
+func (r *CachedEmployeeRepository) GetAllAsOf(
+	asOf time.Time,
+	page int,
+	limit int,
+) ([]models.Employee, int, error) {
+	return respository.HistoryOf[models.Employee](r.next).GetAllAsOf(asOf, page, limit)
+}
+
+// get returns the cached employee, or the current generation on a miss
+func (c *lru) get(id models.ID) (models.Employee, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[id]
+	if ok && c.now().Before(element.Value.(*entry).expiresAt) {
+		c.order.MoveToFront(element)
+		c.stats.Hits++
+		return element.Value.(*entry).employee, 0, true
+	}
+	if ok {
+		c.remove(element) // Expired
+	}
+	c.stats.Misses++
+	return models.Employee{}, c.generation, false
+}
+
+// set caches the employee read at the generation, unless it was invalidated since
+func (c *lru) set(id models.ID, employee models.Employee, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return // Possibly stale
+	}
+
+	if element, ok := c.entries[id]; ok {
+		c.remove(element)
+	}
+	c.entries[id] = c.order.PushFront(&entry{
+		id:        id,
+		employee:  employee,
+		expiresAt: c.now().Add(c.ttl),
+	})
+
+	// Evict the least recently used employees
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+
+// invalidate drops the employee, and the reads of it in progress
+func (c *lru) invalidate(id models.ID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.stats.Invalidations++
+	if element, ok := c.entries[id]; ok {
+		c.remove(element)
+	}
+	// Reads starting from now on do not join the reads started before the write
+	c.loads.Forget(string(id))
+}
+
+// remove drops the element, the caller must hold the lock
+func (c *lru) remove(element *list.Element) {
+	delete(c.entries, element.Value.(*entry).id)
+	c.order.Remove(element)
+}
@@ -0,0 +1,168 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/respository"
+	"github.com/stretchr/testify/assert"
+)
+
+// slowRepository counts the reads by ID, and blocks them while gate is set
+type slowRepository struct {
+	respository.IEmployeeRepository
+	reads   atomic.Int32
+	gate    chan struct{} // Reads wait for it to be closed when not nil
+	started chan struct{} // Receives a value when a read starts, when not nil
+}
+
+func (r *slowRepository) GetEmployeeByID(id models.ID) (models.Employee, error) {
+	r.reads.Add(1)
+	employee, err := r.IEmployeeRepository.GetEmployeeByID(id)
+	if r.started != nil {
+		r.started <- struct{}{}
+	}
+	if r.gate != nil {
+		<-r.gate
+	}
+	return employee, err
+}
+
+func newCache(capacity int, ttl time.Duration) (*CachedEmployeeRepository, *slowRepository) {
+	slow := &slowRepository{IEmployeeRepository: respository.NewEmployeeInMemoryRepository()}
+	return CacheEmployeeRepository(slow, capacity, ttl), slow
+}
+
+func TestCachedEmployeeRepository_HitsAndMisses(t *testing.T) {
+	repo, slow := newCache(10, time.Minute)
+	employee, _ := repo.CreateEmployee("Harshit", "harshit@example.com", "Engineer", 1000)
+
+	for i := 0; i < 3; i++ {
+		cached, err := repo.GetEmployeeByID(employee.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, employee, cached)
+	}
+	assert.Equal(t, int32(1), slow.reads.Load(), "only the first read should reach the repository")
+
+	// Errors are not cached
+	_, err := repo.GetEmployeeByID("42")
+	assert.ErrorIs(t, err, respository.ErrRecordNotFound)
+	_, err = repo.GetEmployeeByID("42")
+	assert.ErrorIs(t, err, respository.ErrRecordNotFound)
+
+	assert.Equal(t, Stats{Hits: 2, Misses: 3, Size: 1}, repo.Stats())
+}
+
+func TestCachedEmployeeRepository_Eviction(t *testing.T) {
+	repo, slow := newCache(2, time.Minute)
+	first, _ := repo.CreateEmployee("Ganesh", "ganesh@example.com", "Engineer", 1000)
+	second, _ := repo.CreateEmployee("Harshit", "harshit@example.com", "Engineer", 1000)
+	third, _ := repo.CreateEmployee("Rahul", "rahul@example.com", "Engineer", 1000)
+
+	repo.GetEmployeeByID(first.ID)
+	repo.GetEmployeeByID(second.ID)
+	repo.GetEmployeeByID(first.ID)  // The second is now the least recently used
+	repo.GetEmployeeByID(third.ID)  // Evicts the second
+	repo.GetEmployeeByID(first.ID)  // Hit
+	repo.GetEmployeeByID(second.ID) // Miss, evicts the third
+	assert.Equal(t, int32(4), slow.reads.Load())
+
+	stats := repo.Stats()
+	assert.Equal(t, uint64(2), stats.Evictions)
+	assert.Equal(t, 2, stats.Size, "size should be bounded")
+}
+
+func TestCachedEmployeeRepository_TTL(t *testing.T) {
+	repo, slow := newCache(10, time.Minute)
+	now := time.Now()
+	repo.cache.now = func() time.Time { return now }
+	employee, _ := repo.CreateEmployee("Harshit", "harshit@example.com", "Engineer", 1000)
+
+	repo.GetEmployeeByID(employee.ID)
+	now = now.Add(59 * time.Second)
+	repo.GetEmployeeByID(employee.ID)
+	assert.Equal(t, int32(1), slow.reads.Load(), "entry should be fresh")
+
+	now = now.Add(time.Second)
+	repo.GetEmployeeByID(employee.ID)
+	assert.Equal(t, int32(2), slow.reads.Load(), "entry should expire")
+}
+
+func TestCachedEmployeeRepository_CoalescesMisses(t *testing.T) {
+	repo, slow := newCache(10, time.Minute)
+	employee, _ := repo.CreateEmployee("Harshit", "harshit@example.com", "Engineer", 1000)
+	slow.gate = make(chan struct{})
+	slow.started = make(chan struct{}, 10)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cached, err := repo.GetEmployeeByID(employee.ID)
+			assert.Nil(t, err)
+			assert.Equal(t, employee, cached)
+		}()
+	}
+	<-slow.started
+	assert.Eventually(
+		t,
+		func() bool { return repo.Stats().Misses == 10 },
+		time.Second,
+		time.Millisecond,
+	)
+	close(slow.gate)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), slow.reads.Load(), "concurrent misses should share one read")
+}
+
+func TestCachedEmployeeRepository_NoStaleReadAfterUpdate(t *testing.T) {
+	repo, slow := newCache(10, time.Minute)
+	employee, _ := repo.CreateEmployee("Harshit", "harshit@example.com", "Engineer", 1000)
+
+	// A cached employee is invalidated by the update
+	repo.GetEmployeeByID(employee.ID)
+	_, err := repo.UpdateEmployee(employee.ID, "Harshit", "harshit@example.com", "Lead", 2000)
+	assert.Nil(t, err)
+	cached, _ := repo.GetEmployeeByID(employee.ID)
+	assert.Equal(t, "Lead", cached.Position, "update should be read")
+
+	// A read started before the update returns the old employee, but neither
+	// caches it nor shares it with the reads started after the update
+	slow.gate = make(chan struct{})
+	slow.started = make(chan struct{}, 10)
+	repo.cache.invalidate(employee.ID) // Force a miss
+	before := make(chan models.Employee)
+	go func() {
+		employee, _ := repo.GetEmployeeByID(employee.ID)
+		before <- employee
+	}()
+	<-slow.started // The old employee was read
+
+	_, err = repo.UpdateEmployee(employee.ID, "Harshit", "harshit@example.com", "Manager", 3000)
+	assert.Nil(t, err)
+
+	after := make(chan models.Employee)
+	go func() {
+		employee, _ := repo.GetEmployeeByID(employee.ID)
+		after <- employee
+	}()
+	<-slow.started // The read after the update did not join the read before it
+	close(slow.gate)
+
+	assert.Equal(t, "Lead", (<-before).Position)
+	assert.Equal(t, "Manager", (<-after).Position, "read after the update should not be stale")
+
+	slow.gate, slow.started = nil, nil
+	cached, _ = repo.GetEmployeeByID(employee.ID)
+	assert.Equal(t, "Manager", cached.Position, "old employee should not be cached")
+
+	// Deleted employees are not read from the cache
+	assert.Nil(t, repo.DeleteEmployee(employee.ID))
+	_, err = repo.GetEmployeeByID(employee.ID)
+	assert.ErrorIs(t, err, respository.ErrRecordNotFound)
+}
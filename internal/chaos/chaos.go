@@ -0,0 +1,171 @@
+// Package chaos injects faults into the employee repository, to test how the
+// API behaves when the storage misbehaves
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/respository"
+)
+
+// Fault is the kind of failure injected into a call
+type Fault string
+
+// Faults
+const (
+	FaultLatency  Fault = "latency"   // Delays the call by the latency
+	FaultNotFound Fault = "not_found" // Fails with a wrapped respository.ErrRecordNotFound
+	FaultError    Fault = "error"     // Fails with an arbitrary error
+	FaultTimeout  Fault = "timeout"   // Waits for the latency, then fails with context.DeadlineExceeded
+)
+
+// AnyMethod matches every method of the repository
+const AnyMethod = "*"
+
+// ErrInjected is the arbitrary error of the error fault
+var ErrInjected = errors.New("injected fault")
+
+// methods are the methods of the repository, GetAllEmployees cannot fail
+var methods = []string{
+	"CreateEmployee",
+	"GetEmployeeByID",
+	"UpdateEmployee",
+	"DeleteEmployee",
+	"GetAllEmployees",
+	"GetByIDAsOf",
+	"GetAllAsOf",
+}
+
+// Rule injects a fault into the calls of a method with a probability
+type Rule struct {
+	Method      string  `json:"method"` // Method of IEmployeeRepository, or "*"
+	Fault       Fault   `json:"fault"`
+	Probability float64 `json:"probability"`          // From 0 (never) to 1 (every call)
+	LatencyMS   int     `json:"latency_ms,omitempty"` // Delay of the latency fault, maximum wait of the timeout fault
+}
+
+// Settings are the rules of an injector and whether they are applied
+type Settings struct {
+	Enabled bool   `json:"enabled"`
+	Rules   []Rule `json:"rules"`
+}
+
+// Validate validates the settings
+func (s Settings) Validate() error {
+	for i, rule := range s.Rules {
+		switch {
+		case rule.Method != AnyMethod && !slices.Contains(methods, rule.Method):
+			return fmt.Errorf("rules[%d]: unknown method %q", i, rule.Method)
+		case !slices.Contains(
+			[]Fault{FaultLatency, FaultNotFound, FaultError, FaultTimeout},
+			rule.Fault,
+		):
+			return fmt.Errorf("rules[%d]: unknown fault %q", i, rule.Fault)
+		case rule.Method == "GetAllEmployees" && rule.Fault != FaultLatency:
+			return fmt.Errorf("rules[%d]: GetAllEmployees cannot fail, only latency applies", i)
+		case rule.Probability < 0 || rule.Probability > 1:
+			return fmt.Errorf("rules[%d]: probability must be between 0 and 1", i)
+		case rule.LatencyMS < 0:
+			return fmt.Errorf("rules[%d]: latency_ms must not be negative", i)
+		}
+	}
+	return nil
+}
+
+// Injector holds the rules applied by the repositories it wraps, they can be
+// changed at runtime
+type Injector struct {
+	mu       sync.RWMutex
+	settings Settings
+	roll     func() float64 // Returns a number in [0, 1)
+}
+
+// NewInjector creates a new disabled injector without rules
+func NewInjector() *Injector {
+	return &Injector{settings: Settings{Rules: []Rule{}}, roll: rand.Float64}
+}
+
+// Settings returns the current settings
+func (i *Injector) Settings() Settings {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	settings := i.settings
+	settings.Rules = slices.Clone(settings.Rules)
+	return settings
+}
+
+// Configure validates and replaces the settings
+func (i *Injector) Configure(settings Settings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	settings.Rules = slices.Clone(settings.Rules)
+	if settings.Rules == nil {
+		settings.Rules = []Rule{}
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.settings = settings
+	return nil
+}
+
+// inject applies the rules of the method to a call, and returns the error the
+// call fails with, if any
+//
+// Every matching rule rolls on its own, in order. Latencies add up, the first
+// failure stops the others. Errors are ignored when the method cannot fail.
+func (i *Injector) inject(ctx context.Context, method string, canFail bool) error {
+	i.mu.RLock()
+	settings := i.settings
+	i.mu.RUnlock()
+	if !settings.Enabled {
+		return nil
+	}
+
+	for _, rule := range settings.Rules {
+		if rule.Method != AnyMethod && rule.Method != method {
+			continue
+		}
+		if rule.Fault != FaultLatency && !canFail {
+			continue
+		}
+		if i.roll() >= rule.Probability {
+			continue
+		}
+
+		latency := time.Duration(rule.LatencyMS) * time.Millisecond
+		switch rule.Fault {
+		case FaultLatency:
+			if err := sleep(ctx, latency); err != nil {
+				return fmt.Errorf("chaos: %s: %w", method, err)
+			}
+		case FaultNotFound:
+			return fmt.Errorf("chaos: %s: %w", method, respository.ErrRecordNotFound)
+		case FaultError:
+			return fmt.Errorf("chaos: %s: %w", method, ErrInjected)
+		case FaultTimeout:
+			_ = sleep(ctx, latency)
+			return fmt.Errorf("chaos: %s: %w", method, context.DeadlineExceeded)
+		}
+	}
+	return nil
+}
+
+// sleep waits for the duration, or until the context is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
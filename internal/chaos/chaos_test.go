@@ -0,0 +1,128 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/respository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInjector_Disabled(t *testing.T) {
+	injector := NewInjector()
+	err := injector.Configure(Settings{
+		Rules: []Rule{{Method: AnyMethod, Fault: FaultError, Probability: 1}},
+	})
+	assert.Nil(t, err)
+
+	repo := injector.Wrap(respository.NewEmployeeInMemoryRepository())
+	_, err = repo.CreateEmployee("Harshit", "harshit@example.com", "Engineer", 1000)
+	assert.Nil(t, err, "rules should not apply while disabled")
+}
+
+func TestChaosEmployeeRepository_Faults(t *testing.T) {
+	injector := NewInjector()
+	store := respository.NewEmployeeInMemoryRepository()
+	employee, err := store.CreateEmployee("Harshit", "harshit@example.com", "Engineer", 1000)
+	assert.Nil(t, err)
+	repo := injector.Wrap(store)
+
+	// Not found
+	assert.Nil(t, injector.Configure(Settings{
+		Enabled: true,
+		Rules:   []Rule{{Method: "GetEmployeeByID", Fault: FaultNotFound, Probability: 1}},
+	}))
+	_, err = repo.GetEmployeeByID(employee.ID)
+	assert.ErrorIs(t, err, respository.ErrRecordNotFound)
+	_, err = repo.UpdateEmployee(employee.ID, "Harshit", "harshit@example.com", "Lead", 1000)
+	assert.Nil(t, err, "rules should only apply to their method")
+
+	// Arbitrary error, the store is left unchanged
+	assert.Nil(t, injector.Configure(Settings{
+		Enabled: true,
+		Rules:   []Rule{{Method: AnyMethod, Fault: FaultError, Probability: 1}},
+	}))
+	assert.ErrorIs(t, repo.DeleteEmployee(employee.ID), ErrInjected)
+	_, err = store.GetEmployeeByID(employee.ID)
+	assert.Nil(t, err, "failed calls should not reach the store")
+	employees, total := repo.GetAllEmployees(1, 10)
+	assert.Len(t, employees, 1, "the list cannot fail")
+	assert.Equal(t, 1, total)
+
+	// Timeout, cut short by the deadline of the context
+	assert.Nil(t, injector.Configure(Settings{
+		Enabled: true,
+		Rules: []Rule{
+			{Method: "UpdateEmployee", Fault: FaultTimeout, Probability: 1, LatencyMS: 60_000},
+		},
+	}))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	bound := respository.BindContext[respository.IEmployeeRepository](ctx, repo)
+	start := time.Now()
+	_, err = bound.UpdateEmployee(employee.ID, "Harshit", "harshit@example.com", "Lead", 1000)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second, "timeouts should end with the context")
+}
+
+func TestChaosEmployeeRepository_Latency(t *testing.T) {
+	injector := NewInjector()
+	assert.Nil(t, injector.Configure(Settings{
+		Enabled: true,
+		Rules: []Rule{
+			{Method: "GetAllEmployees", Fault: FaultLatency, Probability: 1, LatencyMS: 20},
+			{Method: AnyMethod, Fault: FaultLatency, Probability: 1, LatencyMS: 20},
+		},
+	}))
+	repo := injector.Wrap(respository.NewEmployeeInMemoryRepository())
+
+	start := time.Now()
+	repo.GetAllEmployees(1, 10)
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond, "latencies should add up")
+}
+
+func TestInjector_Probability(t *testing.T) {
+	injector := NewInjector()
+	rolls := []float64{0.2, 0.7}
+	injector.roll = func() float64 {
+		roll := rolls[0]
+		rolls = rolls[1:]
+		return roll
+	}
+	assert.Nil(t, injector.Configure(Settings{
+		Enabled: true,
+		Rules:   []Rule{{Method: AnyMethod, Fault: FaultError, Probability: 0.5}},
+	}))
+	repo := injector.Wrap(respository.NewEmployeeInMemoryRepository())
+
+	_, err := repo.CreateEmployee("Harshit", "harshit@example.com", "Engineer", 1000)
+	assert.True(t, errors.Is(err, ErrInjected), "rolls below the probability should fail")
+	_, err = repo.CreateEmployee("Harshit", "harshit@example.com", "Engineer", 1000)
+	assert.Nil(t, err, "rolls above the probability should pass")
+}
+
+func TestSettings_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+		err  string
+	}{
+		{"method", Rule{Method: "Nope", Fault: FaultError}, `unknown method "Nope"`},
+		{"fault", Rule{Method: AnyMethod, Fault: "boom"}, `unknown fault "boom"`},
+		{
+			"list",
+			Rule{Method: "GetAllEmployees", Fault: FaultError},
+			"GetAllEmployees cannot fail",
+		},
+		{"probability", Rule{Method: AnyMethod, Fault: FaultError, Probability: 2}, "probability"},
+		{"latency", Rule{Method: AnyMethod, Fault: FaultLatency, LatencyMS: -1}, "latency_ms"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Settings{Rules: []Rule{tt.rule}}.Validate()
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
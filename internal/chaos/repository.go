@@ -0,0 +1,114 @@
+package chaos
+
+import (
+	"context"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/respository"
+)
+
+// Ensure type implements the interface
+var _ respository.IEmployeeRepository = (*ChaosEmployeeRepository)(nil)
+
+var _ respository.ContextBinder[respository.IEmployeeRepository] = (*ChaosEmployeeRepository)(
+	nil,
+)
+
+var _ respository.HistoryReader[models.Employee] = (*ChaosEmployeeRepository)(nil)
+
+// ChaosEmployeeRepository is an IEmployeeRepository decorator injecting the
+// faults of the injector before every call
+//
+// Failed calls never reach the wrapped repository, so that injected failures
+// leave the store unchanged.
+type ChaosEmployeeRepository struct {
+	next     respository.IEmployeeRepository
+	injector *Injector
+	ctx      context.Context // Context the latencies and timeouts are cut short by
+}
+
+// Wrap wraps the repository with the faults of the injector
+func (i *Injector) Wrap(next respository.IEmployeeRepository) *ChaosEmployeeRepository {
+	return &ChaosEmployeeRepository{next: next, injector: i, ctx: context.Background()}
+}
+
+// WithContext binds the wrapped repository, and the injected waits, to the context
+func (r *ChaosEmployeeRepository) WithContext(
+	ctx context.Context,
+) respository.IEmployeeRepository {
+	return &ChaosEmployeeRepository{
+		next:     respository.BindContext(ctx, r.next),
+		injector: r.injector,
+		ctx:      ctx,
+	}
+}
+
+func (r *ChaosEmployeeRepository) CreateEmployee(
+	name string,
+	email string,
+	position string,
+	salary float64,
+) (models.Employee, error) {
+	if err := r.injector.inject(r.ctx, "CreateEmployee", true); err != nil {
+		return models.Employee{}, err
+	}
+	return r.next.CreateEmployee(name, email, position, salary)
+}
+
+func (r *ChaosEmployeeRepository) GetEmployeeByID(id models.ID) (models.Employee, error) {
+	if err := r.injector.inject(r.ctx, "GetEmployeeByID", true); err != nil {
+		return models.Employee{}, err
+	}
+	return r.next.GetEmployeeByID(id)
+}
+
+func (r *ChaosEmployeeRepository) UpdateEmployee(
+	id models.ID,
+	name string,
+	email string,
+	position string,
+	salary float64,
+) (models.Employee, error) {
+	if err := r.injector.inject(r.ctx, "UpdateEmployee", true); err != nil {
+		return models.Employee{}, err
+	}
+	return r.next.UpdateEmployee(id, name, email, position, salary)
+}
+
+func (r *ChaosEmployeeRepository) DeleteEmployee(id models.ID) error {
+	if err := r.injector.inject(r.ctx, "DeleteEmployee", true); err != nil {
+		return err
+	}
+	return r.next.DeleteEmployee(id)
+}
+
+func (r *ChaosEmployeeRepository) GetAllEmployees(
+	page int,
+	limit int,
+) ([]models.Employee, int) {
+	// Only latencies apply, the list cannot fail
+	_ = r.injector.inject(r.ctx, "GetAllEmployees", false)
+	return r.next.GetAllEmployees(page, limit)
+}
+
+func (r *ChaosEmployeeRepository) GetByIDAsOf(
+	id models.ID,
+	asOf time.Time,
+) (models.Employee, error) {
+	if err := r.injector.inject(r.ctx, "GetByIDAsOf", true); err != nil {
+		return models.Employee{}, err
+	}
+	return respository.HistoryOf[models.Employee](r.next).GetByIDAsOf(id, asOf)
+}
+
+func (r *ChaosEmployeeRepository) GetAllAsOf(
+	asOf time.Time,
+	page int,
+	limit int,
+) ([]models.Employee, int, error) {
+	if err := r.injector.inject(r.ctx, "GetAllAsOf", true); err != nil {
+		return nil, 0, err
+	}
+	return respository.HistoryOf[models.Employee](r.next).GetAllAsOf(asOf, page, limit)
+}
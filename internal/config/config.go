@@ -0,0 +1,405 @@
+// Package config provides the typed configuration of the application
+//
+// The configuration is loaded from, in increasing order of precedence:
+// built-in defaults, a YAML or JSON file, environment variables and CLI flags.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"path/filepath"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/idgen"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/logging"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/migration"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/ratelimit"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/tracing"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/webhook"
+)
+
+// Supported storage backends
+const (
+	BackendMemory       = "memory"       // Data lives in memory only and is lost on exit
+	BackendFile         = "file"         // Data lives in memory and is snapshotted to files in the data dir
+	BackendEventSourced = "eventsourced" // Employees are folded from an event log in the data dir
+)
+
+// mask replaces secrets in the effective configuration
+const mask = "******"
+
+// Config is the configuration of the application
+type Config struct {
+	Server     ServerConfig     `json:"server"      yaml:"server"`
+	Admin      AdminConfig      `json:"admin"       yaml:"admin"`
+	Storage    StorageConfig    `json:"storage"     yaml:"storage"`
+	Migration  MigrationConfig  `json:"migration"   yaml:"migration"`
+	Pagination PaginationConfig `json:"pagination"  yaml:"pagination"`
+	Log        LogConfig        `json:"log"         yaml:"log"`
+	Health     HealthConfig     `json:"health"      yaml:"health"`
+	Events     EventsConfig     `json:"events"      yaml:"events"`
+	Webhooks   WebhooksConfig   `json:"webhooks"    yaml:"webhooks"`
+	IDs        IDConfig         `json:"ids"         yaml:"ids"`
+	Auth       AuthConfig       `json:"auth"        yaml:"auth"`
+	RateLimits ratelimit.Limits `json:"rate_limits" yaml:"rate_limits"`
+	Tracing    tracing.Config   `json:"tracing"     yaml:"tracing"`
+}
+
+// ServerConfig configures the HTTP server
+type ServerConfig struct {
+	Address         string   `json:"address"          yaml:"address"`
+	ReadTimeout     Duration `json:"read_timeout"     yaml:"read_timeout"`
+	WriteTimeout    Duration `json:"write_timeout"    yaml:"write_timeout"`
+	IdleTimeout     Duration `json:"idle_timeout"     yaml:"idle_timeout"`
+	ShutdownTimeout Duration `json:"shutdown_timeout" yaml:"shutdown_timeout"` // Drain deadline, then flush deadline
+	PreStopDelay    Duration `json:"pre_stop_delay"   yaml:"pre_stop_delay"`   // Serving after readiness fails, before draining
+	TrustedProxies  []string `json:"trusted_proxies"  yaml:"trusted_proxies"`  // IPs or CIDRs allowed to set X-Forwarded-For
+}
+
+// TrustedProxyNets parses the trusted proxies, single IPs become single address networks
+func (c ServerConfig) TrustedProxyNets() ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(c.TrustedProxies))
+	for _, proxy := range c.TrustedProxies {
+		if ip := net.ParseIP(proxy); ip != nil {
+			bits := 8 * len(ip.To16())
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an IP or a CIDR", proxy)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// AdminConfig configures the admin listener, it is disabled without an address
+type AdminConfig struct {
+	Address string `json:"address" yaml:"address"`
+	Token   string `json:"token"   yaml:"token"` // Secret, masked in the effective config
+	Chaos   bool   `json:"chaos"   yaml:"chaos"` // Fault injection into the employee storage, toggled on the admin server
+}
+
+// StorageConfig configures where the data is stored
+type StorageConfig struct {
+	Backend            string   `json:"backend"              yaml:"backend"`
+	DataDir            string   `json:"data_dir"             yaml:"data_dir"`
+	SnapshotInterval   Duration `json:"snapshot_interval"    yaml:"snapshot_interval"`
+	CacheSize          int      `json:"cache_size"           yaml:"cache_size"` // Employees cached by ID, 0 disables the cache
+	CacheTTL           Duration `json:"cache_ttl"            yaml:"cache_ttl"`
+	EventSnapshotEvery int      `json:"event_snapshot_every" yaml:"event_snapshot_every"` // 0 disables the eventsourced snapshots
+	HistoryRetention   Duration `json:"history_retention"    yaml:"history_retention"`    // Versions older are pruned on compaction, 0 keeps them all
+}
+
+// MigrationConfig configures the migration of the employees to a secondary
+// file backend, with dual writes
+type MigrationConfig struct {
+	SecondaryDataDir string `json:"secondary_data_dir" yaml:"secondary_data_dir"` // Empty disables the migration
+	ReadFrom         string `json:"read_from"          yaml:"read_from"`          // Side the reads are served from
+	ShadowReads      bool   `json:"shadow_reads"       yaml:"shadow_reads"`       // Also read from the other side
+}
+
+// PaginationConfig configures the page sizes of list endpoints
+type PaginationConfig struct {
+	DefaultPageSize int `json:"default_page_size" yaml:"default_page_size"`
+	MaxPageSize     int `json:"max_page_size"     yaml:"max_page_size"`
+}
+
+// LogConfig configures logging
+type LogConfig struct {
+	Level string `json:"level" yaml:"level"`
+}
+
+// HealthConfig configures the readiness probe
+type HealthConfig struct {
+	CheckTimeout     Duration `json:"check_timeout"       yaml:"check_timeout"`
+	MinFreeDiskBytes int64    `json:"min_free_disk_bytes" yaml:"min_free_disk_bytes"` // Checked with the file backend
+}
+
+// EventsConfig configures the stream of employee changes
+type EventsConfig struct {
+	BufferSize        int      `json:"buffer_size"        yaml:"buffer_size"` // Events retained for streams and the changes feed
+	HeartbeatInterval Duration `json:"heartbeat_interval" yaml:"heartbeat_interval"`
+	RelayInterval     Duration `json:"relay_interval"     yaml:"relay_interval"`  // Retry interval of the failed outbox publishers
+	OutboxCapacity    int      `json:"outbox_capacity"    yaml:"outbox_capacity"` // Writes kept unpublished before the oldest are dead-lettered
+}
+
+// WebhooksConfig configures the deliveries of the webhooks
+type WebhooksConfig struct {
+	MaxAttempts    int      `json:"max_attempts"    yaml:"max_attempts"` // Attempts before dead-lettering
+	InitialBackoff Duration `json:"initial_backoff" yaml:"initial_backoff"`
+	MaxBackoff     Duration `json:"max_backoff"     yaml:"max_backoff"`
+	Timeout        Duration `json:"timeout"         yaml:"timeout"`
+}
+
+// IDConfig configures the generation of entity IDs
+type IDConfig struct {
+	Strategy      string `json:"strategy"       yaml:"strategy"`
+	SnowflakeNode int64  `json:"snowflake_node" yaml:"snowflake_node"`
+	NumericIDs    bool   `json:"numeric_ids"    yaml:"numeric_ids"`
+}
+
+// AuthConfig configures the JWT bearer authentication
+type AuthConfig struct {
+	JWTSecret   string `json:"jwt_secret"    yaml:"jwt_secret"` // Secret, masked in the effective config
+	JWTJWKSFile string `json:"jwt_jwks_file" yaml:"jwt_jwks_file"`
+	JWTIssuer   string `json:"jwt_issuer"    yaml:"jwt_issuer"`
+	JWTAudience string `json:"jwt_audience"  yaml:"jwt_audience"`
+}
+
+// Default returns the built-in default configuration
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Address:         ":8080",
+			ReadTimeout:     Duration(10 * time.Second),
+			WriteTimeout:    Duration(30 * time.Second),
+			IdleTimeout:     Duration(2 * time.Minute),
+			ShutdownTimeout: Duration(15 * time.Second),
+		},
+		Admin: AdminConfig{
+			Address: "127.0.0.1:9090",
+		},
+		Storage: StorageConfig{
+			Backend:            BackendMemory,
+			DataDir:            "data",
+			SnapshotInterval:   Duration(time.Minute),
+			CacheTTL:           Duration(time.Minute),
+			EventSnapshotEvery: 1000,
+			HistoryRetention:   Duration(30 * 24 * time.Hour),
+		},
+		Migration: MigrationConfig{
+			ReadFrom: string(migration.SidePrimary),
+		},
+		Pagination: PaginationConfig{
+			DefaultPageSize: 10,
+			MaxPageSize:     1000,
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+		Health: HealthConfig{
+			CheckTimeout:     Duration(2 * time.Second),
+			MinFreeDiskBytes: 100 << 20, // 100 MiB
+		},
+		Events: EventsConfig{
+			BufferSize:        1000,
+			HeartbeatInterval: Duration(15 * time.Second),
+			RelayInterval:     Duration(time.Second),
+			OutboxCapacity:    100000,
+		},
+		Webhooks: WebhooksConfig{
+			MaxAttempts:    webhook.DefaultConfig.MaxAttempts,
+			InitialBackoff: Duration(webhook.DefaultConfig.InitialBackoff),
+			MaxBackoff:     Duration(webhook.DefaultConfig.MaxBackoff),
+			Timeout:        Duration(webhook.DefaultConfig.Timeout),
+		},
+		IDs: IDConfig{
+			Strategy:   idgen.StrategySequential,
+			NumericIDs: true,
+		},
+		RateLimits: ratelimit.Limits{
+			Default: ratelimit.DefaultLimits.Default,
+			Roles:   maps.Clone(ratelimit.DefaultLimits.Roles), // Files decode into the map
+		},
+		Tracing: tracing.DefaultConfig,
+	}
+}
+
+// Validate checks the configuration and reports every problem found
+func (cfg Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	// Server
+	if _, _, err := net.SplitHostPort(cfg.Server.Address); err != nil {
+		fail("server.address: %q is not a valid host:port address", cfg.Server.Address)
+	}
+	if cfg.Server.ReadTimeout < 0 {
+		fail("server.read_timeout: must not be negative")
+	}
+	if cfg.Server.WriteTimeout < 0 {
+		fail("server.write_timeout: must not be negative")
+	}
+	if cfg.Server.IdleTimeout < 0 {
+		fail("server.idle_timeout: must not be negative")
+	}
+	if cfg.Server.ShutdownTimeout <= 0 {
+		fail("server.shutdown_timeout: must be greater than 0")
+	}
+	if cfg.Server.PreStopDelay < 0 {
+		fail("server.pre_stop_delay: must not be negative")
+	}
+	if _, err := cfg.Server.TrustedProxyNets(); err != nil {
+		fail("server.trusted_proxies: %v", err)
+	}
+
+	// Admin, the token can only be omitted on a loopback address
+	if cfg.Admin.Address != "" {
+		host, _, err := net.SplitHostPort(cfg.Admin.Address)
+		if err != nil {
+			fail("admin.address: %q is not a valid host:port address", cfg.Admin.Address)
+		} else if cfg.Admin.Token == "" && !isLoopback(host) {
+			fail("admin.token: is required when admin.address is not a loopback address")
+		}
+	}
+	if cfg.Admin.Chaos && cfg.Admin.Address == "" {
+		fail("admin.chaos: requires admin.address, the faults are toggled on the admin server")
+	}
+
+	// Storage
+	switch cfg.Storage.Backend {
+	case BackendMemory:
+	case BackendFile:
+		if cfg.Storage.DataDir == "" {
+			fail("storage.data_dir: is required by the %q backend", BackendFile)
+		}
+		if cfg.Storage.SnapshotInterval <= 0 {
+			fail("storage.snapshot_interval: must be greater than 0")
+		}
+	case BackendEventSourced:
+		if cfg.Storage.DataDir == "" {
+			fail("storage.data_dir: is required by the %q backend", BackendEventSourced)
+		}
+		if cfg.Storage.EventSnapshotEvery < 0 {
+			fail("storage.event_snapshot_every: must not be negative")
+		}
+		// The API keys and the webhooks are snapshotted periodically with this backend too
+		if cfg.Storage.SnapshotInterval <= 0 {
+			fail("storage.snapshot_interval: must be greater than 0")
+		}
+	default:
+		fail(
+			"storage.backend: %q is not one of %q, %q, %q",
+			cfg.Storage.Backend,
+			BackendMemory,
+			BackendFile,
+			BackendEventSourced,
+		)
+	}
+	if cfg.Storage.HistoryRetention < 0 {
+		fail("storage.history_retention: must not be negative")
+	}
+	if cfg.Storage.CacheSize < 0 {
+		fail("storage.cache_size: must not be negative")
+	}
+	if cfg.Storage.CacheSize > 0 && cfg.Storage.CacheTTL <= 0 {
+		fail("storage.cache_ttl: must be greater than 0")
+	}
+
+	// Migration
+	if cfg.Migration.SecondaryDataDir != "" {
+		if _, err := migration.ParseSide(cfg.Migration.ReadFrom); err != nil {
+			fail("migration.read_from: %v", err)
+		}
+		if cfg.Storage.Backend != BackendMemory &&
+			filepath.Clean(cfg.Migration.SecondaryDataDir) == filepath.Clean(cfg.Storage.DataDir) {
+			fail("migration.secondary_data_dir: must differ from storage.data_dir")
+		}
+		if cfg.Storage.SnapshotInterval <= 0 {
+			fail("storage.snapshot_interval: must be greater than 0")
+		}
+	}
+
+	// Pagination
+	if cfg.Pagination.MaxPageSize <= 0 {
+		fail("pagination.max_page_size: must be greater than 0")
+	}
+	if cfg.Pagination.DefaultPageSize <= 0 {
+		fail("pagination.default_page_size: must be greater than 0")
+	} else if cfg.Pagination.DefaultPageSize > cfg.Pagination.MaxPageSize {
+		fail("pagination.default_page_size: must not be greater than pagination.max_page_size")
+	}
+
+	// Logging
+	if _, err := logging.ParseLevel(cfg.Log.Level); err != nil {
+		fail("log.level: %v", err)
+	}
+
+	// Health
+	if cfg.Health.CheckTimeout <= 0 {
+		fail("health.check_timeout: must be greater than 0")
+	}
+	if cfg.Health.MinFreeDiskBytes < 0 {
+		fail("health.min_free_disk_bytes: must not be negative")
+	}
+
+	// Events
+	if cfg.Events.BufferSize <= 0 {
+		fail("events.buffer_size: must be greater than 0")
+	}
+	if cfg.Events.HeartbeatInterval <= 0 {
+		fail("events.heartbeat_interval: must be greater than 0")
+	}
+	if cfg.Events.RelayInterval <= 0 {
+		fail("events.relay_interval: must be greater than 0")
+	}
+	if cfg.Events.OutboxCapacity <= 0 {
+		fail("events.outbox_capacity: must be greater than 0")
+	}
+
+	// Webhooks
+	if cfg.Webhooks.MaxAttempts <= 0 {
+		fail("webhooks.max_attempts: must be greater than 0")
+	}
+	if cfg.Webhooks.InitialBackoff <= 0 {
+		fail("webhooks.initial_backoff: must be greater than 0")
+	} else if cfg.Webhooks.InitialBackoff > cfg.Webhooks.MaxBackoff {
+		fail("webhooks.initial_backoff: must not be greater than webhooks.max_backoff")
+	}
+	if cfg.Webhooks.Timeout <= 0 {
+		fail("webhooks.timeout: must be greater than 0")
+	}
+
+	// IDs
+	if _, err := idgen.New(cfg.IDs.Strategy, cfg.IDs.SnowflakeNode); err != nil {
+		fail("ids: %v", err)
+	}
+
+	// Auth
+	if cfg.Auth.JWTSecret == "" && cfg.Auth.JWTJWKSFile == "" {
+		fail("auth: either auth.jwt_secret or auth.jwt_jwks_file is required")
+	}
+
+	// Rate limits
+	if err := cfg.RateLimits.Validate(); err != nil {
+		fail("rate_limits: %v", err)
+	}
+
+	// Tracing
+	if err := cfg.Tracing.Validate(); err != nil {
+		fail("tracing.%v", err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Masked returns a copy of the configuration with the secrets masked,
+// suitable to be logged or exposed
+func (cfg Config) Masked() Config {
+	if cfg.Auth.JWTSecret != "" {
+		cfg.Auth.JWTSecret = mask
+	}
+	if cfg.Admin.Token != "" {
+		cfg.Admin.Token = mask
+	}
+	return cfg
+}
+
+// isLoopback reports whether the host only accepts local connections,
+// an empty host listens on every interface
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
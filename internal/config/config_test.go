@@ -0,0 +1,281 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/auth"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+// env returns a getenv function backed by the map
+func env(values map[string]string) func(string) string {
+	return func(key string) string { return values[key] }
+}
+
+// writeFile writes the content to a file in a temporary directory
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := Load("app", nil, env(map[string]string{"JWT_SECRET": "secret"}))
+	assert.Nil(t, err, "error should be nil")
+
+	expected := Default()
+	expected.Auth.JWTSecret = "secret"
+	assert.Equal(t, expected, cfg, "config should be the defaults")
+	assert.Equal(t, ":8080", cfg.Server.Address, "address should be :8080")
+	assert.Equal(t, 10, cfg.Pagination.DefaultPageSize, "default page size should be 10")
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+server:
+  address: ":9000"
+  read_timeout: 3s
+pagination:
+  max_page_size: 50
+log:
+  level: debug
+auth:
+  jwt_secret: from-file
+rate_limits:
+  roles:
+    hr:
+      read: {per_minute: 600}
+      write: {per_minute: 120, burst: 20}
+`)
+
+	cfg, err := Load(
+		"app",
+		[]string{"-config", path, "-log-level", "warn", "-numeric-ids=false"},
+		env(map[string]string{
+			"APP_SERVER_ADDRESS":           ":9001",
+			"APP_PAGINATION_MAX_PAGE_SIZE": "20",
+			"APP_LOG_LEVEL":                "error",
+		}),
+	)
+	assert.Nil(t, err, "error should be nil")
+
+	// Flags override the environment, which overrides the file, which overrides the defaults
+	assert.Equal(t, "warn", cfg.Log.Level, "flag should win")
+	assert.False(t, cfg.IDs.NumericIDs, "flag should win")
+	assert.Equal(t, ":9001", cfg.Server.Address, "environment should win over the file")
+	assert.Equal(t, 20, cfg.Pagination.MaxPageSize, "environment should win over the file")
+	assert.Equal(
+		t,
+		Duration(3*time.Second),
+		cfg.Server.ReadTimeout,
+		"file should win over the defaults",
+	)
+	assert.Equal(t, "from-file", cfg.Auth.JWTSecret, "file should win over the defaults")
+	assert.Equal(t, Duration(30*time.Second), cfg.Server.WriteTimeout, "default should be kept")
+
+	// Rate limits from the file are merged with the default roles
+	assert.Equal(
+		t,
+		600,
+		cfg.RateLimits.Roles[auth.RoleHR].Read.PerMinute,
+		"hr limits should be loaded",
+	)
+	assert.Contains(t, cfg.RateLimits.Roles, auth.RoleAdmin, "admin limits should be kept")
+	assert.NotContains(
+		t,
+		ratelimit.DefaultLimits.Roles,
+		auth.RoleHR,
+		"defaults should not be modified",
+	)
+}
+
+func TestLoad_TrustedProxies(t *testing.T) {
+	cfg, err := Load(
+		"app",
+		[]string{"-trusted-proxies", "10.0.0.0/8, 192.168.1.7"},
+		env(map[string]string{"JWT_SECRET": "s"}),
+	)
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, []string{"10.0.0.0/8", "192.168.1.7"}, cfg.Server.TrustedProxies)
+
+	nets, err := cfg.Server.TrustedProxyNets()
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(
+		t,
+		[]string{"10.0.0.0/8", "192.168.1.7/32"},
+		[]string{nets[0].String(), nets[1].String()},
+	)
+}
+
+func TestLoad_JSONFile(t *testing.T) {
+	path := writeFile(t, "config.json", `{
+		"storage": {"backend": "file", "data_dir": "/tmp/data", "snapshot_interval": "10s"},
+		"ids": {"strategy": "snowflake", "snowflake_node": 7}
+	}`)
+
+	cfg, err := Load("app", nil, env(map[string]string{"APP_CONFIG": path, "JWT_SECRET": "s"}))
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, BackendFile, cfg.Storage.Backend, "backend should be file")
+	assert.Equal(t, Duration(10*time.Second), cfg.Storage.SnapshotInterval)
+	assert.Equal(t, int64(7), cfg.IDs.SnowflakeNode, "node should be 7")
+}
+
+func TestLoad_Errors(t *testing.T) {
+	secret := map[string]string{"JWT_SECRET": "secret"}
+
+	tests := map[string]struct {
+		args     []string
+		env      map[string]string
+		file     [2]string // Name and content
+		contains string
+	}{
+		"unknown file key": {
+			file:     [2]string{"config.yaml", "server:\n  adress: \":80\"\n"},
+			contains: "field adress not found",
+		},
+		"unknown json key": {
+			file:     [2]string{"config.json", `{"log": {"lvl": "debug"}}`},
+			contains: `unknown field "lvl"`,
+		},
+		"unsupported format": {
+			file:     [2]string{"config.toml", ""},
+			contains: "unsupported format",
+		},
+		"invalid duration": {
+			env:      map[string]string{"JWT_SECRET": "secret", "APP_SERVER_READ_TIMEOUT": "soon"},
+			contains: `environment variable APP_SERVER_READ_TIMEOUT: invalid duration "soon"`,
+		},
+		"invalid integer flag": {
+			args:     []string{"-max-page-size", "many"},
+			env:      secret,
+			contains: `flag -max-page-size: invalid integer "many"`,
+		},
+		"invalid backend": {
+			args:     []string{"-storage-backend", "postgres"},
+			env:      secret,
+			contains: `storage.backend: "postgres" is not one of`,
+		},
+		"negative event snapshots": {
+			args:     []string{"-storage-backend", "eventsourced", "-event-snapshot-every", "-1"},
+			env:      secret,
+			contains: "storage.event_snapshot_every: must not be negative",
+		},
+		"eventsourced without snapshot interval": {
+			args:     []string{"-storage-backend", "eventsourced", "-snapshot-interval", "0s"},
+			env:      secret,
+			contains: "storage.snapshot_interval: must be greater than 0",
+		},
+		"empty outbox": {
+			args:     []string{"-events-outbox-capacity", "0"},
+			env:      secret,
+			contains: "events.outbox_capacity: must be greater than 0",
+		},
+		"negative history retention": {
+			args:     []string{"-history-retention", "-1h"},
+			env:      secret,
+			contains: "storage.history_retention: must not be negative",
+		},
+		"missing secret": {
+			contains: "auth: either auth.jwt_secret or auth.jwt_jwks_file is required",
+		},
+		"default page size above max": {
+			args:     []string{"-default-page-size", "100", "-max-page-size", "50"},
+			env:      secret,
+			contains: "pagination.default_page_size: must not be greater than",
+		},
+		"invalid address": {
+			args:     []string{"-listen-addr", "8080"},
+			env:      secret,
+			contains: `server.address: "8080" is not a valid host:port address`,
+		},
+		"negative pre-stop delay": {
+			args:     []string{"-pre-stop-delay", "-1s"},
+			env:      secret,
+			contains: "server.pre_stop_delay: must not be negative",
+		},
+		"invalid trusted proxy": {
+			args:     []string{"-trusted-proxies", "10.0.0.0/8, proxy.local"},
+			env:      secret,
+			contains: `server.trusted_proxies: "proxy.local" is not an IP or a CIDR`,
+		},
+		"public admin address without token": {
+			args:     []string{"-admin-addr", ":9090"},
+			env:      secret,
+			contains: "admin.token: is required when admin.address is not a loopback address",
+		},
+		"chaos without admin server": {
+			args:     []string{"-admin-addr", "", "-admin-chaos"},
+			env:      secret,
+			contains: "admin.chaos: requires admin.address",
+		},
+		"invalid log level": {
+			args:     []string{"-log-level", "verbose"},
+			env:      secret,
+			contains: `log.level: unknown log level "verbose"`,
+		},
+		"invalid tracing exporter": {
+			args:     []string{"-tracing-exporter", "zipkin"},
+			env:      secret,
+			contains: `tracing.exporter: "zipkin" is not one of`,
+		},
+		"invalid ID strategy": {
+			args:     []string{"-id-strategy", "random"},
+			env:      secret,
+			contains: `ids: unknown ID generation strategy "random"`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := test.args
+			if test.file[0] != "" {
+				args = append(args, "-config", writeFile(t, test.file[0], test.file[1]))
+			}
+			_, err := Load("app", args, env(test.env))
+			if assert.NotNil(t, err, "error should not be nil") {
+				assert.Contains(t, err.Error(), test.contains)
+			}
+		})
+	}
+}
+
+func TestLoad_Help(t *testing.T) {
+	_, err := Load("app", []string{"-h"}, env(nil))
+	assert.True(t, errors.Is(err, flag.ErrHelp), "error should be flag.ErrHelp")
+}
+
+func TestValidate_ReportsEveryError(t *testing.T) {
+	cfg := Default()
+	cfg.Server.ReadTimeout = -1
+	cfg.Pagination.MaxPageSize = 0
+	cfg.Log.Level = "loud"
+
+	err := cfg.Validate()
+	assert.NotNil(t, err, "error should not be nil")
+	for _, field := range []string{"server.read_timeout", "pagination.max_page_size", "log.level", "auth"} {
+		assert.Contains(t, err.Error(), field, "error should report %s", field)
+	}
+}
+
+func TestMasked(t *testing.T) {
+	cfg := Default()
+	cfg.Auth.JWTSecret = "super-secret"
+	cfg.Admin.Token = "admin-secret"
+
+	data, err := json.Marshal(cfg.Masked())
+	assert.Nil(t, err, "error should be nil")
+	assert.NotContains(t, string(data), "super-secret", "secret should be masked")
+	assert.Contains(t, string(data), `"jwt_secret":"******"`, "secret should be masked")
+	assert.NotContains(t, string(data), "admin-secret", "admin token should be masked")
+	assert.Contains(t, string(data), `"read_timeout":"10s"`, "durations should be human readable")
+	assert.Equal(t, "super-secret", cfg.Auth.JWTSecret, "original config should not be modified")
+}
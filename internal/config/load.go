@@ -0,0 +1,503 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// binding maps a configuration value to its CLI flag and environment variables
+type binding struct {
+	flag    string                            // Flag name, without the leading dash
+	envs    []string                          // Environment variables, the first one set wins
+	usage   string                            // Flag usage
+	boolean bool                              // Flag can be given without a value
+	set     func(cfg *Config, s string) error // Parses and sets the value
+	get     func(cfg *Config) string          // Formats the value for the flag usage
+}
+
+// bindings lists every value that can be set from the environment or the command line
+var bindings = []binding{
+	stringBinding(
+		"listen-addr",
+		"APP_SERVER_ADDRESS",
+		"address the HTTP server listens on",
+		func(cfg *Config) *string { return &cfg.Server.Address },
+	),
+	durationBinding(
+		"read-timeout",
+		"APP_SERVER_READ_TIMEOUT",
+		"maximum duration to read a request",
+		func(cfg *Config) *Duration { return &cfg.Server.ReadTimeout },
+	),
+	durationBinding(
+		"write-timeout",
+		"APP_SERVER_WRITE_TIMEOUT",
+		"maximum duration to write a response",
+		func(cfg *Config) *Duration { return &cfg.Server.WriteTimeout },
+	),
+	durationBinding(
+		"idle-timeout",
+		"APP_SERVER_IDLE_TIMEOUT",
+		"maximum duration of idle keep-alive connections",
+		func(cfg *Config) *Duration { return &cfg.Server.IdleTimeout },
+	),
+	durationBinding(
+		"shutdown-timeout",
+		"APP_SERVER_SHUTDOWN_TIMEOUT",
+		"deadline to drain in-flight requests on shutdown, and then to flush the storage",
+		func(cfg *Config) *Duration { return &cfg.Server.ShutdownTimeout },
+	),
+	durationBinding(
+		"pre-stop-delay",
+		"APP_SERVER_PRE_STOP_DELAY",
+		"duration requests are still served on shutdown once readiness fails, before draining",
+		func(cfg *Config) *Duration { return &cfg.Server.PreStopDelay },
+	),
+	stringsBinding(
+		"trusted-proxies",
+		"APP_SERVER_TRUSTED_PROXIES",
+		"comma-separated IPs or CIDRs of the proxies trusted to set X-Forwarded-For, empty to use the peer address",
+		func(cfg *Config) *[]string { return &cfg.Server.TrustedProxies },
+	),
+	stringBinding(
+		"admin-addr",
+		"APP_ADMIN_ADDRESS",
+		"address the admin server listens on, empty to disable it",
+		func(cfg *Config) *string { return &cfg.Admin.Address },
+	),
+	stringBinding(
+		"admin-token",
+		"APP_ADMIN_TOKEN",
+		"bearer token of the admin server (required on non-loopback addresses)",
+		func(cfg *Config) *string { return &cfg.Admin.Token },
+	),
+	boolBinding(
+		"admin-chaos",
+		"APP_ADMIN_CHAOS",
+		"inject faults into the employee storage, toggled on the admin server (testing only)",
+		func(cfg *Config) *bool { return &cfg.Admin.Chaos },
+	),
+	stringBinding(
+		"storage-backend",
+		"APP_STORAGE_BACKEND",
+		"storage backend: memory, file or eventsourced",
+		func(cfg *Config) *string { return &cfg.Storage.Backend },
+	),
+	stringBinding(
+		"data-dir",
+		"APP_STORAGE_DATA_DIR",
+		"directory of the snapshots of the file backend, and of the event log of the eventsourced backend",
+		func(cfg *Config) *string { return &cfg.Storage.DataDir },
+	),
+	durationBinding(
+		"snapshot-interval",
+		"APP_STORAGE_SNAPSHOT_INTERVAL",
+		"interval between snapshots of the file backend",
+		func(cfg *Config) *Duration { return &cfg.Storage.SnapshotInterval },
+	),
+	intBinding(
+		"event-snapshot-every",
+		"APP_STORAGE_EVENT_SNAPSHOT_EVERY",
+		"number of events between snapshots of the eventsourced backend, 0 to disable them",
+		func(cfg *Config) *int { return &cfg.Storage.EventSnapshotEvery },
+	),
+	durationBinding(
+		"history-retention",
+		"APP_STORAGE_HISTORY_RETENTION",
+		"age of the employee versions pruned on compaction, 0 to keep every version",
+		func(cfg *Config) *Duration { return &cfg.Storage.HistoryRetention },
+	),
+	stringBinding(
+		"migration-data-dir",
+		"APP_MIGRATION_SECONDARY_DATA_DIR",
+		"directory of the file backend the employees are migrated to, empty to disable the migration",
+		func(cfg *Config) *string { return &cfg.Migration.SecondaryDataDir },
+	),
+	stringBinding(
+		"migration-read-from",
+		"APP_MIGRATION_READ_FROM",
+		"side the reads are served from during the migration: primary or secondary",
+		func(cfg *Config) *string { return &cfg.Migration.ReadFrom },
+	),
+	boolBinding(
+		"migration-shadow-reads",
+		"APP_MIGRATION_SHADOW_READS",
+		"also read from the other side during the migration and log the mismatches",
+		func(cfg *Config) *bool { return &cfg.Migration.ShadowReads },
+	),
+	intBinding(
+		"cache-size",
+		"APP_STORAGE_CACHE_SIZE",
+		"number of employees cached by ID in front of the storage, 0 to disable the cache",
+		func(cfg *Config) *int { return &cfg.Storage.CacheSize },
+	),
+	durationBinding(
+		"cache-ttl",
+		"APP_STORAGE_CACHE_TTL",
+		"duration employees stay cached",
+		func(cfg *Config) *Duration { return &cfg.Storage.CacheTTL },
+	),
+	intBinding(
+		"default-page-size",
+		"APP_PAGINATION_DEFAULT_PAGE_SIZE",
+		"page size of list requests without a limit",
+		func(cfg *Config) *int { return &cfg.Pagination.DefaultPageSize },
+	),
+	intBinding(
+		"max-page-size",
+		"APP_PAGINATION_MAX_PAGE_SIZE",
+		"maximum page size of list requests",
+		func(cfg *Config) *int { return &cfg.Pagination.MaxPageSize },
+	),
+	stringBinding(
+		"log-level",
+		"APP_LOG_LEVEL",
+		"log level: debug, info, warn, error or off",
+		func(cfg *Config) *string { return &cfg.Log.Level },
+	),
+	durationBinding(
+		"health-check-timeout",
+		"APP_HEALTH_CHECK_TIMEOUT",
+		"deadline of each readiness check",
+		func(cfg *Config) *Duration { return &cfg.Health.CheckTimeout },
+	),
+	int64Binding(
+		"min-free-disk-bytes",
+		"APP_HEALTH_MIN_FREE_DISK_BYTES",
+		"free disk space required in the data dir of the file backend to be ready",
+		func(cfg *Config) *int64 { return &cfg.Health.MinFreeDiskBytes },
+	),
+	intBinding(
+		"events-buffer-size",
+		"APP_EVENTS_BUFFER_SIZE",
+		"number of employee events retained to resume event streams and serve the changes feed",
+		func(cfg *Config) *int { return &cfg.Events.BufferSize },
+	),
+	durationBinding(
+		"events-heartbeat-interval",
+		"APP_EVENTS_HEARTBEAT_INTERVAL",
+		"interval between heartbeats of idle event streams",
+		func(cfg *Config) *Duration { return &cfg.Events.HeartbeatInterval },
+	),
+	durationBinding(
+		"events-relay-interval",
+		"APP_EVENTS_RELAY_INTERVAL",
+		"interval between retries of the outbox publishers that failed",
+		func(cfg *Config) *Duration { return &cfg.Events.RelayInterval },
+	),
+	intBinding(
+		"events-outbox-capacity",
+		"APP_EVENTS_OUTBOX_CAPACITY",
+		"number of employee writes kept unpublished in the outbox before the oldest are dead-lettered",
+		func(cfg *Config) *int { return &cfg.Events.OutboxCapacity },
+	),
+	intBinding(
+		"webhook-max-attempts",
+		"APP_WEBHOOKS_MAX_ATTEMPTS",
+		"attempts of a webhook delivery before it is dead-lettered",
+		func(cfg *Config) *int { return &cfg.Webhooks.MaxAttempts },
+	),
+	durationBinding(
+		"webhook-initial-backoff",
+		"APP_WEBHOOKS_INITIAL_BACKOFF",
+		"delay before the first retry of a webhook delivery, doubled on every retry",
+		func(cfg *Config) *Duration { return &cfg.Webhooks.InitialBackoff },
+	),
+	durationBinding(
+		"webhook-max-backoff",
+		"APP_WEBHOOKS_MAX_BACKOFF",
+		"maximum delay between retries of a webhook delivery",
+		func(cfg *Config) *Duration { return &cfg.Webhooks.MaxBackoff },
+	),
+	durationBinding(
+		"webhook-timeout",
+		"APP_WEBHOOKS_TIMEOUT",
+		"deadline of each attempt of a webhook delivery",
+		func(cfg *Config) *Duration { return &cfg.Webhooks.Timeout },
+	),
+	stringBinding(
+		"id-strategy",
+		"APP_IDS_STRATEGY",
+		"ID generation strategy: sequential, uuidv7, ulid or snowflake",
+		func(cfg *Config) *string { return &cfg.IDs.Strategy },
+	),
+	int64Binding(
+		"snowflake-node",
+		"APP_IDS_SNOWFLAKE_NODE",
+		"unique node number (0-1023) for snowflake IDs",
+		func(cfg *Config) *int64 { return &cfg.IDs.SnowflakeNode },
+	),
+	boolBinding(
+		"numeric-ids",
+		"APP_IDS_NUMERIC_IDS",
+		"encode sequential IDs as JSON numbers for numeric clients (ignored by other strategies)",
+		func(cfg *Config) *bool { return &cfg.IDs.NumericIDs },
+	),
+	stringBinding(
+		"jwt-secret",
+		"APP_AUTH_JWT_SECRET",
+		"shared secret for HS256 tokens (also read from $JWT_SECRET)",
+		func(cfg *Config) *string { return &cfg.Auth.JWTSecret },
+		"JWT_SECRET",
+	),
+	stringBinding(
+		"jwt-jwks-file",
+		"APP_AUTH_JWT_JWKS_FILE",
+		"local JWKS file with RS256/ES256 public keys",
+		func(cfg *Config) *string { return &cfg.Auth.JWTJWKSFile },
+	),
+	stringBinding(
+		"jwt-issuer",
+		"APP_AUTH_JWT_ISSUER",
+		"required issuer (iss) of bearer tokens",
+		func(cfg *Config) *string { return &cfg.Auth.JWTIssuer },
+	),
+	stringBinding(
+		"jwt-audience",
+		"APP_AUTH_JWT_AUDIENCE",
+		"required audience (aud) of bearer tokens",
+		func(cfg *Config) *string { return &cfg.Auth.JWTAudience },
+	),
+	stringBinding(
+		"tracing-exporter",
+		"APP_TRACING_EXPORTER",
+		"span exporter: none, stdout or otlp",
+		func(cfg *Config) *string { return &cfg.Tracing.Exporter },
+	),
+	stringBinding(
+		"otlp-endpoint",
+		"APP_TRACING_OTLP_ENDPOINT",
+		"URL of the OTLP/HTTP collector (defaults to $OTEL_EXPORTER_OTLP_ENDPOINT)",
+		func(cfg *Config) *string { return &cfg.Tracing.OTLPEndpoint },
+	),
+	stringBinding(
+		"service-name",
+		"APP_TRACING_SERVICE_NAME",
+		"service name of the spans (also read from $OTEL_SERVICE_NAME)",
+		func(cfg *Config) *string { return &cfg.Tracing.ServiceName },
+		"OTEL_SERVICE_NAME",
+	),
+}
+
+// Load builds the configuration from the defaults, the configuration file, the
+// environment and the command line arguments, in increasing order of precedence,
+// and validates it
+//
+// The configuration file is given by the -config flag or the APP_CONFIG
+// environment variable, its format is chosen by its extension (.yaml, .yml or .json).
+// flag.ErrHelp is returned when the usage was requested with -h.
+func Load(name string, args []string, getenv func(string) string) (Config, error) {
+	cfg := Default()
+
+	// Parse the flags first to find the configuration file, they are applied last
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	configFile := fs.String(
+		"config",
+		getenv("APP_CONFIG"),
+		"YAML or JSON configuration file (defaults to $APP_CONFIG)",
+	)
+	flags := make(map[string]*flagValue, len(bindings))
+	for _, b := range bindings {
+		flags[b.flag] = &flagValue{boolean: b.boolean}
+		fs.Var(flags[b.flag], b.flag, fmt.Sprintf("%s (default %q)", b.usage, b.get(&cfg)))
+	}
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+	if fs.NArg() > 0 {
+		return cfg, fmt.Errorf("unexpected arguments %q", fs.Args())
+	}
+
+	// Apply the configuration file
+	if *configFile != "" {
+		if err := loadFile(*configFile, &cfg); err != nil {
+			return cfg, fmt.Errorf("config file %s: %w", *configFile, err)
+		}
+	}
+
+	// Apply the environment variables
+	for _, b := range bindings {
+		for _, env := range b.envs {
+			value := getenv(env)
+			if value == "" {
+				continue
+			}
+			if err := b.set(&cfg, value); err != nil {
+				return cfg, fmt.Errorf("environment variable %s: %w", env, err)
+			}
+			break
+		}
+	}
+
+	// Apply the flags
+	for _, b := range bindings {
+		if value := flags[b.flag]; value.set {
+			if err := b.set(&cfg, value.value); err != nil {
+				return cfg, fmt.Errorf("flag -%s: %w", b.flag, err)
+			}
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return cfg, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return cfg, nil
+}
+
+// loadFile decodes the configuration file over the configuration,
+// unknown keys are rejected to catch typos
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(cfg); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported format %q, expected .yaml, .yml or .json", ext)
+	}
+	return nil
+}
+
+// flagValue records the raw value of a flag until it is applied
+type flagValue struct {
+	value   string
+	set     bool
+	boolean bool
+}
+
+func (f *flagValue) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.value
+}
+
+func (f *flagValue) Set(s string) error {
+	f.value, f.set = s, true
+	return nil
+}
+
+func (f *flagValue) IsBoolFlag() bool {
+	return f.boolean
+}
+
+func stringBinding(
+	name, env, usage string,
+	field func(cfg *Config) *string,
+	aliases ...string,
+) binding {
+	return binding{
+		flag:  name,
+		envs:  append([]string{env}, aliases...),
+		usage: usage,
+		set: func(cfg *Config, s string) error {
+			*field(cfg) = s
+			return nil
+		},
+		get: func(cfg *Config) string { return *field(cfg) },
+	}
+}
+
+func stringsBinding(name, env, usage string, field func(cfg *Config) *[]string) binding {
+	return binding{
+		flag:  name,
+		envs:  []string{env},
+		usage: usage,
+		set: func(cfg *Config, s string) error {
+			var values []string
+			for _, value := range strings.Split(s, ",") {
+				if value = strings.TrimSpace(value); value != "" {
+					values = append(values, value)
+				}
+			}
+			*field(cfg) = values
+			return nil
+		},
+		get: func(cfg *Config) string { return strings.Join(*field(cfg), ",") },
+	}
+}
+
+func durationBinding(name, env, usage string, field func(cfg *Config) *Duration) binding {
+	return binding{
+		flag:  name,
+		envs:  []string{env},
+		usage: usage,
+		set:   func(cfg *Config, s string) error { return field(cfg).Set(s) },
+		get:   func(cfg *Config) string { return field(cfg).String() },
+	}
+}
+
+func intBinding(name, env, usage string, field func(cfg *Config) *int) binding {
+	return binding{
+		flag:  name,
+		envs:  []string{env},
+		usage: usage,
+		set: func(cfg *Config, s string) error {
+			value, err := strconv.Atoi(s)
+			if err != nil {
+				return fmt.Errorf("invalid integer %q", s)
+			}
+			*field(cfg) = value
+			return nil
+		},
+		get: func(cfg *Config) string { return strconv.Itoa(*field(cfg)) },
+	}
+}
+
+func int64Binding(name, env, usage string, field func(cfg *Config) *int64) binding {
+	return binding{
+		flag:  name,
+		envs:  []string{env},
+		usage: usage,
+		set: func(cfg *Config, s string) error {
+			value, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid integer %q", s)
+			}
+			*field(cfg) = value
+			return nil
+		},
+		get: func(cfg *Config) string { return strconv.FormatInt(*field(cfg), 10) },
+	}
+}
+
+func boolBinding(name, env, usage string, field func(cfg *Config) *bool) binding {
+	return binding{
+		flag:    name,
+		envs:    []string{env},
+		usage:   usage,
+		boolean: true,
+		set: func(cfg *Config, s string) error {
+			value, err := strconv.ParseBool(s)
+			if err != nil {
+				return fmt.Errorf("invalid boolean %q", s)
+			}
+			*field(cfg) = value
+			return nil
+		},
+		get: func(cfg *Config) string { return strconv.FormatBool(*field(cfg)) },
+	}
+}
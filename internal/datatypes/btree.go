@@ -0,0 +1,488 @@
+package datatypes
+
+// defaultBTreeDegree is the minimum degree used by NewBTree.
+// Every node except the root holds between degree-1 and 2*degree-1 keys.
+const defaultBTreeDegree = 16
+
+// BTree is a sorted set of keys backed by an order-statistic B-tree.
+//
+// This implementation is non-thread-safe.
+//
+// The BTree is parameterized by the key type K and ordered by the less function
+// given at construction. Two keys a and b are considered equal when neither
+// less(a, b) nor less(b, a) holds.
+// Every node tracks the number of keys in its subtree, which allows rank and
+// select queries in O(log n) in addition to the usual lookups and range scans.
+type BTree[K any] struct {
+	root   *btreeNode[K]
+	degree int
+	less   func(a, b K) bool
+}
+
+type btreeNode[K any] struct {
+	keys     []K
+	children []*btreeNode[K]
+	size     int // Number of keys in the subtree rooted at this node
+}
+
+func (n *btreeNode[K]) leaf() bool {
+	return len(n.children) == 0
+}
+
+func NewBTree[K any](less func(a, b K) bool) *BTree[K] {
+	return NewBTreeWithDegree(defaultBTreeDegree, less)
+}
+
+// NewBTreeWithDegree creates a B-tree with the given minimum degree (at least 2)
+func NewBTreeWithDegree[K any](degree int, less func(a, b K) bool) *BTree[K] {
+	if degree < 2 {
+		degree = 2
+	}
+	return &BTree[K]{degree: degree, less: less}
+}
+
+func (t *BTree[K]) Len() int {
+	if t.root == nil {
+		return 0
+	}
+	return t.root.size
+}
+
+// Set adds the key to the tree, replacing an equal key if one exists.
+// It returns true if the key was not present before.
+func (t *BTree[K]) Set(key K) bool {
+	if t.replace(key) {
+		return false
+	}
+
+	if t.root == nil {
+		t.root = &btreeNode[K]{}
+	}
+	if len(t.root.keys) == t.maxKeys() {
+		old := t.root
+		t.root = &btreeNode[K]{children: []*btreeNode[K]{old}, size: old.size}
+		t.splitChild(t.root, 0)
+	}
+	t.insertNonFull(t.root, key)
+
+	return true
+}
+
+func (t *BTree[K]) Get(key K) (K, bool) {
+	for n := t.root; n != nil; {
+		i, found := t.find(n, key)
+		if found {
+			return n.keys[i], true
+		}
+		if n.leaf() {
+			break
+		}
+		n = n.children[i]
+	}
+
+	var zero K
+	return zero, false
+}
+
+func (t *BTree[K]) Has(key K) bool {
+	_, ok := t.Get(key)
+	return ok
+}
+
+// Delete removes the key from the tree and reports whether it was present
+func (t *BTree[K]) Delete(key K) bool {
+	if !t.Has(key) {
+		return false
+	}
+
+	t.remove(t.root, key)
+	if len(t.root.keys) == 0 {
+		if t.root.leaf() {
+			t.root = nil
+		} else {
+			t.root = t.root.children[0]
+		}
+	}
+
+	return true
+}
+
+// Min returns the smallest key in the tree
+func (t *BTree[K]) Min() (K, bool) {
+	if t.root == nil {
+		var zero K
+		return zero, false
+	}
+	return t.minKey(t.root), true
+}
+
+// Max returns the largest key in the tree
+func (t *BTree[K]) Max() (K, bool) {
+	if t.root == nil {
+		var zero K
+		return zero, false
+	}
+	return t.maxKey(t.root), true
+}
+
+// Floor returns the largest key less than or equal to the given key
+func (t *BTree[K]) Floor(key K) (K, bool) {
+	var (
+		best K
+		ok   bool
+	)
+	for n := t.root; n != nil; {
+		i, found := t.find(n, key)
+		if found {
+			return n.keys[i], true
+		}
+		if i > 0 {
+			best, ok = n.keys[i-1], true
+		}
+		if n.leaf() {
+			break
+		}
+		n = n.children[i]
+	}
+	return best, ok
+}
+
+// Ceiling returns the smallest key greater than or equal to the given key
+func (t *BTree[K]) Ceiling(key K) (K, bool) {
+	var (
+		best K
+		ok   bool
+	)
+	for n := t.root; n != nil; {
+		i, found := t.find(n, key)
+		if found {
+			return n.keys[i], true
+		}
+		if i < len(n.keys) {
+			best, ok = n.keys[i], true
+		}
+		if n.leaf() {
+			break
+		}
+		n = n.children[i]
+	}
+	return best, ok
+}
+
+// Rank returns the number of keys strictly less than the given key
+func (t *BTree[K]) Rank(key K) int {
+	rank := 0
+	for n := t.root; n != nil; {
+		i, found := t.find(n, key)
+		rank += i
+		if !n.leaf() {
+			for _, child := range n.children[:i] {
+				rank += child.size
+			}
+		}
+		if found {
+			if !n.leaf() {
+				rank += n.children[i].size
+			}
+			break
+		}
+		if n.leaf() {
+			break
+		}
+		n = n.children[i]
+	}
+	return rank
+}
+
+// Select returns the key at the given zero-based position in sorted order
+func (t *BTree[K]) Select(index int) (K, bool) {
+	if index < 0 || index >= t.Len() {
+		var zero K
+		return zero, false
+	}
+
+	n := t.root
+	for !n.leaf() {
+		next := n.children[len(n.keys)]
+		for i, child := range n.children[:len(n.keys)] {
+			if index < child.size {
+				next = child
+				break
+			}
+			index -= child.size
+			if index == 0 {
+				return n.keys[i], true
+			}
+			index--
+		}
+		n = next
+	}
+
+	return n.keys[index], true
+}
+
+// Ascend calls fn for every key in ascending order until fn returns false
+func (t *BTree[K]) Ascend(fn func(key K) bool) {
+	if t.root != nil {
+		t.ascend(t.root, nil, nil, fn)
+	}
+}
+
+// AscendGreaterOrEqual calls fn for every key >= pivot in ascending order
+// until fn returns false
+func (t *BTree[K]) AscendGreaterOrEqual(pivot K, fn func(key K) bool) {
+	if t.root != nil {
+		t.ascend(t.root, &pivot, nil, fn)
+	}
+}
+
+// AscendRange calls fn for every key in the range [from, to) in ascending order
+// until fn returns false
+func (t *BTree[K]) AscendRange(from, to K, fn func(key K) bool) {
+	if t.root != nil {
+		t.ascend(t.root, &from, &to, fn)
+	}
+}
+
+func (t *BTree[K]) maxKeys() int {
+	return 2*t.degree - 1
+}
+
+// find returns the index of the first key in the node that is >= key,
+// and whether that key is equal to the given key
+func (t *BTree[K]) find(n *btreeNode[K], key K) (int, bool) {
+	lo, hi := 0, len(n.keys)
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if t.less(n.keys[mid], key) {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo, lo < len(n.keys) && !t.less(key, n.keys[lo])
+}
+
+// replace overwrites an equal key in place and reports whether one was found
+func (t *BTree[K]) replace(key K) bool {
+	for n := t.root; n != nil; {
+		i, found := t.find(n, key)
+		if found {
+			n.keys[i] = key
+			return true
+		}
+		if n.leaf() {
+			break
+		}
+		n = n.children[i]
+	}
+	return false
+}
+
+func (t *BTree[K]) insertNonFull(n *btreeNode[K], key K) {
+	for {
+		n.size++
+		i, _ := t.find(n, key)
+		if n.leaf() {
+			n.keys = insertAt(n.keys, i, key)
+			return
+		}
+		if len(n.children[i].keys) == t.maxKeys() {
+			t.splitChild(n, i)
+			if t.less(n.keys[i], key) {
+				i++
+			}
+		}
+		n = n.children[i]
+	}
+}
+
+// splitChild splits the full child at index i around its median key,
+// which moves up into the parent
+func (t *BTree[K]) splitChild(parent *btreeNode[K], i int) {
+	child := parent.children[i]
+	mid := t.degree - 1
+	median := child.keys[mid]
+
+	right := &btreeNode[K]{keys: append([]K(nil), child.keys[mid+1:]...)}
+	child.keys = child.keys[:mid:mid]
+	if !child.leaf() {
+		right.children = append([]*btreeNode[K](nil), child.children[mid+1:]...)
+		child.children = child.children[: mid+1 : mid+1]
+	}
+	child.size = subtreeSize(child)
+	right.size = subtreeSize(right)
+
+	parent.keys = insertAt(parent.keys, i, median)
+	parent.children = insertAt(parent.children, i+1, right)
+}
+
+// remove deletes a key known to be present in the subtree rooted at n
+func (t *BTree[K]) remove(n *btreeNode[K], key K) {
+	for {
+		n.size--
+		i, found := t.find(n, key)
+
+		if n.leaf() {
+			n.keys = removeAt(n.keys, i)
+			return
+		}
+
+		if found {
+			left, right := n.children[i], n.children[i+1]
+			switch {
+			case len(left.keys) >= t.degree:
+				// Replace the key with its predecessor and remove that instead
+				pred := t.maxKey(left)
+				n.keys[i] = pred
+				n, key = left, pred
+			case len(right.keys) >= t.degree:
+				// Replace the key with its successor and remove that instead
+				succ := t.minKey(right)
+				n.keys[i] = succ
+				n, key = right, succ
+			default:
+				t.merge(n, i)
+				n = left
+			}
+			continue
+		}
+
+		// Make sure the child we descend into can afford to lose a key
+		if len(n.children[i].keys) < t.degree {
+			i = t.fill(n, i)
+		}
+		n = n.children[i]
+	}
+}
+
+// fill grows the child at index i to at least degree keys by borrowing from
+// a sibling or merging with one, and returns the index of the resulting child
+func (t *BTree[K]) fill(n *btreeNode[K], i int) int {
+	switch {
+	case i > 0 && len(n.children[i-1].keys) >= t.degree:
+		t.borrowFromLeft(n, i)
+		return i
+	case i < len(n.keys) && len(n.children[i+1].keys) >= t.degree:
+		t.borrowFromRight(n, i)
+		return i
+	case i < len(n.keys):
+		t.merge(n, i)
+		return i
+	default:
+		t.merge(n, i-1)
+		return i - 1
+	}
+}
+
+func (t *BTree[K]) borrowFromLeft(n *btreeNode[K], i int) {
+	child, sibling := n.children[i], n.children[i-1]
+
+	child.keys = insertAt(child.keys, 0, n.keys[i-1])
+	n.keys[i-1] = sibling.keys[len(sibling.keys)-1]
+	sibling.keys = sibling.keys[:len(sibling.keys)-1]
+
+	moved := 1
+	if !sibling.leaf() {
+		last := sibling.children[len(sibling.children)-1]
+		sibling.children = sibling.children[:len(sibling.children)-1]
+		child.children = insertAt(child.children, 0, last)
+		moved += last.size
+	}
+	child.size += moved
+	sibling.size -= moved
+}
+
+func (t *BTree[K]) borrowFromRight(n *btreeNode[K], i int) {
+	child, sibling := n.children[i], n.children[i+1]
+
+	child.keys = append(child.keys, n.keys[i])
+	n.keys[i] = sibling.keys[0]
+	sibling.keys = removeAt(sibling.keys, 0)
+
+	moved := 1
+	if !sibling.leaf() {
+		first := sibling.children[0]
+		sibling.children = removeAt(sibling.children, 0)
+		child.children = append(child.children, first)
+		moved += first.size
+	}
+	child.size += moved
+	sibling.size -= moved
+}
+
+// merge folds the key at index i and the child to its right into the child on its left
+func (t *BTree[K]) merge(n *btreeNode[K], i int) {
+	left, right := n.children[i], n.children[i+1]
+
+	left.keys = append(left.keys, n.keys[i])
+	left.keys = append(left.keys, right.keys...)
+	left.children = append(left.children, right.children...)
+	left.size += 1 + right.size
+
+	n.keys = removeAt(n.keys, i)
+	n.children = removeAt(n.children, i+1)
+}
+
+func (t *BTree[K]) minKey(n *btreeNode[K]) K {
+	for !n.leaf() {
+		n = n.children[0]
+	}
+	return n.keys[0]
+}
+
+func (t *BTree[K]) maxKey(n *btreeNode[K]) K {
+	for !n.leaf() {
+		n = n.children[len(n.children)-1]
+	}
+	return n.keys[len(n.keys)-1]
+}
+
+// ascend walks the subtree in order, skipping keys < from and stopping at the
+// first key >= to. It returns false once iteration has been stopped.
+func (t *BTree[K]) ascend(n *btreeNode[K], from, to *K, fn func(key K) bool) bool {
+	i := 0
+	if from != nil {
+		i, _ = t.find(n, *from)
+	}
+
+	for ; i < len(n.keys); i++ {
+		if !n.leaf() && !t.ascend(n.children[i], from, to, fn) {
+			return false
+		}
+		if to != nil && !t.less(n.keys[i], *to) {
+			return false
+		}
+		if !fn(n.keys[i]) {
+			return false
+		}
+	}
+
+	if !n.leaf() {
+		return t.ascend(n.children[len(n.keys)], from, to, fn)
+	}
+	return true
+}
+
+func subtreeSize[K any](n *btreeNode[K]) int {
+	size := len(n.keys)
+	for _, child := range n.children {
+		size += child.size
+	}
+	return size
+}
+
+func insertAt[E any](s []E, i int, v E) []E {
+	var zero E
+	s = append(s, zero)
+	copy(s[i+1:], s[i:])
+	s[i] = v
+	return s
+}
+
+func removeAt[E any](s []E, i int) []E {
+	copy(s[i:], s[i+1:])
+	var zero E
+	s[len(s)-1] = zero
+	return s[:len(s)-1]
+}
@@ -0,0 +1,126 @@
+package datatypes
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func intLess(a, b int) bool { return a < b }
+
+func collect(bt *BTree[int]) []int {
+	keys := make([]int, 0, bt.Len())
+	bt.Ascend(func(key int) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+func TestBTree(t *testing.T) {
+	bt := NewBTreeWithDegree(2, intLess)
+
+	// Test initial length
+	assert.Equal(t, 0, bt.Len(), "expected length 0, got %d", bt.Len())
+	_, ok := bt.Min()
+	assert.False(t, ok, "expected no min key in an empty tree")
+
+	// Test Set and Get
+	for _, key := range []int{50, 10, 40, 20, 30} {
+		assert.True(t, bt.Set(key), "expected key %d to be inserted", key)
+	}
+	assert.False(t, bt.Set(30), "expected duplicate key 30 to be replaced, not inserted")
+	assert.Equal(t, 5, bt.Len(), "expected length 5, got %d", bt.Len())
+
+	value, ok := bt.Get(20)
+	assert.True(t, ok, "expected key 20 to be found")
+	assert.Equal(t, 20, value, "expected value 20, got %d", value)
+	assert.False(t, bt.Has(25), "expected key 25 to be missing")
+
+	// Test ordered iteration
+	assert.Equal(t, []int{10, 20, 30, 40, 50}, collect(bt))
+
+	// Test range scans
+	var scanned []int
+	bt.AscendRange(15, 40, func(key int) bool {
+		scanned = append(scanned, key)
+		return true
+	})
+	assert.Equal(t, []int{20, 30}, scanned, "expected keys in [15, 40)")
+
+	scanned = nil
+	bt.AscendGreaterOrEqual(30, func(key int) bool {
+		scanned = append(scanned, key)
+		return len(scanned) < 2
+	})
+	assert.Equal(t, []int{30, 40}, scanned, "expected iteration to stop after 2 keys")
+
+	// Test floor and ceiling
+	floor, ok := bt.Floor(35)
+	assert.True(t, ok)
+	assert.Equal(t, 30, floor, "expected floor of 35 to be 30")
+	_, ok = bt.Floor(5)
+	assert.False(t, ok, "expected no floor below the smallest key")
+
+	ceiling, ok := bt.Ceiling(35)
+	assert.True(t, ok)
+	assert.Equal(t, 40, ceiling, "expected ceiling of 35 to be 40")
+	_, ok = bt.Ceiling(55)
+	assert.False(t, ok, "expected no ceiling above the largest key")
+
+	// Test rank and select
+	assert.Equal(t, 0, bt.Rank(10), "expected rank of 10 to be 0")
+	assert.Equal(t, 3, bt.Rank(35), "expected rank of 35 to be 3")
+	assert.Equal(t, 5, bt.Rank(99), "expected rank of 99 to be 5")
+
+	key, ok := bt.Select(3)
+	assert.True(t, ok)
+	assert.Equal(t, 40, key, "expected key at position 3 to be 40")
+	_, ok = bt.Select(5)
+	assert.False(t, ok, "expected no key at position 5")
+
+	// Test deleting keys
+	assert.True(t, bt.Delete(30), "expected key 30 to be deleted")
+	assert.False(t, bt.Delete(30), "expected key 30 to be already deleted")
+	assert.Equal(t, []int{10, 20, 40, 50}, collect(bt))
+
+	for _, key := range []int{10, 20, 40, 50} {
+		assert.True(t, bt.Delete(key), "expected key %d to be deleted", key)
+	}
+	assert.Equal(t, 0, bt.Len(), "expected length 0 after deleting every key")
+}
+
+func TestBTree_Randomized(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	bt := NewBTreeWithDegree(3, intLess)
+	reference := map[int]bool{}
+
+	for i := 0; i < 5000; i++ {
+		key := rng.Intn(1000)
+		if rng.Intn(3) == 0 {
+			assert.Equal(t, reference[key], bt.Delete(key), "unexpected delete result for %d", key)
+			delete(reference, key)
+		} else {
+			assert.Equal(t, !reference[key], bt.Set(key), "unexpected set result for %d", key)
+			reference[key] = true
+		}
+	}
+
+	expected := make([]int, 0, len(reference))
+	for key := range reference {
+		expected = append(expected, key)
+	}
+	sort.Ints(expected)
+
+	assert.Equal(t, len(expected), bt.Len(), "expected length %d", len(expected))
+	assert.Equal(t, expected, collect(bt), "expected keys to be sorted")
+
+	for i, key := range expected {
+		assert.Equal(t, i, bt.Rank(key), "unexpected rank for %d", key)
+		selected, ok := bt.Select(i)
+		assert.True(t, ok)
+		assert.Equal(t, key, selected, "unexpected key at position %d", i)
+	}
+}
@@ -57,3 +57,17 @@ func (om *OrderedMap[K, T]) Delete(key K) bool {
 func (om *OrderedMap[K, T]) Len() int {
 	return len(om.keys)
 }
+
+// Compact reallocates the keys and the values to release the memory still held
+// after deletions, as neither the slice nor the map shrink on their own
+func (om *OrderedMap[K, T]) Compact() {
+	keys := make([]K, len(om.keys))
+	copy(keys, om.keys)
+
+	values := make(map[K]T, len(om.values))
+	for k, v := range om.values {
+		values[k] = v
+	}
+
+	om.keys, om.values = keys, values
+}
@@ -66,4 +66,19 @@ func TestOrderedMap(t *testing.T) {
 	expectedKeys = []string{"b", "c"}
 	keys = om.Keys()
 	assert.Equal(t, expectedKeys, keys, "expected keys %v, got %v", expectedKeys, keys)
+
+	// Test compacting keeps the keys, their order and their values
+	om.Compact()
+	keys = om.Keys()
+	assert.Equal(
+		t,
+		expectedKeys,
+		keys,
+		"expected keys %v after compact, got %v",
+		expectedKeys,
+		keys,
+	)
+	value, ok = om.Get("c")
+	assert.True(t, ok, "expected key 'c' to be found after compact")
+	assert.Equal(t, 4, value, "expected value 4 after compact, got %d", value)
 }
@@ -0,0 +1,225 @@
+// Package events publishes the changes made to the employees to in-process subscribers
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+)
+
+// Type is the kind of change an event records
+type Type string
+
+// Event types
+const (
+	TypeCreated Type = "created"
+	TypeUpdated Type = "updated"
+	TypeDeleted Type = "deleted"
+)
+
+// Types lists every event type
+var Types = []Type{TypeCreated, TypeUpdated, TypeDeleted}
+
+// ParseType parses an event type
+func ParseType(s string) (Type, error) {
+	for _, t := range Types {
+		if string(t) == s {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("unknown event type %q, expected one of %q", s, Types)
+}
+
+// Event is a change made to an employee
+type Event struct {
+	ID       string          `json:"id"`       // Deduplication ID, the same on every redelivery
+	Seq      uint64          `json:"seq"`      // Monotonic sequence number, starting at 1
+	Type     Type            `json:"type"`     // Kind of change
+	Time     time.Time       `json:"time"`     // Time the change was applied
+	Employee models.Employee `json:"employee"` // Employee after the change, or before its deletion
+}
+
+// ErrGone is returned when the events following a sequence number are no
+// longer retained, or were never published by this process
+var ErrGone = errors.New("events are no longer retained")
+
+// subscriberBuffer is the number of events a subscriber can lag behind before
+// it is dropped
+const subscriberBuffer = 64
+
+// Broker retains the latest events in a bounded buffer and fans them out to
+// the subscribers
+type Broker struct {
+	mu          sync.Mutex
+	epoch       string  // Identifies the sequence numbers of this broker, unique per process
+	seq         uint64  // Sequence number of the last event
+	buffer      []Event // Ring buffer of the retained events
+	start       int     // Index of the oldest retained event in the buffer
+	size        int     // Number of retained events
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+// NewBroker creates a broker retaining the last capacity events
+func NewBroker(capacity int) *Broker {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &Broker{
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		buffer:      make([]Event, capacity),
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish records the event and delivers it to the subscribers
+//
+// Events already published, e.g. redelivered by the outbox relay, are ignored.
+// When events are skipped, e.g. after a restart, the retained events are
+// dropped so that resuming across the gap fails with ErrGone.
+//
+// Subscribers that lag too far behind are dropped, they can resume from the
+// buffer with the sequence number of the last event they received.
+func (b *Broker) Publish(_ context.Context, event Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if event.Seq <= b.seq {
+		return nil // Duplicate
+	}
+	if event.Seq > b.seq+1 {
+		b.start, b.size = 0, 0
+	}
+	b.seq = event.Seq
+
+	// Append to the ring buffer, overwriting the oldest event when full
+	end := (b.start + b.size) % len(b.buffer)
+	b.buffer[end] = event
+	if b.size < len(b.buffer) {
+		b.size++
+	} else {
+		b.start = (b.start + 1) % len(b.buffer)
+	}
+
+	for sub := range b.subscribers {
+		select {
+		case sub.events <- event:
+		default:
+			b.drop(sub)
+		}
+	}
+	return nil
+}
+
+// Epoch identifies the sequence numbers of the broker
+//
+// Sequence numbers restart with the process on some backends, and the retained
+// events never survive it, so a sequence number is only meaningful along with
+// the epoch of the broker it was read from.
+func (b *Broker) Epoch() string {
+	return b.epoch
+}
+
+// Seq returns the sequence number of the last event, 0 when none was published
+func (b *Broker) Seq() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.seq
+}
+
+// Since returns up to limit retained events following the sequence number,
+// oldest first, or all of them when limit is 0
+//
+// ErrGone is returned when some of these events are no longer retained.
+func (b *Broker) Since(seq uint64, limit int) ([]Event, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.since(seq, limit)
+}
+
+// Subscribe returns the retained events following the sequence number and a
+// subscription to the events published afterwards, with no gap in between
+//
+// ErrGone is returned when some of these events are no longer retained.
+func (b *Broker) Subscribe(seq uint64) ([]Event, *Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	replay, err := b.since(seq, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sub := &Subscription{broker: b, events: make(chan Event, subscriberBuffer)}
+	if b.closed {
+		close(sub.events)
+	} else {
+		b.subscribers[sub] = struct{}{}
+	}
+	return replay, sub, nil
+}
+
+// Close ends every subscription, e.g. when the server shuts down
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subscribers {
+		b.drop(sub)
+	}
+}
+
+// since returns the retained events following the sequence number, the caller
+// must hold the lock
+func (b *Broker) since(seq uint64, limit int) ([]Event, error) {
+	// The oldest retained event must directly follow the sequence number,
+	// and the sequence number must have been published by this broker
+	oldest := b.seq - uint64(b.size) // Sequence number preceding the oldest retained event
+	if seq < oldest || seq > b.seq {
+		return nil, ErrGone
+	}
+
+	count := int(b.seq - seq)
+	if limit > 0 && count > limit {
+		count = limit
+	}
+	events := make([]Event, 0, count)
+	skip := int(seq - oldest)
+	for i := 0; i < count; i++ {
+		events = append(events, b.buffer[(b.start+skip+i)%len(b.buffer)])
+	}
+	return events, nil
+}
+
+// drop ends the subscription, the caller must hold the lock
+func (b *Broker) drop(sub *Subscription) {
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// Subscription receives the events published after it was created
+type Subscription struct {
+	broker *Broker
+	events chan Event
+}
+
+// Events returns the channel of the events, it is closed when the subscriber
+// is dropped or the broker is closed
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close ends the subscription
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.drop(s)
+}
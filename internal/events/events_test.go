@@ -0,0 +1,114 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// publish publishes an event following the last one
+func publish(broker *Broker, eventType Type) {
+	broker.Publish(context.Background(), Event{Seq: broker.Seq() + 1, Type: eventType})
+}
+
+// seqs returns the sequence numbers of the events
+func seqs(events []Event) []uint64 {
+	result := make([]uint64, 0, len(events))
+	for _, event := range events {
+		result = append(result, event.Seq)
+	}
+	return result
+}
+
+func TestBroker_Since(t *testing.T) {
+	broker := NewBroker(3)
+
+	events, err := broker.Since(0, 0)
+	assert.Nil(t, err, "nothing is missed before the first event")
+	assert.Empty(t, events)
+
+	for i := 0; i < 5; i++ {
+		publish(broker, TypeCreated)
+	}
+	assert.Equal(t, uint64(5), broker.Seq())
+
+	// Only the last 3 events are retained
+	events, err = broker.Since(2, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{3, 4, 5}, seqs(events))
+
+	events, err = broker.Since(3, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{4}, seqs(events), "events should be limited")
+
+	events, err = broker.Since(5, 0)
+	assert.Nil(t, err)
+	assert.Empty(t, events, "client should be up to date")
+
+	_, err = broker.Since(1, 0)
+	assert.ErrorIs(t, err, ErrGone, "event 2 is no longer retained")
+
+	_, err = broker.Since(6, 0)
+	assert.ErrorIs(t, err, ErrGone, "event 6 was never published, e.g. before a restart")
+}
+
+func TestBroker_Subscribe(t *testing.T) {
+	broker := NewBroker(10)
+	publish(broker, TypeCreated)
+
+	// Resume after the first event
+	replay, sub, err := broker.Subscribe(0)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{1}, seqs(replay), "missed events should be replayed")
+
+	publish(broker, TypeUpdated)
+	event := <-sub.Events()
+	assert.Equal(t, uint64(2), event.Seq, "new events should be delivered")
+	assert.Equal(t, TypeUpdated, event.Type)
+
+	// Closing the broker ends the subscriptions
+	broker.Close()
+	_, ok := <-sub.Events()
+	assert.False(t, ok, "channel should be closed")
+	sub.Close() // Closing twice is harmless
+}
+
+func TestBroker_DropsSlowSubscribers(t *testing.T) {
+	broker := NewBroker(100)
+	_, slow, _ := broker.Subscribe(0)
+
+	for i := 0; i < subscriberBuffer+1; i++ {
+		publish(broker, TypeCreated)
+	}
+
+	received := 0
+	for range slow.Events() {
+		received++
+	}
+	assert.Equal(t, subscriberBuffer, received, "slow subscriber should be dropped once full")
+}
+
+func TestBroker_PublishDuplicatesAndGaps(t *testing.T) {
+	broker := NewBroker(10)
+	ctx := context.Background()
+
+	publish(broker, TypeCreated)
+	publish(broker, TypeUpdated)
+
+	// Redelivered events are ignored
+	assert.Nil(t, broker.Publish(ctx, Event{Seq: 2, Type: TypeDeleted}))
+	events, err := broker.Since(0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{1, 2}, seqs(events))
+	assert.Equal(t, TypeUpdated, events[1].Type, "duplicate should not replace the event")
+
+	// Skipped events, e.g. published before a restart, can not be resumed across
+	assert.Nil(t, broker.Publish(ctx, Event{Seq: 5, Type: TypeCreated}))
+	assert.Equal(t, uint64(5), broker.Seq())
+	_, err = broker.Since(2, 0)
+	assert.ErrorIs(t, err, ErrGone)
+	events, err = broker.Since(4, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{5}, seqs(events))
+}
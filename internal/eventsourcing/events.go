@@ -0,0 +1,117 @@
+// Package eventsourcing derives the employees from an append-only log of the
+// events of their lifecycle
+package eventsourcing
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/respository"
+)
+
+// EventType is the kind of change an event records
+type EventType string
+
+// Event types
+const (
+	EmployeeHired        EventType = "EmployeeHired"        // Carries every field
+	EmployeeRenamed      EventType = "EmployeeRenamed"      // Carries the name
+	EmployeeEmailChanged EventType = "EmployeeEmailChanged" // Carries the email
+	PositionChanged      EventType = "PositionChanged"      // Carries the position
+	SalaryChanged        EventType = "SalaryChanged"        // Carries the salary
+	EmployeeTerminated   EventType = "EmployeeTerminated"   // Carries no field
+)
+
+// Event is an entry of the log, it is never changed once appended
+type Event struct {
+	Seq        uint64    `json:"seq"` // Position in the log, from 1 without gaps
+	Type       EventType `json:"type"`
+	EmployeeID models.ID `json:"employee_id"`
+	Time       time.Time `json:"time"`
+	Name       string    `json:"name,omitempty"`
+	Email      string    `json:"email,omitempty"`
+	Position   string    `json:"position,omitempty"`
+	Salary     float64   `json:"salary,omitempty"`
+	// First event of the write the event is part of, 0 when logged before writes were numbered
+	WriteSeq uint64 `json:"write_seq,omitempty"`
+}
+
+// hired returns the event of the creation of the employee
+func hired(employee models.Employee) Event {
+	return Event{
+		Type:       EmployeeHired,
+		EmployeeID: employee.ID,
+		Name:       employee.Name,
+		Email:      employee.Email,
+		Position:   employee.Position,
+		Salary:     employee.Salary,
+	}
+}
+
+// changes returns the events turning the current employee into the updated one,
+// none when nothing changed
+func changes(current models.Employee, updated models.Employee) []Event {
+	var events []Event
+	if current.Name != updated.Name {
+		events = append(events, Event{Type: EmployeeRenamed, Name: updated.Name})
+	}
+	if current.Email != updated.Email {
+		events = append(events, Event{Type: EmployeeEmailChanged, Email: updated.Email})
+	}
+	if current.Position != updated.Position {
+		events = append(events, Event{Type: PositionChanged, Position: updated.Position})
+	}
+	if current.Salary != updated.Salary {
+		events = append(events, Event{Type: SalaryChanged, Salary: updated.Salary})
+	}
+	for i := range events {
+		events[i].EmployeeID = updated.ID
+	}
+	return events
+}
+
+// apply folds the event into the employees, it returns the employee after
+// the event, or before its termination
+func apply(
+	employees *respository.EmployeeInMemoryRepository,
+	event Event,
+) (models.Employee, error) {
+	if event.Type == EmployeeHired {
+		employee := models.Employee{
+			ID:       event.EmployeeID,
+			Name:     event.Name,
+			Email:    event.Email,
+			Position: event.Position,
+			Salary:   event.Salary,
+		}
+		return employee, employees.ImportEmployee(employee)
+	}
+
+	employee, err := employees.GetEmployeeByID(event.EmployeeID)
+	if err != nil {
+		return employee, err
+	}
+	switch event.Type {
+	case EmployeeRenamed:
+		employee.Name = event.Name
+	case EmployeeEmailChanged:
+		employee.Email = event.Email
+	case PositionChanged:
+		employee.Position = event.Position
+	case SalaryChanged:
+		employee.Salary = event.Salary
+	case EmployeeTerminated:
+		return employee, employees.DeleteEmployee(event.EmployeeID)
+	default:
+		return employee, fmt.Errorf("unknown event type %q", event.Type)
+	}
+
+	return employees.UpdateEmployee(
+		employee.ID,
+		employee.Name,
+		employee.Email,
+		employee.Position,
+		employee.Salary,
+	)
+}
@@ -0,0 +1,391 @@
+package eventsourcing
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/idgen"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/respository"
+	"github.com/stretchr/testify/assert"
+)
+
+// open opens a repository on the store, with sequential IDs and a clock
+// advancing by a minute on every write
+func open(t *testing.T, store Store, snapshotEvery int) *EventSourcedEmployeeRepository {
+	t.Helper()
+	repo, err := Open(
+		store,
+		idgen.NewSequential(),
+		snapshotEvery,
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+	)
+	assert.Nil(t, err)
+
+	now := time.Date(2024, time.March, 1, 9, 0, 0, 0, time.UTC)
+	repo.now = func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	}
+	return repo
+}
+
+// types returns the types of the events of the store
+func types(t *testing.T, store Store) []EventType {
+	t.Helper()
+	var result []EventType
+	err := store.Read(0, func(event Event) error {
+		result = append(result, event.Type)
+		return nil
+	})
+	assert.Nil(t, err)
+	return result
+}
+
+func TestEventSourcedEmployeeRepository_Log(t *testing.T) {
+	store := NewMemoryStore()
+	repo := open(t, store, 0)
+
+	employee, err := repo.CreateEmployee("Harshit", "harshit@example.com", "Engineer", 1000)
+	assert.Nil(t, err)
+	_, err = repo.UpdateEmployee(
+		employee.ID,
+		"Harshit Kumar",
+		"harshit@example.com",
+		"Engineer",
+		1200,
+	)
+	assert.Nil(t, err)
+	_, err = repo.UpdateEmployee(
+		employee.ID,
+		"Harshit Kumar",
+		"harshit@example.com",
+		"Engineer",
+		1200,
+	)
+	assert.Nil(t, err, "updates without changes should succeed")
+	_, err = repo.UpdateEmployee(employee.ID, "Harshit Kumar", "hk@example.com", "Lead", 1200)
+	assert.Nil(t, err)
+	other, err := repo.CreateEmployee("Ganesh", "ganesh@example.com", "Engineer", 1000)
+	assert.Nil(t, err)
+	assert.Nil(t, repo.DeleteEmployee(other.ID))
+
+	// Rejected writes are not logged
+	_, err = repo.CreateEmployee("Copy", "hk@example.com", "Engineer", 1000)
+	assert.ErrorIs(t, err, respository.ErrDuplicate)
+	assert.ErrorIs(t, repo.DeleteEmployee(other.ID), respository.ErrRecordNotFound)
+
+	assert.Equal(t, []EventType{
+		EmployeeHired,
+		EmployeeRenamed,
+		SalaryChanged,
+		EmployeeEmailChanged,
+		PositionChanged,
+		EmployeeHired,
+		EmployeeTerminated,
+	}, types(t, store))
+	assert.Equal(t, uint64(7), repo.Seq())
+
+	// The employees are rebuilt from the log, and the IDs of terminated employees are not reused
+	reopened := open(t, store, 0)
+	employees, total := reopened.GetAllEmployees(1, 0)
+	assert.Equal(t, 1, total)
+	assert.Equal(t, models.Employee{
+		ID:       employee.ID,
+		Name:     "Harshit Kumar",
+		Email:    "hk@example.com",
+		Position: "Lead",
+		Salary:   1200,
+	}, employees[0])
+	created, err := reopened.CreateEmployee("Ganesh", "ganesh@example.com", "Engineer", 1000)
+	assert.Nil(t, err)
+	assert.NotEqual(t, other.ID, created.ID, "IDs of terminated employees should not be reused")
+}
+
+func TestEventSourcedEmployeeRepository_Snapshots(t *testing.T) {
+	store := NewMemoryStore()
+	repo := open(t, store, 2)
+
+	employee, err := repo.CreateEmployee("Harshit", "harshit@example.com", "Engineer", 1000)
+	assert.Nil(t, err)
+	_, err = repo.UpdateEmployee(employee.ID, "Harshit", "harshit@example.com", "Lead", 1000)
+	assert.Nil(t, err)
+	_, err = repo.UpdateEmployee(employee.ID, "Harshit", "harshit@example.com", "Lead", 1500)
+	assert.Nil(t, err)
+
+	snapshot, err := store.LoadSnapshot()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), snapshot.Seq, "a snapshot should be taken every 2 events")
+	assert.Equal(t, "Lead", snapshot.Employees[0].Position)
+
+	// Events covered by the snapshot are not folded again
+	store.events = store.events[2:]
+	reopened := open(t, store, 2)
+	rebuilt, err := reopened.GetEmployeeByID(employee.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, 1500.0, rebuilt.Salary)
+	assert.Equal(t, uint64(3), reopened.Seq())
+
+	// A snapshot taken at a termination no longer holds the employee
+	assert.Nil(t, reopened.DeleteEmployee(employee.ID))
+	snapshot, err = store.LoadSnapshot()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(4), snapshot.Seq)
+	reopened = open(t, store, 2)
+	_, err = reopened.GetEmployeeByID(employee.ID)
+	assert.ErrorIs(
+		t,
+		err,
+		respository.ErrRecordNotFound,
+		"terminated employee should stay terminated",
+	)
+}
+
+func TestEventSourcedEmployeeRepository_Replay(t *testing.T) {
+	store := NewMemoryStore()
+	repo := open(t, store, 2)
+
+	// Hired at 09:01, raised at 09:02 and 09:03, terminated at 09:04
+	employee, err := repo.CreateEmployee("Harshit", "harshit@example.com", "Engineer", 1000)
+	assert.Nil(t, err)
+	_, err = repo.UpdateEmployee(employee.ID, "Harshit", "harshit@example.com", "Engineer", 1200)
+	assert.Nil(t, err)
+	_, err = repo.UpdateEmployee(employee.ID, "Harshit", "harshit@example.com", "Engineer", 1500)
+	assert.Nil(t, err)
+	assert.Nil(t, repo.DeleteEmployee(employee.ID))
+
+	past, err := repo.ReplayTo(2)
+	assert.Nil(t, err)
+	replayed, err := past.GetEmployeeByID(employee.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, 1200.0, replayed.Salary, "replay should stop at the sequence number")
+
+	past, err = repo.ReplayUntil(time.Date(2024, time.March, 1, 9, 3, 30, 0, time.UTC))
+	assert.Nil(t, err)
+	replayed, err = past.GetEmployeeByID(employee.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, 1500.0, replayed.Salary, "replay should stop at the time")
+
+	past, err = repo.ReplayUntil(time.Date(2024, time.March, 1, 9, 0, 0, 0, time.UTC))
+	assert.Nil(t, err)
+	_, err = past.GetEmployeeByID(employee.ID)
+	assert.ErrorIs(t, err, respository.ErrRecordNotFound, "employee should not be hired yet")
+
+	_, err = repo.GetEmployeeByID(employee.ID)
+	assert.ErrorIs(t, err, respository.ErrRecordNotFound, "replays should not change the employees")
+
+	// Point-in-time reads replay the log too
+	replayed, err = repo.GetByIDAsOf(
+		employee.ID,
+		time.Date(2024, time.March, 1, 9, 2, 0, 0, time.UTC),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, 1200.0, replayed.Salary)
+	employees, total, err := repo.GetAllAsOf(
+		time.Date(2024, time.March, 1, 9, 4, 0, 0, time.UTC),
+		1,
+		0,
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, total, "employee should be terminated")
+	assert.Empty(t, employees)
+}
+
+// countingStore is a memory store counting the reads of the log
+type countingStore struct {
+	*MemoryStore
+	reads int
+}
+
+func (s *countingStore) Read(after uint64, fn func(event Event) error) error {
+	s.reads++
+	return s.MemoryStore.Read(after, fn)
+}
+
+func TestEventSourcedEmployeeRepository_ReplayCache(t *testing.T) {
+	store := &countingStore{MemoryStore: NewMemoryStore()}
+	repo := open(t, store, 0)
+
+	// Hired at 09:01, raised at 09:02
+	employee, err := repo.CreateEmployee("Harshit", "harshit@example.com", "Engineer", 1000)
+	assert.Nil(t, err)
+	_, err = repo.UpdateEmployee(employee.ID, "Harshit", "harshit@example.com", "Engineer", 1200)
+	assert.Nil(t, err)
+
+	// Pages of a past time are replayed once
+	past := time.Date(2024, time.March, 1, 9, 1, 30, 0, time.UTC)
+	reads := store.reads
+	_, total, err := repo.GetAllAsOf(past, 1, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, total)
+	replayed, err := repo.GetByIDAsOf(employee.ID, past)
+	assert.Nil(t, err)
+	assert.Equal(t, 1000.0, replayed.Salary)
+	assert.Equal(t, reads+1, store.reads, "replay should be cached")
+
+	// Times after the last event are replayed again, later writes can change them
+	future := time.Date(2024, time.March, 1, 10, 0, 0, 0, time.UTC)
+	_, err = repo.GetByIDAsOf(employee.ID, future)
+	assert.Nil(t, err)
+	_, err = repo.UpdateEmployee(employee.ID, "Harshit", "harshit@example.com", "Engineer", 1500)
+	assert.Nil(t, err)
+	replayed, err = repo.GetByIDAsOf(employee.ID, future)
+	assert.Nil(t, err)
+	assert.Equal(t, 1500.0, replayed.Salary)
+}
+
+// failingStore is a memory store whose appends fail on demand, and call
+// appending, when set, before they fail or succeed
+type failingStore struct {
+	*MemoryStore
+	fail      bool
+	appending func()
+}
+
+func (s *failingStore) Append(events []Event) error {
+	if s.appending != nil {
+		s.appending()
+	}
+	if s.fail {
+		return errors.New("disk full")
+	}
+	return s.MemoryStore.Append(events)
+}
+
+func TestEventSourcedEmployeeRepository_FailedAppend(t *testing.T) {
+	store := &failingStore{MemoryStore: NewMemoryStore()}
+	repo := open(t, store, 0)
+	employee, err := repo.CreateEmployee("Harshit", "harshit@example.com", "Engineer", 1000)
+	assert.Nil(t, err)
+
+	store.fail = true
+	_, err = repo.CreateEmployee("Ganesh", "ganesh@example.com", "Engineer", 1000)
+	assert.ErrorContains(t, err, "disk full")
+	_, err = repo.UpdateEmployee(employee.ID, "Harshit", "harshit@example.com", "Lead", 1000)
+	assert.ErrorContains(t, err, "disk full")
+	assert.ErrorContains(t, repo.DeleteEmployee(employee.ID), "disk full")
+
+	employees, total := repo.GetAllEmployees(1, 0)
+	assert.Equal(t, 1, total, "failed writes should be undone")
+	assert.Equal(t, "Engineer", employees[0].Position)
+	assert.Equal(t, uint64(1), repo.Seq())
+
+	// Writes are not read before they are appended
+	store.fail = false
+	store.appending = func() {
+		employees, _ = repo.GetAllEmployees(1, 0)
+	}
+	_, err = repo.CreateEmployee("Ganesh", "ganesh@example.com", "Engineer", 1000)
+	assert.Nil(t, err)
+	assert.Len(t, employees, 1, "created employee should not be read before the append")
+	_, err = repo.UpdateEmployee(employee.ID, "Harshit", "harshit@example.com", "Lead", 1000)
+	assert.Nil(t, err)
+	assert.Equal(t, "Engineer", employees[0].Position, "update should not be read before the append")
+}
+
+func TestEventSourcedEmployeeRepository_Outbox(t *testing.T) {
+	store := &failingStore{MemoryStore: NewMemoryStore()}
+	repo := open(t, store, 0)
+	outbox := respository.NewOutbox[models.Employee]()
+	repo.SetOutbox(outbox)
+	employee, err := repo.CreateEmployee("Harshit", "harshit@example.com", "Engineer", 1000)
+	assert.Nil(t, err)
+
+	// Writes undone after a failed append are not recorded
+	store.fail = true
+	_, err = repo.CreateEmployee("Ganesh", "ganesh@example.com", "Engineer", 1000)
+	assert.NotNil(t, err)
+	_, err = repo.UpdateEmployee(employee.ID, "Harshit", "harshit@example.com", "Lead", 1000)
+	assert.NotNil(t, err)
+	assert.NotNil(t, repo.DeleteEmployee(employee.ID))
+	assert.Equal(t, 1, outbox.Len())
+
+	// The outbox is saved with the snapshots
+	store.fail = false
+	_, err = repo.UpdateEmployee(employee.ID, "Harshit", "harshit@example.com", "Lead", 1500)
+	assert.Nil(t, err)
+	assert.Nil(t, repo.Snapshot())
+	outbox.Ack(1)
+
+	// The writes logged after the snapshot are recovered, with the same IDs
+	first := outbox.Pending(0, 0)[0]
+	assert.Nil(t, repo.DeleteEmployee(employee.ID))
+	deleted := outbox.Pending(0, 0)[1]
+	restored := respository.NewOutbox[models.Employee]()
+	open(t, store, 0).SetOutbox(restored)
+	pending := restored.Pending(0, 0)
+	assert.Len(t, pending, 3, "acknowledgements after the snapshot should be lost")
+	assert.Equal(t, first, pending[1])
+	assert.Equal(t, deleted.ID, pending[2].ID)
+	assert.Equal(t, deleted.Seq, pending[2].Seq)
+	assert.Equal(t, respository.OpDelete, pending[2].Op)
+	assert.Equal(t, "Lead", pending[2].Entity.Position)
+}
+
+func TestEventSourcedEmployeeRepository_RecoverWrites(t *testing.T) {
+	store := NewMemoryStore()
+	repo := open(t, store, 0)
+	repo.SetOutbox(respository.NewOutbox[models.Employee]())
+	employee, err := repo.CreateEmployee("Harshit", "harshit@example.com", "Engineer", 1000)
+	assert.Nil(t, err)
+	assert.Nil(t, repo.Snapshot())
+
+	// Two updates of the employee at the same time, e.g. a clock going backwards
+	now := repo.now()
+	repo.now = func() time.Time { return now }
+	_, err = repo.UpdateEmployee(employee.ID, "Harshit Kumar", "harshit@example.com", "Lead", 1000)
+	assert.Nil(t, err)
+	_, err = repo.UpdateEmployee(employee.ID, "Harshit Kumar", "harshit@example.com", "Lead", 1500)
+	assert.Nil(t, err)
+
+	// Each update is recovered on its own, the first one with both of its events
+	restored := respository.NewOutbox[models.Employee]()
+	open(t, store, 0).SetOutbox(restored)
+	pending := restored.Pending(0, 0)
+	if assert.Len(t, pending, 3, "updates should not be merged") {
+		assert.Equal(t, "Lead", pending[1].Entity.Position)
+		assert.Equal(t, 1000.0, pending[1].Entity.Salary)
+		assert.Equal(t, 1500.0, pending[2].Entity.Salary)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := OpenFileStore(dir)
+	assert.Nil(t, err)
+	repo := open(t, store, 0)
+	employee, err := repo.CreateEmployee("Harshit", "harshit@example.com", "Engineer", 1000)
+	assert.Nil(t, err)
+	_, err = repo.UpdateEmployee(employee.ID, "Harshit", "harshit@example.com", "Lead", 1000)
+	assert.Nil(t, err)
+	assert.Nil(t, repo.Snapshot())
+	assert.Nil(t, store.Close())
+
+	// A crash in the middle of an append leaves an incomplete line
+	file, err := os.OpenFile(filepath.Join(dir, "events.jsonl"), os.O_APPEND|os.O_WRONLY, 0)
+	assert.Nil(t, err)
+	_, err = file.WriteString(`{"seq":3,"type":"Sal`)
+	assert.Nil(t, err)
+	assert.Nil(t, file.Close())
+
+	store, err = OpenFileStore(dir)
+	assert.Nil(t, err)
+	defer store.Close()
+	assert.Equal(t, []EventType{EmployeeHired, PositionChanged}, types(t, store))
+	snapshot, err := store.LoadSnapshot()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), snapshot.Seq)
+
+	reopened := open(t, store, 0)
+	rebuilt, err := reopened.GetEmployeeByID(employee.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "Lead", rebuilt.Position)
+	_, err = reopened.UpdateEmployee(employee.ID, "Harshit", "harshit@example.com", "Lead", 1500)
+	assert.Nil(t, err)
+	assert.Equal(t, []EventType{EmployeeHired, PositionChanged, SalaryChanged}, types(t, store))
+}
@@ -0,0 +1,549 @@
+package eventsourcing
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/idgen"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/respository"
+)
+
+// Ensure type implements the interface
+var _ respository.IEmployeeRepository = (*EventSourcedEmployeeRepository)(nil)
+var _ respository.HistoryReader[models.Employee] = (*EventSourcedEmployeeRepository)(nil)
+
+// errStop stops a read of the log early
+var errStop = errors.New("stop")
+
+// replayCacheSize is the number of replays kept for the point-in-time reads
+const replayCacheSize = 8
+
+// EventSourcedEmployeeRepository is an IEmployeeRepository deriving the
+// employees from the event log of the store
+//
+// The employees are folded into an in-memory projection serving the reads.
+// Writes are validated against the projection, appended to the log, and only
+// then applied to the projection, so that reads never see a write the log
+// does not hold. Reads do not wait for the log.
+//
+// The writes are recorded in the outbox once appended, so that undone writes
+// are never published, and the outbox is saved with the snapshots.
+type EventSourcedEmployeeRepository struct {
+	mu            sync.Mutex // Serializes the writes, so that the log and the projection agree
+	store         Store
+	projection    *respository.EmployeeInMemoryRepository
+	idGenerator   idgen.IDGenerator
+	snapshotEvery int // Events between snapshots, 0 disables them
+	logger        *slog.Logger
+	now           func() time.Time
+	outbox        *respository.Outbox[models.Employee] // Records the writes when set
+
+	seq           uint64    // Last event of the log
+	lastTime      time.Time // Time of the last event
+	lastHired     models.ID // Latest hired employee
+	sinceSnapshot int       // Events appended since the last snapshot
+
+	// Outbox of the snapshot and writes logged after it, recorded once the outbox is set
+	restoredOutbox *respository.OutboxState[models.Employee]
+	recovered      []write
+
+	replays *replayCache // Replays of the point-in-time reads
+}
+
+// replayCache keeps the latest replays until a time before the last event,
+// later events cannot change them
+type replayCache struct {
+	mu      sync.Mutex
+	entries []replayed // Least recently used first
+}
+
+// replayed is the employees replayed until a time
+type replayed struct {
+	asOf      time.Time
+	employees *respository.EmployeeInMemoryRepository
+}
+
+// write is a write folded from the events following the snapshot
+type write struct {
+	seq      uint64 // First event of the write
+	id       string // Deduplication ID of the message
+	op       respository.WriteOp
+	employee models.Employee
+	time     time.Time
+}
+
+// Open rebuilds the employees from the latest snapshot of the store and the
+// events following it, new employees get IDs of the generator
+func Open(
+	store Store,
+	idGenerator idgen.IDGenerator,
+	snapshotEvery int,
+	logger *slog.Logger,
+) (*EventSourcedEmployeeRepository, error) {
+	r := &EventSourcedEmployeeRepository{
+		store:         store,
+		projection:    respository.NewEmployeeInMemoryRepositoryWithIDGenerator(idGenerator),
+		idGenerator:   idGenerator,
+		snapshotEvery: snapshotEvery,
+		logger:        logger,
+		now:           time.Now,
+		replays:       &replayCache{},
+	}
+
+	snapshot, err := store.LoadSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	// Without an outbox in the snapshot, the events were logged before the outbox was set
+	var recoverWrite func(event Event, employee models.Employee)
+	if snapshot.Outbox != nil || snapshot.Seq == 0 {
+		r.restoredOutbox, recoverWrite = snapshot.Outbox, r.recoverWrite
+	}
+	state, err := fold(
+		store,
+		r.projection,
+		snapshot,
+		func(uint64, time.Time) bool { return true },
+		recoverWrite,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Employees hired and terminated since are not in the projection, their IDs must not be reused
+	if observer, ok := idGenerator.(idgen.Observer); ok && state.LastHired != "" {
+		observer.Observe(string(state.LastHired))
+	}
+	r.seq, r.lastTime, r.lastHired = state.Seq, state.Time, state.LastHired
+	r.sinceSnapshot = int(state.Seq - snapshot.Seq)
+	return r, nil
+}
+
+// Projection returns the employees folded from the log, it must not be written to
+func (r *EventSourcedEmployeeRepository) Projection() *respository.EmployeeInMemoryRepository {
+	return r.projection
+}
+
+// SetOutbox records every subsequent write in the outbox once appended, and
+// includes the outbox in the snapshots
+//
+// The outbox is restored from the latest snapshot, along with the writes
+// logged after it: they are published again when they already were, e.g.
+// after a crash.
+func (r *EventSourcedEmployeeRepository) SetOutbox(outbox *respository.Outbox[models.Employee]) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.restoredOutbox != nil {
+		outbox.Restore(*r.restoredOutbox)
+	}
+	for _, w := range r.recovered {
+		outbox.RecordAs(w.id, w.op, w.employee)
+	}
+	r.outbox, r.restoredOutbox, r.recovered = outbox, nil, nil
+}
+
+// Seq returns the sequence number of the last event of the log
+func (r *EventSourcedEmployeeRepository) Seq() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.seq
+}
+
+func (r *EventSourcedEmployeeRepository) CreateEmployee(
+	name string,
+	email string,
+	position string,
+	salary float64,
+) (models.Employee, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Enforce the unique constraints before an ID is used up
+	employee := models.Employee{Name: name, Email: email, Position: position, Salary: salary}
+	if err := r.projection.Check(employee); err != nil {
+		return models.Employee{}, fmt.Errorf("employee create failed: %w", err)
+	}
+	employee.ID = models.ID(r.idGenerator.NewID())
+
+	first := r.seq + 1
+	if err := r.append(hired(employee)); err != nil {
+		return models.Employee{}, err
+	}
+	if err := r.projection.ImportEmployee(employee); err != nil {
+		return models.Employee{}, err
+	}
+	r.record(first, respository.OpCreate, employee)
+	r.maybeSnapshot()
+	return employee, nil
+}
+
+func (r *EventSourcedEmployeeRepository) GetEmployeeByID(id models.ID) (models.Employee, error) {
+	return r.projection.GetEmployeeByID(id)
+}
+
+func (r *EventSourcedEmployeeRepository) UpdateEmployee(
+	id models.ID,
+	name string,
+	email string,
+	position string,
+	salary float64,
+) (models.Employee, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, err := r.projection.GetEmployeeByID(id)
+	if err != nil {
+		return current, err
+	}
+	employee := models.Employee{
+		ID:       id,
+		Name:     name,
+		Email:    email,
+		Position: position,
+		Salary:   salary,
+	}
+	if err := r.projection.Check(employee); err != nil {
+		return models.Employee{}, fmt.Errorf("employee with ID %s update failed: %w", id, err)
+	}
+
+	// Updates without changes append no event, and are not recorded either
+	first, events := r.seq+1, changes(current, employee)
+	if len(events) == 0 {
+		return current, nil
+	}
+	if err := r.append(events...); err != nil {
+		return models.Employee{}, err
+	}
+	employee, err = r.projection.UpdateEmployee(id, name, email, position, salary)
+	if err != nil {
+		return models.Employee{}, err
+	}
+	r.record(first, respository.OpUpdate, employee)
+	r.maybeSnapshot()
+	return employee, nil
+}
+
+func (r *EventSourcedEmployeeRepository) DeleteEmployee(id models.ID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	employee, err := r.projection.GetEmployeeByID(id)
+	if err != nil {
+		return err
+	}
+	first := r.seq + 1
+	if err := r.append(Event{Type: EmployeeTerminated, EmployeeID: id}); err != nil {
+		return err
+	}
+	err = r.projection.DeleteEmployee(id)
+	r.record(first, respository.OpDelete, employee)
+	r.maybeSnapshot()
+	return err
+}
+
+func (r *EventSourcedEmployeeRepository) GetAllEmployees(
+	page int,
+	limit int,
+) ([]models.Employee, int) {
+	return r.projection.GetAllEmployees(page, limit)
+}
+
+// Snapshot saves the current employees in the store, so that the next Open
+// only folds the events following them
+func (r *EventSourcedEmployeeRepository) Snapshot() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.snapshot()
+}
+
+// ReplayTo returns the employees as they were once the events up to the
+// sequence number were applied
+//
+// The employees are folded into a new repository, detached from this one.
+func (r *EventSourcedEmployeeRepository) ReplayTo(
+	seq uint64,
+) (*respository.EmployeeInMemoryRepository, error) {
+	return r.replay(func(eventSeq uint64, _ time.Time) bool { return eventSeq <= seq })
+}
+
+// ReplayUntil returns the employees as they were at the given time, once the
+// events up to it were applied
+//
+// The employees are folded into a new repository, detached from this one.
+func (r *EventSourcedEmployeeRepository) ReplayUntil(
+	t time.Time,
+) (*respository.EmployeeInMemoryRepository, error) {
+	return r.replay(func(_ uint64, eventTime time.Time) bool { return !eventTime.After(t) })
+}
+
+// GetByIDAsOf retrieves the employee as it was at the time, replayed from the log
+func (r *EventSourcedEmployeeRepository) GetByIDAsOf(
+	id models.ID,
+	asOf time.Time,
+) (models.Employee, error) {
+	past, err := r.replayAsOf(asOf)
+	if err != nil {
+		return models.Employee{}, err
+	}
+	return past.GetEmployeeByID(id)
+}
+
+// GetAllAsOf retrieves the employees as they were at the time, replayed from the log
+func (r *EventSourcedEmployeeRepository) GetAllAsOf(
+	asOf time.Time,
+	page int,
+	limit int,
+) ([]models.Employee, int, error) {
+	past, err := r.replayAsOf(asOf)
+	if err != nil {
+		return nil, 0, err
+	}
+	employees, total := past.GetAllEmployees(page, limit)
+	return employees, total, nil
+}
+
+// replayAsOf returns the employees replayed until the time, from the cache
+// when they were replayed recently, e.g. for the next page of a list
+func (r *EventSourcedEmployeeRepository) replayAsOf(
+	asOf time.Time,
+) (*respository.EmployeeInMemoryRepository, error) {
+	if past, ok := r.replays.get(asOf); ok {
+		return past, nil
+	}
+
+	r.mu.Lock()
+	lastTime := r.lastTime
+	r.mu.Unlock()
+	past, err := r.ReplayUntil(asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	// Events are never before the last one, those appended later cannot change the replay
+	if asOf.Before(lastTime) {
+		r.replays.add(asOf, past)
+	}
+	return past, nil
+}
+
+// get returns the employees replayed until the time, if cached
+func (c *replayCache) get(asOf time.Time) (*respository.EmployeeInMemoryRepository, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, entry := range c.entries {
+		if entry.asOf.Equal(asOf) {
+			c.entries = append(append(c.entries[:i:i], c.entries[i+1:]...), entry)
+			return entry.employees, true
+		}
+	}
+	return nil, false
+}
+
+// add caches the employees replayed until the time, evicting the least
+// recently used replay when full
+func (c *replayCache) add(asOf time.Time, employees *respository.EmployeeInMemoryRepository) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Concurrent misses replay the same time
+	for _, entry := range c.entries {
+		if entry.asOf.Equal(asOf) {
+			return
+		}
+	}
+	if len(c.entries) == replayCacheSize {
+		c.entries = c.entries[1:]
+	}
+	c.entries = append(c.entries, replayed{asOf: asOf, employees: employees})
+}
+
+// replay folds the events the predicate includes into a new repository,
+// starting from the snapshot when it includes it
+func (r *EventSourcedEmployeeRepository) replay(
+	includes func(seq uint64, t time.Time) bool,
+) (*respository.EmployeeInMemoryRepository, error) {
+	employees := respository.NewEmployeeInMemoryRepository()
+
+	snapshot, err := r.store.LoadSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	if snapshot.Seq > 0 && !includes(snapshot.Seq, snapshot.Time) {
+		snapshot = Snapshot{}
+	}
+
+	if _, err := fold(r.store, employees, snapshot, includes, nil); err != nil {
+		return nil, err
+	}
+	return employees, nil
+}
+
+// append numbers the events and appends them to the log
+//
+// The caller must hold the lock.
+func (r *EventSourcedEmployeeRepository) append(events ...Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	// Event times never go backwards, so that replays until a time stop at the right event
+	now := r.now()
+	if now.Before(r.lastTime) {
+		now = r.lastTime
+	}
+	for i := range events {
+		events[i].Seq = r.seq + uint64(i) + 1
+		events[i].WriteSeq = r.seq + 1
+		events[i].Time = now
+	}
+	if err := r.store.Append(events); err != nil {
+		return err
+	}
+
+	r.seq, r.lastTime = events[len(events)-1].Seq, now
+	if events[0].Type == EmployeeHired {
+		r.lastHired = events[0].EmployeeID
+	}
+	r.sinceSnapshot += len(events)
+	return nil
+}
+
+// record appends the write just appended from the event to the outbox, if any,
+// the caller must hold the lock
+func (r *EventSourcedEmployeeRepository) record(
+	first uint64,
+	op respository.WriteOp,
+	employee models.Employee,
+) {
+	if r.outbox != nil {
+		r.outbox.RecordAs(messageID(first, r.lastTime), op, employee)
+	}
+}
+
+// messageID returns the deduplication ID of the write starting at the event,
+// the same when the write is recovered from the log
+func messageID(seq uint64, t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 36) + "." + strconv.FormatUint(seq, 10)
+}
+
+// recoverWrite keeps the write of the event folded by Open, to be recorded once the
+// outbox is set
+//
+// The events of an update share the sequence number of the first one as
+// WriteSeq, they make up a single write. Events logged before the writes were
+// numbered are grouped by employee and time instead.
+func (r *EventSourcedEmployeeRepository) recoverWrite(event Event, employee models.Employee) {
+	op := respository.OpUpdate
+	switch event.Type {
+	case EmployeeHired:
+		op = respository.OpCreate
+	case EmployeeTerminated:
+		op = respository.OpDelete
+	}
+
+	if last := len(r.recovered) - 1; op == respository.OpUpdate && last >= 0 {
+		previous := &r.recovered[last]
+		same := event.WriteSeq != 0 && event.WriteSeq == previous.seq
+		if event.WriteSeq == 0 {
+			same = previous.employee.ID == employee.ID && previous.time.Equal(event.Time)
+		}
+		if previous.op == op && same {
+			previous.employee = employee
+			return
+		}
+	}
+	r.recovered = append(r.recovered, write{
+		seq:      event.Seq,
+		id:       messageID(event.Seq, event.Time),
+		op:       op,
+		employee: employee,
+		time:     event.Time,
+	})
+}
+
+// maybeSnapshot takes a snapshot when enough events were appended since the
+// last one, once the projection reflects them
+//
+// The caller must hold the lock.
+func (r *EventSourcedEmployeeRepository) maybeSnapshot() {
+	if r.snapshotEvery <= 0 || r.sinceSnapshot < r.snapshotEvery {
+		return
+	}
+
+	// A failed snapshot only slows down the next Open, the write succeeded
+	if err := r.snapshot(); err != nil {
+		r.logger.Error("event snapshot failed", "seq", r.seq, "error", err)
+	}
+}
+
+// snapshot saves the current employees in the store, the caller must hold the lock
+func (r *EventSourcedEmployeeRepository) snapshot() error {
+	employees, _ := r.projection.GetAllEmployees(1, 0)
+	snapshot := Snapshot{
+		Seq:       r.seq,
+		Time:      r.lastTime,
+		LastHired: r.lastHired,
+		Employees: employees,
+	}
+	if r.outbox != nil {
+		state := r.outbox.State()
+		snapshot.Outbox = &state
+	}
+	err := r.store.SaveSnapshot(snapshot)
+	if err != nil {
+		return err
+	}
+	r.sinceSnapshot = 0
+	return nil
+}
+
+// fold restores the snapshot into the empty employees, and applies the events
+// following it until the predicate excludes one, calling applied, when set,
+// with every event applied and the employee it returned
+//
+// It returns the snapshot of the state reached, without the employees.
+func fold(
+	store Store,
+	employees *respository.EmployeeInMemoryRepository,
+	snapshot Snapshot,
+	includes func(seq uint64, t time.Time) bool,
+	applied func(event Event, employee models.Employee),
+) (Snapshot, error) {
+	if err := employees.ReplaceEmployees(snapshot.Employees); err != nil {
+		return Snapshot{}, fmt.Errorf("event snapshot %d restore failed: %w", snapshot.Seq, err)
+	}
+
+	state := Snapshot{Seq: snapshot.Seq, Time: snapshot.Time, LastHired: snapshot.LastHired}
+	err := store.Read(snapshot.Seq, func(event Event) error {
+		if !includes(event.Seq, event.Time) {
+			return errStop
+		}
+		if event.Seq != state.Seq+1 {
+			return fmt.Errorf("event log has a gap after event %d", state.Seq)
+		}
+		employee, err := apply(employees, event)
+		if err != nil {
+			return fmt.Errorf("event %d apply failed: %w", event.Seq, err)
+		}
+		if applied != nil {
+			applied(event, employee)
+		}
+
+		state.Seq, state.Time = event.Seq, event.Time
+		if event.Type == EmployeeHired {
+			state.LastHired = event.EmployeeID
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStop) {
+		return Snapshot{}, err
+	}
+	return state, nil
+}
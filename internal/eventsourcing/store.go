@@ -0,0 +1,226 @@
+package eventsourcing
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/respository"
+)
+
+// Snapshot is the state of the employees once the events up to Seq were applied
+type Snapshot struct {
+	Seq       uint64            `json:"seq"`        // Last event applied, 0 for no snapshot
+	Time      time.Time         `json:"time"`       // Time of the last event applied
+	LastHired models.ID         `json:"last_hired"` // Latest hired employee, possibly terminated since
+	Employees []models.Employee `json:"employees"`  // In creation order
+	// Outbox messages not acknowledged yet, nil when no outbox was set
+	Outbox *respository.OutboxState[models.Employee] `json:"outbox,omitempty"`
+}
+
+// Store persists the event log and its latest snapshot
+//
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// Append adds the events at the end of the log, all of them or none
+	Append(events []Event) error
+	// Read calls fn with the events following the sequence number, in order,
+	// until fn returns an error
+	Read(after uint64, fn func(event Event) error) error
+	// SaveSnapshot replaces the latest snapshot
+	SaveSnapshot(snapshot Snapshot) error
+	// LoadSnapshot returns the latest snapshot, the zero snapshot when there is none
+	LoadSnapshot() (Snapshot, error)
+}
+
+// Ensure type implements the interface
+var _ Store = (*MemoryStore)(nil)
+var _ Store = (*FileStore)(nil)
+
+// MemoryStore is a Store keeping the log in memory, e.g. for tests
+type MemoryStore struct {
+	mu       sync.RWMutex
+	events   []Event
+	snapshot Snapshot
+}
+
+// NewMemoryStore creates a new empty store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+func (s *MemoryStore) Append(events []Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, events...)
+	return nil
+}
+
+func (s *MemoryStore) Read(after uint64, fn func(event Event) error) error {
+	s.mu.RLock()
+	events := slices.Clone(s.events)
+	s.mu.RUnlock()
+
+	for _, event := range events {
+		if event.Seq <= after {
+			continue
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) SaveSnapshot(snapshot Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshot = snapshot
+	return nil
+}
+
+func (s *MemoryStore) LoadSnapshot() (Snapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.snapshot, nil
+}
+
+// FileStore is a Store keeping the log in a file of JSON lines, synced on
+// every append, and the snapshot in another file
+type FileStore struct {
+	mu           sync.Mutex // Serializes the appends
+	log          *os.File
+	size         int64 // Bytes of the complete events, reads stop there
+	snapshotPath string
+}
+
+// OpenFileStore opens the store of the directory, creating its files if needed
+//
+// A last line left incomplete by a crash during an append is truncated, the
+// append it belonged to had failed.
+func OpenFileStore(dir string) (*FileStore, error) {
+	log, err := os.OpenFile(filepath.Join(dir, "events.jsonl"), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find the end of the last complete line
+	data, err := io.ReadAll(log)
+	if err != nil {
+		log.Close()
+		return nil, err
+	}
+	size := int64(bytes.LastIndexByte(data, '\n') + 1)
+	if size != int64(len(data)) {
+		if err := log.Truncate(size); err != nil {
+			log.Close()
+			return nil, err
+		}
+	}
+	if _, err := log.Seek(size, io.SeekStart); err != nil {
+		log.Close()
+		return nil, err
+	}
+
+	return &FileStore{
+		log:          log,
+		size:         size,
+		snapshotPath: filepath.Join(dir, "events.snapshot.json"),
+	}, nil
+}
+
+// Close closes the log
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.log.Close()
+}
+
+func (s *FileStore) Append(events []Event) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf) // Ends every event with a new line
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return fmt.Errorf("event log append failed: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Drop the events partially written, so that none of them is kept
+	_, err := s.log.Write(buf.Bytes())
+	if err == nil {
+		err = s.log.Sync()
+	}
+	if err != nil {
+		_ = s.log.Truncate(s.size)
+		_, _ = s.log.Seek(s.size, io.SeekStart)
+		return fmt.Errorf("event log append failed: %w", err)
+	}
+	s.size += int64(buf.Len())
+	return nil
+}
+
+func (s *FileStore) Read(after uint64, fn func(event Event) error) error {
+	s.mu.Lock()
+	size := s.size
+	s.mu.Unlock()
+
+	file, err := os.Open(s.log.Name())
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Appends in progress are not read
+	scanner := bufio.NewScanner(io.LimitReader(file, size))
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return fmt.Errorf("event log read failed: %w", err)
+		}
+		if event.Seq <= after {
+			continue
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func (s *FileStore) SaveSnapshot(snapshot Snapshot) error {
+	return respository.SaveSnapshotFile(s.snapshotPath, &snapshotFile{snapshot: &snapshot})
+}
+
+func (s *FileStore) LoadSnapshot() (Snapshot, error) {
+	var snapshot Snapshot
+	err := respository.LoadSnapshotFile(s.snapshotPath, &snapshotFile{snapshot: &snapshot})
+	return snapshot, err
+}
+
+// snapshotFile adapts a snapshot to the snapshot files of the repositories
+type snapshotFile struct {
+	snapshot *Snapshot
+}
+
+func (f *snapshotFile) Snapshot(w io.Writer) error {
+	return json.NewEncoder(w).Encode(f.snapshot)
+}
+
+func (f *snapshotFile) Restore(r io.Reader) error {
+	if err := json.NewDecoder(r).Decode(f.snapshot); err != nil {
+		return fmt.Errorf("event snapshot restore failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Draining fails once the server is shutting down, so that load balancers stop
+// routing requests to it while the in-flight ones drain
+func Draining(ready func() bool) Checker {
+	return CheckerFunc(func(context.Context) error {
+		if !ready() {
+			return errors.New("server is shutting down")
+		}
+		return nil
+	})
+}
+
+// DiskSpace fails when the file system holding the directory has less than
+// minFree bytes available, e.g. for the snapshots of the file backend
+func DiskSpace(dir string, minFree uint64) Checker {
+	return CheckerFunc(func(context.Context) error {
+		free, err := freeDiskSpace(dir)
+		if err != nil {
+			return fmt.Errorf("disk space of %s: %w", dir, err)
+		}
+		if free < minFree {
+			return fmt.Errorf("%d bytes free in %s, at least %d required", free, dir, minFree)
+		}
+		return nil
+	})
+}
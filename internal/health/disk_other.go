@@ -0,0 +1,10 @@
+//go:build !unix
+
+package health
+
+import "math"
+
+// freeDiskSpace is not supported on this platform, the disk space check always passes
+func freeDiskSpace(string) (uint64, error) {
+	return math.MaxUint64, nil
+}
@@ -0,0 +1,173 @@
+// Package health provides the liveness and readiness probes
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Status of a check or of the whole report
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Checker checks that a dependency of the application is usable
+//
+// Check must return once the context is done.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to the Checker interface
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// CheckResult is the outcome of one check
+type CheckResult struct {
+	Status    string  `json:"status"`
+	Error     string  `json:"error,omitempty"`
+	LatencyMS float64 `json:"latency_ms"`
+}
+
+// Report is the outcome of every check, the status fails if any check fails
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// namedChecker is a registered checker
+type namedChecker struct {
+	name    string
+	checker Checker
+}
+
+// Registry holds the checkers of the readiness probe
+//
+// Backends and other dependencies register their own checkers. It is safe for
+// concurrent use.
+type Registry struct {
+	mu       sync.RWMutex
+	checkers []namedChecker
+	timeout  time.Duration // Deadline of each check
+}
+
+// NewRegistry creates an empty registry whose checks fail after the timeout
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{timeout: timeout}
+}
+
+// Register adds a checker, names must be unique
+func (r *Registry) Register(name string, checker Checker) {
+	r.mu.Lock()         // Lock the mutex
+	defer r.mu.Unlock() // Unlock the mutex when the function returns
+
+	for _, c := range r.checkers {
+		if c.name == name {
+			panic(fmt.Sprintf("health: checker %q registered twice", name))
+		}
+	}
+	r.checkers = append(r.checkers, namedChecker{name: name, checker: checker})
+}
+
+// Check runs every checker concurrently, each within the timeout
+func (r *Registry) Check(ctx context.Context) Report {
+	r.mu.RLock()
+	checkers := append([]namedChecker(nil), r.checkers...)
+	r.mu.RUnlock()
+
+	results := make([]CheckResult, len(checkers))
+	var wg sync.WaitGroup
+	for i, c := range checkers {
+		wg.Add(1)
+		go func(i int, c namedChecker) {
+			defer wg.Done()
+			results[i] = r.run(ctx, c.checker)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(checkers))}
+	for i, c := range checkers {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+// run runs one checker within the timeout, a checker that does not return in
+// time fails even if it ignores the context
+func (r *Registry) run(ctx context.Context, checker Checker) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- checker.Check(ctx) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("check timed out after %s", r.timeout)
+	}
+
+	result := CheckResult{
+		Status:    StatusOK,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status, result.Error = StatusFail, err.Error()
+	}
+	return result
+}
+
+// Live answers the liveness probe, it succeeds as long as the process serves requests
+//
+// GET /healthz/live
+func Live(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]string{"status": StatusOK})
+}
+
+// Ready answers the readiness probe, 503 Service Unavailable when any check
+// fails, with the status of each check
+//
+// The errors of the checks are left out, as they can reveal e.g. paths, serve
+// them with Details on a private listener.
+//
+// GET /healthz/ready
+func (r *Registry) Ready(c echo.Context) error {
+	return r.serve(c, false)
+}
+
+// Details answers like Ready, with the errors of the failed checks
+//
+// GET /healthz/ready
+func (r *Registry) Details(c echo.Context) error {
+	return r.serve(c, true)
+}
+
+// serve runs the checks and answers with the report, the errors are only kept when detailed
+func (r *Registry) serve(c echo.Context, detailed bool) error {
+	report := r.Check(c.Request().Context())
+	if !detailed {
+		for name, result := range report.Checks {
+			result.Error = ""
+			report.Checks[name] = result
+		}
+	}
+	if report.Status != StatusOK {
+		return c.JSON(http.StatusServiceUnavailable, report)
+	}
+	return c.JSON(http.StatusOK, report)
+}
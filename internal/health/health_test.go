@@ -0,0 +1,108 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// serve calls the handler and decodes the JSON response
+func serve(t *testing.T, handler echo.HandlerFunc) (int, Report) {
+	app := echo.New()
+	rec := httptest.NewRecorder()
+	c := app.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+	assert.Nil(t, handler(c), "error should be nil")
+
+	var report Report
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	return rec.Code, report
+}
+
+func TestRegistry_Ready(t *testing.T) {
+	ready := true
+	registry := NewRegistry(50 * time.Millisecond)
+	registry.Register("draining", Draining(func() bool { return ready }))
+	registry.Register("repository", CheckerFunc(func(context.Context) error { return nil }))
+
+	// Every check passes
+	code, report := serve(t, registry.Ready)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, StatusOK, report.Status)
+	assert.Len(t, report.Checks, 2, "every check should be reported")
+	assert.Equal(t, StatusOK, report.Checks["repository"].Status)
+
+	// A failing check fails readiness and is reported
+	ready = false
+	code, report = serve(t, registry.Ready)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Equal(t, StatusFail, report.Checks["draining"].Status)
+	assert.Empty(t, report.Checks["draining"].Error, "errors should not be public")
+	assert.Equal(t, StatusOK, report.Checks["repository"].Status, "other checks should pass")
+
+	// The details report the errors
+	code, report = serve(t, registry.Details)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "server is shutting down", report.Checks["draining"].Error)
+}
+
+func TestRegistry_Timeout(t *testing.T) {
+	registry := NewRegistry(20 * time.Millisecond)
+
+	// A checker ignoring its context still fails in time
+	block := make(chan struct{})
+	defer close(block)
+	registry.Register("stuck", CheckerFunc(func(context.Context) error {
+		<-block
+		return nil
+	}))
+	registry.Register("slow", CheckerFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+
+	start := time.Now()
+	report := registry.Check(context.Background())
+	assert.Less(t, time.Since(start), time.Second, "checks should run concurrently and time out")
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Contains(t, report.Checks["stuck"].Error, "timed out")
+	assert.GreaterOrEqual(t, report.Checks["stuck"].LatencyMS, 20.0, "latency should be measured")
+	assert.Equal(t, StatusFail, report.Checks["slow"].Status)
+}
+
+func TestRegistry_RegisterTwice(t *testing.T) {
+	registry := NewRegistry(time.Second)
+	registry.Register("disk", DiskSpace(t.TempDir(), 0))
+	assert.Panics(t, func() {
+		registry.Register("disk", CheckerFunc(func(context.Context) error { return nil }))
+	}, "duplicate names should be rejected")
+}
+
+func TestDiskSpace(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, DiskSpace(dir, 1).Check(context.Background()), "error should be nil")
+
+	err := DiskSpace(dir, math.MaxUint64).Check(context.Background())
+	assert.NotNil(t, err, "error should not be nil")
+	assert.Contains(t, err.Error(), "required")
+
+	err = DiskSpace(dir+"/missing", 1).Check(context.Background())
+	assert.NotNil(t, err, "error should not be nil for a missing directory")
+	assert.False(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestLive(t *testing.T) {
+	code, report := serve(t, Live)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, StatusOK, report.Status)
+}
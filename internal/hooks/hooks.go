@@ -0,0 +1,143 @@
+// Package hooks runs side effects and business rules around the employee writes
+package hooks
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/respository"
+)
+
+// Op is the kind of write a hook runs around
+type Op string
+
+// Write operations
+const (
+	OpCreate Op = "create"
+	OpUpdate Op = "update"
+	OpDelete Op = "delete"
+)
+
+// Change is the write a hook runs around
+type Change struct {
+	Op       Op
+	Current  models.Employee // Employee the write replaces, zero on create
+	Employee models.Employee // Employee written, or deleted
+}
+
+// BeforeHook runs before a write, it can mutate the employee written (changes
+// are ignored on delete) or veto the write by returning an error
+//
+// Vetoes should be a *respository.RuleError, other errors fail the request.
+type BeforeHook func(ctx context.Context, change *Change) error
+
+// AfterHook observes a committed write, Employee is the employee as stored
+type AfterHook func(ctx context.Context, change Change)
+
+// hook is a registered hook
+type hook[H any] struct {
+	name     string
+	priority int
+	fn       H
+}
+
+// Registry holds the hooks run around the writes of the repositories it wraps
+//
+// Hooks run by ascending priority, hooks of the same priority in the order
+// they were registered. The first before-hook vetoing a write stops the others.
+type Registry struct {
+	mu     sync.RWMutex
+	before map[Op][]hook[BeforeHook]
+	after  map[Op][]hook[AfterHook]
+}
+
+// NewRegistry creates a new registry without hooks
+func NewRegistry() *Registry {
+	return &Registry{
+		before: make(map[Op][]hook[BeforeHook]),
+		after:  make(map[Op][]hook[AfterHook]),
+	}
+}
+
+// Before registers a hook run before the given writes, or all of them when none is given
+func (r *Registry) Before(name string, priority int, fn BeforeHook, ops ...Op) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, op := range opsOrAll(ops) {
+		r.before[op] = insert(r.before[op], hook[BeforeHook]{name, priority, fn})
+	}
+}
+
+// After registers a hook run after the given writes, or all of them when none is given
+func (r *Registry) After(name string, priority int, fn AfterHook, ops ...Op) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, op := range opsOrAll(ops) {
+		r.after[op] = insert(r.after[op], hook[AfterHook]{name, priority, fn})
+	}
+}
+
+// runBefore runs the before-hooks of the change until one vetoes it
+//
+// Rule errors without a rule name are named after the hook.
+func (r *Registry) runBefore(ctx context.Context, change *Change) error {
+	r.mu.RLock()
+	hooks := r.before[change.Op]
+	r.mu.RUnlock()
+
+	for _, h := range hooks {
+		if err := h.fn(ctx, change); err != nil {
+			// Name a copy, the hook may return a shared error
+			var ruleErr *respository.RuleError
+			if errors.As(err, &ruleErr) && ruleErr.Rule == "" {
+				named := *ruleErr
+				named.Rule = h.name
+				return &named
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// runAfter runs the after-hooks of the change
+func (r *Registry) runAfter(ctx context.Context, change Change) {
+	r.mu.RLock()
+	hooks := r.after[change.Op]
+	r.mu.RUnlock()
+
+	for _, h := range hooks {
+		h.fn(ctx, change)
+	}
+}
+
+// insert adds the hook after the hooks of lower or equal priority
+//
+// The slice is copied so that the hooks being run are never modified.
+func insert[H any](hooks []hook[H], h hook[H]) []hook[H] {
+	i := len(hooks)
+	for i > 0 && hooks[i-1].priority > h.priority {
+		i--
+	}
+	return slices.Insert(slices.Clone(hooks), i, h)
+}
+
+// opsOrAll returns the operations, or every operation when none is given
+func opsOrAll(ops []Op) []Op {
+	if len(ops) == 0 {
+		return []Op{OpCreate, OpUpdate, OpDelete}
+	}
+	return ops
+}
+
+// NormalizeNames is a before-hook trimming the name and position of the
+// employees and collapsing their inner spaces
+func NormalizeNames(_ context.Context, change *Change) error {
+	change.Employee.Name = strings.Join(strings.Fields(change.Employee.Name), " ")
+	change.Employee.Position = strings.Join(strings.Fields(change.Employee.Position), " ")
+	return nil
+}
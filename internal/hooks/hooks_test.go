@@ -0,0 +1,217 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/respository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry_Order(t *testing.T) {
+	registry := NewRegistry()
+	var order []string
+	record := func(name string) BeforeHook {
+		return func(context.Context, *Change) error {
+			order = append(order, name)
+			return nil
+		}
+	}
+	registry.Before("late", 10, record("late"))
+	registry.Before("first", -1, record("first"))
+	registry.Before("default-1", 0, record("default-1"))
+	registry.Before("default-2", 0, record("default-2"))
+	registry.Before("delete-only", 0, record("delete-only"), OpDelete)
+
+	repo := registry.Wrap(respository.NewEmployeeInMemoryRepository())
+	_, err := repo.CreateEmployee("Harshit", "harshit@example.com", "Engineer", 1000)
+	assert.Nil(t, err)
+	assert.Equal(
+		t,
+		[]string{"first", "default-1", "default-2", "late"},
+		order,
+		"hooks should run by priority, then in registration order",
+	)
+}
+
+func TestHookedEmployeeRepository_Mutate(t *testing.T) {
+	registry := NewRegistry()
+	registry.Before("normalize-names", 0, NormalizeNames)
+
+	var observed []Change
+	registry.After("audit", 0, func(_ context.Context, change Change) {
+		observed = append(observed, change)
+	})
+
+	repo := registry.Wrap(respository.NewEmployeeInMemoryRepository())
+	employee, err := repo.CreateEmployee(
+		"  Harshit   Kumar ",
+		"harshit@example.com",
+		"Engineer",
+		1000,
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, "Harshit Kumar", employee.Name, "name should be normalized")
+
+	_, err = repo.UpdateEmployee(
+		employee.ID,
+		"Harshit Kumar",
+		"harshit@example.com",
+		" Lead ",
+		2000,
+	)
+	assert.Nil(t, err)
+	assert.Nil(t, repo.DeleteEmployee(employee.ID))
+
+	// After-hooks observe the committed writes
+	if assert.Len(t, observed, 3) {
+		assert.Equal(t, OpCreate, observed[0].Op)
+		assert.Equal(t, employee.ID, observed[0].Employee.ID, "created employee should have its ID")
+		assert.Equal(t, "Engineer", observed[1].Current.Position)
+		assert.Equal(t, "Lead", observed[1].Employee.Position)
+		assert.Equal(t, OpDelete, observed[2].Op)
+		assert.Equal(
+			t,
+			"Lead",
+			observed[2].Employee.Position,
+			"deletion should carry the last state",
+		)
+	}
+}
+
+func TestHookedEmployeeRepository_Veto(t *testing.T) {
+	registry := NewRegistry()
+	registry.Before("no-pay-cuts", 0, func(_ context.Context, change *Change) error {
+		if change.Employee.Salary < change.Current.Salary {
+			return &respository.RuleError{Field: "salary", Message: "must not decrease"}
+		}
+		return nil
+	}, OpUpdate)
+	registry.Before("keep-founders", 0, func(_ context.Context, change *Change) error {
+		if change.Current.Position == "Founder" {
+			return &respository.RuleError{Rule: "founders", Message: "founders can not be deleted"}
+		}
+		return nil
+	}, OpDelete)
+
+	after := 0
+	registry.After("count", 0, func(context.Context, Change) { after++ })
+
+	inner := respository.NewEmployeeInMemoryRepository()
+	repo := registry.Wrap(inner)
+	employee, _ := repo.CreateEmployee("Harshit", "harshit@example.com", "Founder", 1000)
+
+	_, err := repo.UpdateEmployee(employee.ID, "Harshit", "harshit@example.com", "Founder", 500)
+	assert.ErrorIs(t, err, respository.ErrRuleViolation)
+	var ruleErr *respository.RuleError
+	if assert.True(t, errors.As(err, &ruleErr)) {
+		assert.Equal(t, "no-pay-cuts", ruleErr.Rule, "rule should be named after the hook")
+		assert.Equal(t, "salary", ruleErr.Field)
+	}
+
+	err = repo.DeleteEmployee(employee.ID)
+	assert.ErrorIs(t, err, respository.ErrRuleViolation)
+	assert.True(t, errors.As(err, &ruleErr))
+	assert.Equal(t, "founders", ruleErr.Rule, "rule name should be kept")
+
+	// Vetoed writes are not applied nor observed
+	stored, _ := inner.GetEmployeeByID(employee.ID)
+	assert.Equal(t, 1000.0, stored.Salary)
+	assert.Equal(t, 1, after, "only the create should be observed")
+
+	// Writes of missing employees fail before the hooks
+	_, err = repo.UpdateEmployee(models.ID("42"), "Harshit", "harshit@example.com", "Founder", 1)
+	assert.ErrorIs(t, err, respository.ErrRecordNotFound)
+}
+
+func TestHookedEmployeeRepository_SerializedWrites(t *testing.T) {
+	// Raises are steps of 100, a rule that concurrent writes must not bypass
+	registry := NewRegistry()
+	errStep := &respository.RuleError{Field: "salary", Message: "raises are steps of 100"}
+	registry.Before("raise-step", 0, func(_ context.Context, change *Change) error {
+		time.Sleep(10 * time.Millisecond) // Let the other update catch up
+		if change.Employee.Salary != change.Current.Salary+100 {
+			return errStep
+		}
+		return nil
+	}, OpUpdate)
+
+	repo := registry.Wrap(respository.NewEmployeeInMemoryRepository())
+	employee, _ := repo.CreateEmployee("Harshit", "harshit@example.com", "Engineer", 1000)
+
+	// Two concurrent raises to 1100, the second one to go sees the first one
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			bound := repo.WithContext(context.Background())
+			_, err := bound.UpdateEmployee(
+				employee.ID,
+				"Harshit",
+				"harshit@example.com",
+				"Engineer",
+				1100,
+			)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	var vetoed []error
+	for err := range errs {
+		if err != nil {
+			vetoed = append(vetoed, err)
+		}
+	}
+	if assert.Len(t, vetoed, 1, "one of the raises should be vetoed") {
+		assert.ErrorIs(t, vetoed[0], respository.ErrRuleViolation)
+
+		// The error returned by the hook is named on a copy
+		var ruleErr *respository.RuleError
+		assert.True(t, errors.As(vetoed[0], &ruleErr))
+		assert.Equal(t, "raise-step", ruleErr.Rule)
+		assert.Empty(t, errStep.Rule, "shared errors should not be modified")
+	}
+	stored, _ := repo.GetEmployeeByID(employee.ID)
+	assert.Equal(t, 1100.0, stored.Salary)
+}
+
+func TestHookedEmployeeRepository_ConcurrentRecords(t *testing.T) {
+	// The hook of the first employee blocks until the second employee is written
+	registry := NewRegistry()
+	entered, release := make(chan struct{}), make(chan struct{})
+	registry.Before("block-first", 0, func(_ context.Context, change *Change) error {
+		if change.Current.Name == "Harshit" {
+			entered <- struct{}{}
+			<-release
+		}
+		return nil
+	}, OpUpdate)
+
+	repo := registry.Wrap(respository.NewEmployeeInMemoryRepository())
+	first, _ := repo.CreateEmployee("Harshit", "harshit@example.com", "Engineer", 1000)
+	second, _ := repo.CreateEmployee("Ganesh", "ganesh@example.com", "Engineer", 1000)
+
+	done := make(chan error)
+	go func() {
+		_, err := repo.UpdateEmployee(first.ID, "Harshit", "harshit@example.com", "Lead", 1000)
+		done <- err
+	}()
+	<-entered
+
+	// Writes of other employees do not wait for the hooks
+	_, err := repo.UpdateEmployee(second.ID, "Ganesh", "ganesh@example.com", "Lead", 1000)
+	assert.Nil(t, err)
+	close(release)
+	assert.Nil(t, <-done)
+
+	stored, _ := repo.GetEmployeeByID(first.ID)
+	assert.Equal(t, "Lead", stored.Position)
+}
@@ -0,0 +1,236 @@
+package hooks
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/respository"
+)
+
+// Ensure type implements the interface
+var _ respository.IEmployeeRepository = (*HookedEmployeeRepository)(nil)
+
+var _ respository.ContextBinder[respository.IEmployeeRepository] = (*HookedEmployeeRepository)(
+	nil,
+)
+
+var _ respository.HistoryReader[models.Employee] = (*HookedEmployeeRepository)(nil)
+
+// HookedEmployeeRepository is an IEmployeeRepository decorator running the
+// hooks of the registry around every write
+//
+// Before-hooks of updates and deletes get the employee the write replaces, as
+// long as every write goes through the decorator: the writes of an employee are
+// serialized from a last read of the employee to the end of the write, and the
+// hooks run again when the employee changed since they ran. No hook runs while
+// an employee is locked, and writes of different employees run concurrently.
+// Before-hooks must not write the employee of the change through the decorator.
+// After-hooks only run once the write succeeded.
+type HookedEmployeeRepository struct {
+	next     respository.IEmployeeRepository
+	registry *Registry
+	ctx      context.Context // Context passed to the hooks
+	locks    *recordLocks    // Locks of the employees written, shared with the context bound copies
+}
+
+// Wrap wraps the repository with the hooks of the registry
+func (r *Registry) Wrap(next respository.IEmployeeRepository) *HookedEmployeeRepository {
+	return &HookedEmployeeRepository{
+		next:     next,
+		registry: r,
+		ctx:      context.Background(),
+		locks:    &recordLocks{locks: make(map[models.ID]*recordLock)},
+	}
+}
+
+// WithContext binds the wrapped repository, and the hooks, to the context
+func (r *HookedEmployeeRepository) WithContext(
+	ctx context.Context,
+) respository.IEmployeeRepository {
+	return &HookedEmployeeRepository{
+		next:     respository.BindContext(ctx, r.next),
+		registry: r.registry,
+		ctx:      ctx,
+		locks:    r.locks,
+	}
+}
+
+func (r *HookedEmployeeRepository) CreateEmployee(
+	name string,
+	email string,
+	position string,
+	salary float64,
+) (models.Employee, error) {
+	change := Change{
+		Op:       OpCreate,
+		Employee: models.Employee{Name: name, Email: email, Position: position, Salary: salary},
+	}
+	if err := r.registry.runBefore(r.ctx, &change); err != nil {
+		return models.Employee{}, err
+	}
+	employee, err := r.next.CreateEmployee(
+		change.Employee.Name,
+		change.Employee.Email,
+		change.Employee.Position,
+		change.Employee.Salary,
+	)
+	if err != nil {
+		return employee, err
+	}
+
+	change.Employee = employee
+	r.registry.runAfter(r.ctx, change)
+	return employee, nil
+}
+
+func (r *HookedEmployeeRepository) GetEmployeeByID(id models.ID) (models.Employee, error) {
+	return r.next.GetEmployeeByID(id)
+}
+
+func (r *HookedEmployeeRepository) UpdateEmployee(
+	id models.ID,
+	name string,
+	email string,
+	position string,
+	salary float64,
+) (models.Employee, error) {
+	change := Change{
+		Op: OpUpdate,
+		Employee: models.Employee{
+			ID:       id,
+			Name:     name,
+			Email:    email,
+			Position: position,
+			Salary:   salary,
+		},
+	}
+	change, employee, err := r.write(change, func(change Change) (models.Employee, error) {
+		return r.next.UpdateEmployee(
+			id,
+			change.Employee.Name,
+			change.Employee.Email,
+			change.Employee.Position,
+			change.Employee.Salary,
+		)
+	})
+	if err != nil {
+		return employee, err
+	}
+
+	change.Employee = employee
+	r.registry.runAfter(r.ctx, change)
+	return employee, nil
+}
+
+func (r *HookedEmployeeRepository) DeleteEmployee(id models.ID) error {
+	change := Change{Op: OpDelete, Employee: models.Employee{ID: id}}
+	change, current, err := r.write(change, func(change Change) (models.Employee, error) {
+		return change.Current, r.next.DeleteEmployee(id)
+	})
+	if err != nil {
+		return err
+	}
+
+	change.Employee = current
+	r.registry.runAfter(r.ctx, change)
+	return nil
+}
+
+// write runs the before-hooks of the update or delete on the current employee,
+// then the write with the employee locked, and returns the change as written
+//
+// The hooks run on a copy of the change outside of the lock. When the employee
+// changed meanwhile, it is not written and the hooks run again on a new copy.
+func (r *HookedEmployeeRepository) write(
+	change Change,
+	fn func(change Change) (models.Employee, error),
+) (Change, models.Employee, error) {
+	id := change.Employee.ID
+	for {
+		current, err := r.next.GetEmployeeByID(id)
+		if err != nil {
+			return change, models.Employee{}, err
+		}
+		attempt := change
+		attempt.Current = current
+		if attempt.Op == OpDelete {
+			attempt.Employee = current
+		}
+		if err := r.registry.runBefore(r.ctx, &attempt); err != nil {
+			return attempt, models.Employee{}, err
+		}
+
+		unlock := r.locks.lock(id)
+		latest, err := r.next.GetEmployeeByID(id)
+		if err == nil && latest == current {
+			employee, err := fn(attempt)
+			unlock()
+			return attempt, employee, err
+		}
+		unlock()
+		if err != nil {
+			return attempt, models.Employee{}, err // E.g. deleted meanwhile
+		}
+	}
+}
+
+func (r *HookedEmployeeRepository) GetAllEmployees(
+	page int,
+	limit int,
+) ([]models.Employee, int) {
+	return r.next.GetAllEmployees(page, limit)
+}
+
+// GetByIDAsOf reads the past version through the wrapped repository, reads run no hook
+func (r *HookedEmployeeRepository) GetByIDAsOf(
+	id models.ID,
+	asOf time.Time,
+) (models.Employee, error) {
+	return respository.HistoryOf[models.Employee](r.next).GetByIDAsOf(id, asOf)
+}
+
+// GetAllAsOf reads the past versions through the wrapped repository, reads run no hook
+func (r *HookedEmployeeRepository) GetAllAsOf(
+	asOf time.Time,
+	page int,
+	limit int,
+) ([]models.Employee, int, error) {
+	return respository.HistoryOf[models.Employee](r.next).GetAllAsOf(asOf, page, limit)
+}
+
+// recordLocks are the locks of the employees being written
+type recordLocks struct {
+	mu    sync.Mutex
+	locks map[models.ID]*recordLock // Locks held or waited for, by employee ID
+}
+
+// recordLock is the lock of an employee, dropped once no write needs it
+type recordLock struct {
+	mu   sync.Mutex
+	refs int // Writes holding or waiting for the lock
+}
+
+// lock locks the employee with the ID and returns the function unlocking it
+func (l *recordLocks) lock(id models.ID) func() {
+	l.mu.Lock()
+	lock, ok := l.locks[id]
+	if !ok {
+		lock = &recordLock{}
+		l.locks[id] = lock
+	}
+	lock.refs++
+	l.mu.Unlock()
+
+	lock.mu.Lock()
+	return func() {
+		lock.mu.Unlock()
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if lock.refs--; lock.refs == 0 {
+			delete(l.locks, id)
+		}
+	}
+}
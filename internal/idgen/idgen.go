@@ -0,0 +1,46 @@
+// Package idgen provides the strategies used to generate entity IDs
+package idgen
+
+import (
+	"fmt"
+)
+
+// IDGenerator generates unique IDs for new entities
+//
+// Implementations must be safe for concurrent use.
+type IDGenerator interface {
+	NewID() string
+}
+
+// Observer is implemented by generators that must skip IDs generated by a
+// previous run, e.g. when entities are restored from a snapshot
+type Observer interface {
+	Observe(id string)
+}
+
+// Supported ID generation strategies
+const (
+	StrategySequential = "sequential"
+	StrategyUUIDv7     = "uuidv7"
+	StrategyULID       = "ulid"
+	StrategySnowflake  = "snowflake"
+)
+
+// New creates the ID generator for the named strategy
+//
+// The node is only used by the snowflake strategy and must be unique per
+// running instance.
+func New(strategy string, node int64) (IDGenerator, error) {
+	switch strategy {
+	case StrategySequential:
+		return NewSequential(), nil
+	case StrategyUUIDv7:
+		return NewUUIDv7(), nil
+	case StrategyULID:
+		return NewULID(), nil
+	case StrategySnowflake:
+		return NewSnowflake(node)
+	default:
+		return nil, fmt.Errorf("unknown ID generation strategy %q", strategy)
+	}
+}
@@ -0,0 +1,88 @@
+package idgen
+
+import (
+	"regexp"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	for _, strategy := range []string{StrategySequential, StrategyUUIDv7, StrategyULID, StrategySnowflake} {
+		gen, err := New(strategy, 1)
+		assert.Nil(t, err, "error should be nil for %s", strategy)
+		assert.NotNil(t, gen, "generator should not be nil for %s", strategy)
+	}
+
+	_, err := New("random", 0)
+	assert.NotNil(t, err, "error should not be nil for an unknown strategy")
+
+	_, err = New(StrategySnowflake, 1024)
+	assert.NotNil(t, err, "error should not be nil for an out of range node")
+}
+
+func TestSequential(t *testing.T) {
+	gen := NewSequential()
+	assert.Equal(t, "1", gen.NewID(), "first ID should be 1")
+	assert.Equal(t, "2", gen.NewID(), "second ID should be 2")
+
+	// Observed IDs are skipped, smaller and non-numeric ones are ignored
+	gen.Observe("10")
+	gen.Observe("5")
+	gen.Observe("abc")
+	assert.Equal(t, int64(11), gen.Peek(), "next ID should be 11")
+	assert.Equal(t, "11", gen.NewID(), "ID after observing 10 should be 11")
+}
+
+func TestGenerators_UniqueAndOrdered(t *testing.T) {
+	snowflake, _ := NewSnowflake(7)
+	generators := map[string]struct {
+		gen     IDGenerator
+		pattern *regexp.Regexp
+	}{
+		StrategyUUIDv7: {
+			gen:     NewUUIDv7(),
+			pattern: regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`),
+		},
+		StrategyULID: {
+			gen:     NewULID(),
+			pattern: regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`),
+		},
+		StrategySnowflake: {
+			gen:     snowflake,
+			pattern: regexp.MustCompile(`^[1-9][0-9]*$`),
+		},
+	}
+
+	for name, tc := range generators {
+		t.Run(name, func(t *testing.T) {
+			seen := map[string]bool{}
+			ids := make([]string, 0, 5000)
+			for i := 0; i < 5000; i++ {
+				id := tc.gen.NewID()
+				assert.Regexp(t, tc.pattern, id, "ID should match the %s format", name)
+				assert.False(t, seen[id], "ID %s should be unique", id)
+				seen[id] = true
+				ids = append(ids, id)
+			}
+
+			// ULIDs are monotonic, snowflakes are equal-length decimals here
+			if name != StrategyUUIDv7 {
+				assert.True(t, sort.StringsAreSorted(ids), "IDs should be sorted")
+			}
+		})
+	}
+}
+
+func TestULID_Encoding(t *testing.T) {
+	gen := NewULID()
+	gen.now = func() time.Time { return time.UnixMilli(0) }
+	gen.lastMs = 0
+	gen.lastRnd = [10]byte{}
+
+	// Same millisecond as the last ID, so the random part is incremented
+	assert.Equal(t, "00000000000000000000000001", gen.NewID())
+	assert.Equal(t, "00000000000000000000000002", gen.NewID())
+}
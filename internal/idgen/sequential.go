@@ -0,0 +1,46 @@
+package idgen
+
+import (
+	"strconv"
+	"sync/atomic"
+)
+
+// Ensure type implements the interface
+var _ IDGenerator = (*Sequential)(nil)
+var _ Observer = (*Sequential)(nil)
+
+// Sequential generates increasing integer IDs starting at 1
+//
+// This is the historical behaviour of the in-memory store. The IDs are only
+// unique within a single instance.
+type Sequential struct {
+	next atomic.Int64 // Next available ID minus one
+}
+
+func NewSequential() *Sequential {
+	return &Sequential{}
+}
+
+func (s *Sequential) NewID() string {
+	return strconv.FormatInt(s.next.Add(1), 10)
+}
+
+// Observe makes sure the next IDs are greater than the observed ID,
+// non-numeric IDs are ignored
+func (s *Sequential) Observe(id string) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return
+	}
+	for {
+		current := s.next.Load()
+		if n <= current || s.next.CompareAndSwap(current, n) {
+			return
+		}
+	}
+}
+
+// Peek returns the next ID that will be generated, without consuming it
+func (s *Sequential) Peek() int64 {
+	return s.next.Load() + 1
+}
@@ -0,0 +1,69 @@
+package idgen
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Snowflake layout: 41 bits of milliseconds since the epoch, 10 bits of node
+// and 12 bits of per-millisecond sequence
+const (
+	snowflakeNodeBits     = 10
+	snowflakeSequenceBits = 12
+	snowflakeMaxNode      = 1<<snowflakeNodeBits - 1
+	snowflakeMaxSequence  = 1<<snowflakeSequenceBits - 1
+)
+
+// SnowflakeEpoch is the custom epoch of snowflake IDs (2024-01-01T00:00:00Z)
+var SnowflakeEpoch = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Ensure type implements the interface
+var _ IDGenerator = (*Snowflake)(nil)
+
+// Snowflake generates roughly time-ordered 63-bit integer IDs that are unique
+// across up to 1024 nodes, as popularized by Twitter
+type Snowflake struct {
+	mu       sync.Mutex
+	now      func() time.Time
+	node     int64
+	lastMs   int64
+	sequence int64
+}
+
+// NewSnowflake creates a snowflake generator for the node (0 to 1023)
+func NewSnowflake(node int64) (*Snowflake, error) {
+	if node < 0 || node > snowflakeMaxNode {
+		return nil, fmt.Errorf("snowflake node must be between 0 and %d, got %d", snowflakeMaxNode, node)
+	}
+	return &Snowflake{now: time.Now, node: node}, nil
+}
+
+func (g *Snowflake) NewID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := g.now().Sub(SnowflakeEpoch).Milliseconds()
+	if ms < g.lastMs {
+		// The clock went backwards, stay on the last known millisecond
+		ms = g.lastMs
+	}
+
+	if ms == g.lastMs {
+		g.sequence = (g.sequence + 1) & snowflakeMaxSequence
+		if g.sequence == 0 {
+			// Sequence exhausted for this millisecond, borrow the next one
+			ms++
+		}
+	} else {
+		g.sequence = 0
+	}
+	g.lastMs = ms
+
+	id := ms<<(snowflakeNodeBits+snowflakeSequenceBits) |
+		g.node<<snowflakeSequenceBits |
+		g.sequence
+
+	return strconv.FormatInt(id, 10)
+}
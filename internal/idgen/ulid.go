@@ -0,0 +1,83 @@
+package idgen
+
+import (
+	"crypto/rand"
+	"sync"
+	"time"
+)
+
+// crockfordBase32 is the alphabet used to encode ULIDs
+const crockfordBase32 = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// Ensure type implements the interface
+var _ IDGenerator = (*ULID)(nil)
+
+// ULID generates lexicographically sortable identifiers as defined by
+// https://github.com/ulid/spec
+//
+// IDs generated within the same millisecond are monotonic: the random part of
+// the previous ID is incremented instead of drawing new random bits.
+type ULID struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	lastMs  uint64
+	lastRnd [10]byte
+}
+
+func NewULID() *ULID {
+	return &ULID{now: time.Now}
+}
+
+func (g *ULID) NewID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := uint64(g.now().UnixMilli())
+	if ms > g.lastMs {
+		g.lastMs = ms
+		if _, err := rand.Read(g.lastRnd[:]); err != nil {
+			panic("idgen: failed to read random bytes: " + err.Error())
+		}
+	} else {
+		// Same (or earlier) millisecond, keep the IDs monotonic
+		for i := len(g.lastRnd) - 1; i >= 0; i-- {
+			g.lastRnd[i]++
+			if g.lastRnd[i] != 0 {
+				break
+			}
+		}
+	}
+
+	var id [16]byte
+	id[0] = byte(g.lastMs >> 40)
+	id[1] = byte(g.lastMs >> 32)
+	id[2] = byte(g.lastMs >> 24)
+	id[3] = byte(g.lastMs >> 16)
+	id[4] = byte(g.lastMs >> 8)
+	id[5] = byte(g.lastMs)
+	copy(id[6:], g.lastRnd[:])
+
+	return encodeULID(id)
+}
+
+// encodeULID encodes the 128-bit ULID as 26 Crockford base32 characters
+func encodeULID(id [16]byte) string {
+	var buf [26]byte
+
+	// 128 bits do not divide into 5-bit groups, so the first character only
+	// carries the top 3 bits. Walk the bits from the least significant end.
+	bits, acc, pos := 0, uint(0), len(buf)-1
+	for i := len(id) - 1; i >= 0; i-- {
+		acc |= uint(id[i]) << bits
+		bits += 8
+		for bits >= 5 {
+			buf[pos] = crockfordBase32[acc&0x1f]
+			pos--
+			acc >>= 5
+			bits -= 5
+		}
+	}
+	buf[0] = crockfordBase32[acc&0x1f]
+
+	return string(buf[:])
+}
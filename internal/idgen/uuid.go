@@ -0,0 +1,53 @@
+package idgen
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// Ensure type implements the interface
+var _ IDGenerator = (*UUIDv7)(nil)
+
+// UUIDv7 generates time-ordered UUIDs as defined in RFC 9562
+//
+// The first 48 bits hold the Unix timestamp in milliseconds and the remaining
+// bits, apart from the version and variant, are random.
+type UUIDv7 struct {
+	now func() time.Time
+}
+
+func NewUUIDv7() *UUIDv7 {
+	return &UUIDv7{now: time.Now}
+}
+
+func (g *UUIDv7) NewID() string {
+	var uuid [16]byte
+	if _, err := rand.Read(uuid[6:]); err != nil {
+		panic("idgen: failed to read random bytes: " + err.Error())
+	}
+
+	ms := uint64(g.now().UnixMilli())
+	uuid[0] = byte(ms >> 40)
+	uuid[1] = byte(ms >> 32)
+	uuid[2] = byte(ms >> 24)
+	uuid[3] = byte(ms >> 16)
+	uuid[4] = byte(ms >> 8)
+	uuid[5] = byte(ms)
+	uuid[6] = (uuid[6] & 0x0f) | 0x70 // Version 7
+	uuid[8] = (uuid[8] & 0x3f) | 0x80 // RFC 9562 variant
+
+	// Format as xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx
+	var buf [36]byte
+	hex.Encode(buf[0:8], uuid[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], uuid[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], uuid[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], uuid[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], uuid[10:])
+
+	return string(buf[:])
+}
@@ -0,0 +1,85 @@
+// Package logging provides structured JSON logging with request IDs
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LevelOff is above every level, no line is logged
+const LevelOff = slog.Level(100)
+
+// Levels maps the configured level names to the slog levels
+var Levels = map[string]slog.Level{
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+	"off":   LevelOff,
+}
+
+// ParseLevel returns the slog level of the named level
+func ParseLevel(name string) (slog.Level, error) {
+	level, ok := Levels[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown log level %q", name)
+	}
+	return level, nil
+}
+
+// redacted replaces the values of the sensitive attributes
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are the attribute keys whose values are never logged,
+// whichever group they are in
+var sensitiveKeys = map[string]bool{
+	"name":   true,
+	"email":  true,
+	"salary": true,
+}
+
+// New creates a JSON logger writing to w at the level of the level variable
+//
+// Lines logged with a context (e.g. InfoContext) carry the request ID and
+// trace ID stored in the context. The values of the name, email and salary
+// attributes are always redacted.
+func New(w io.Writer, level slog.Leveler) *slog.Logger {
+	handler := slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if sensitiveKeys[strings.ToLower(a.Key)] {
+				return slog.String(a.Key, redacted)
+			}
+			return a
+		},
+	})
+	return slog.New(&contextHandler{Handler: handler})
+}
+
+// contextHandler adds the request ID and trace ID of the context to every line
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestIDFromContext(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		record.AddAttrs(slog.String("trace_id", span.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
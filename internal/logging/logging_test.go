@@ -0,0 +1,163 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// lines decodes the JSON lines written by the logger
+func lines(t *testing.T, out *bytes.Buffer) []map[string]any {
+	var decoded []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var fields map[string]any
+		if err := json.Unmarshal([]byte(line), &fields); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", line, err)
+		}
+		decoded = append(decoded, fields)
+	}
+	return decoded
+}
+
+// newTestApp creates an echo application logging to out, with a create route
+// that logs from the handler too
+func newTestApp(out *bytes.Buffer) *echo.Echo {
+	logger := New(out, slog.LevelDebug)
+	app := echo.New()
+	app.Use(RequestID(), Middleware(logger))
+	app.POST("/employees", func(c echo.Context) error {
+		logger.DebugContext(c.Request().Context(), "creating employee")
+		return c.JSON(http.StatusCreated, map[string]string{"id": "1"})
+	}).Name = "employee.create"
+	app.GET("/fail", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "store unavailable")
+	}).Name = "fail"
+	return app
+}
+
+func TestMiddleware(t *testing.T) {
+	var out bytes.Buffer
+	app := newTestApp(&out)
+
+	body := `{"name":"Ganesh Agrawal","email":"ganesh@example.com","salary":9999999}`
+	req := httptest.NewRequest(http.MethodPost, "/employees?name=Ganesh", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+
+	// A request ID is generated and echoed in the response
+	id := rec.Header().Get(HeaderRequestID)
+	assert.Len(t, id, 26, "generated request ID should be a ULID")
+
+	// Every line carries the request ID
+	logged := lines(t, &out)
+	assert.Len(t, logged, 2, "expected the handler line and the request line")
+	assert.Equal(t, "creating employee", logged[0]["msg"])
+	assert.Equal(t, id, logged[0]["request_id"], "handler line should carry the request ID")
+
+	request := logged[1]
+	assert.Equal(t, "request", request["msg"])
+	assert.Equal(t, "INFO", request["level"])
+	assert.Equal(t, id, request["request_id"], "request line should carry the request ID")
+	assert.Equal(t, "employee.create", request["route"], "route name should be logged")
+	assert.Equal(t, "/employees", request["path"], "query string should not be logged")
+	assert.Equal(t, 201.0, request["status"])
+	assert.Contains(t, request, "latency_ms", "latency should be logged")
+
+	// Nothing from the body or the query string is logged
+	for _, value := range []string{"Ganesh", "ganesh@example.com", "9999999"} {
+		assert.NotContains(t, out.String(), value, "request data should never be logged")
+	}
+}
+
+func TestMiddleware_Errors(t *testing.T) {
+	var out bytes.Buffer
+	app := newTestApp(&out)
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fail", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code, "error handler should run")
+
+	logged := lines(t, &out)
+	assert.Len(t, logged, 1)
+	assert.Equal(t, "ERROR", logged[0]["level"], "5xx should be logged as errors")
+	assert.Equal(t, 503.0, logged[0]["status"])
+	assert.Contains(t, logged[0]["error"], "store unavailable", "error should be logged")
+}
+
+func TestRequestID(t *testing.T) {
+	tests := map[string]struct {
+		header    string
+		propagate bool
+	}{
+		"propagated":   {header: "req-123_abc.def:1", propagate: true},
+		"too long":     {header: strings.Repeat("a", maxRequestIDLength+1)},
+		"invalid char": {header: "id with spaces"},
+		"log injection": {
+			header: "abc\",\"level\":\"ERROR",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			app := newTestApp(&out)
+
+			req := httptest.NewRequest(http.MethodGet, "/fail", nil)
+			req.Header.Set(HeaderRequestID, test.header)
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(HeaderRequestID)
+			if test.propagate {
+				assert.Equal(t, test.header, id, "request ID should be propagated")
+			} else {
+				assert.NotEqual(t, test.header, id, "request ID should be replaced")
+				assert.Len(t, id, 26, "request ID should be generated")
+			}
+			assert.Equal(t, id, lines(t, &out)[0]["request_id"])
+		})
+	}
+}
+
+func TestNew_Redaction(t *testing.T) {
+	var out bytes.Buffer
+	logger := New(&out, slog.LevelInfo)
+
+	logger.Info("employee",
+		"id", "1",
+		"name", "Ganesh Agrawal",
+		slog.Group("employee", "Salary", 9999999.0, "email", "ganesh@example.com"),
+	)
+	logger.Debug("filtered out")
+
+	logged := lines(t, &out)
+	assert.Len(t, logged, 1, "debug lines should be filtered out")
+	assert.Equal(t, "1", logged[0]["id"])
+	assert.Equal(t, redacted, logged[0]["name"], "name should be redacted")
+	assert.Equal(t, map[string]any{"Salary": redacted, "email": redacted}, logged[0]["employee"],
+		"attributes in groups should be redacted")
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("WARN")
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, slog.LevelWarn, level)
+
+	_, err = ParseLevel("loud")
+	assert.NotNil(t, err, "error should not be nil")
+
+	var out bytes.Buffer
+	New(&out, LevelOff).Error("nothing")
+	assert.Empty(t, out.String(), "nothing should be logged when off")
+}
@@ -0,0 +1,113 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/idgen"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/routes"
+	"github.com/labstack/echo/v4"
+)
+
+// HeaderRequestID is the header carrying the request ID
+const HeaderRequestID = echo.HeaderXRequestID
+
+// maxRequestIDLength is the length above which a propagated request ID is replaced
+const maxRequestIDLength = 128
+
+// requestIDKey is the context key of the request ID
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of the context carrying the request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID stored in the context, if any
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestID returns a middleware that propagates the X-Request-ID header of the
+// request, or generates one, and echoes it in the response
+//
+// The request ID is stored in the request context so that every line logged
+// with the context carries it. Propagated IDs that are too long or contain
+// characters other than letters, digits, '-', '_', '.' and ':' are replaced.
+func RequestID() echo.MiddlewareFunc {
+	generator := idgen.NewULID()
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			id := req.Header.Get(HeaderRequestID)
+			if !validRequestID(id) {
+				id = generator.NewID()
+			}
+
+			c.Response().Header().Set(HeaderRequestID, id)
+			c.SetRequest(req.WithContext(WithRequestID(req.Context(), id)))
+			return next(c)
+		}
+	}
+}
+
+// validRequestID reports whether a propagated request ID can be used as is
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Middleware returns a middleware that logs a line per request with its route
+// name, status and latency
+//
+// Request bodies and query strings are never logged, as they may hold names and
+// salaries. Handler errors are passed to the echo error handler first so that
+// the status logged is the one sent to the client.
+func Middleware(logger *slog.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				c.Error(err)
+			}
+
+			req, res := c.Request(), c.Response()
+			attrs := []slog.Attr{
+				slog.String("method", req.Method),
+				slog.String("route", routes.Name(c)),
+				slog.String("path", req.URL.Path),
+				slog.Int("status", res.Status),
+				slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+				slog.Int64("bytes_out", res.Size),
+				slog.String("remote_ip", c.RealIP()),
+			}
+			if err != nil {
+				attrs = append(attrs, slog.String("error", err.Error()))
+			}
+
+			level := slog.LevelInfo
+			switch {
+			case res.Status >= http.StatusInternalServerError:
+				level = slog.LevelError
+			case res.Status >= http.StatusBadRequest:
+				level = slog.LevelWarn
+			}
+			logger.LogAttrs(req.Context(), level, "request", attrs...)
+			return nil
+		}
+	}
+}
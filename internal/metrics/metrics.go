@@ -0,0 +1,175 @@
+// Package metrics exposes Prometheus metrics for the HTTP API and the repositories
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/cache"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/routes"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// unmatchedRoute labels the requests that did not match any route, so that
+// unknown paths cannot create new series
+const unmatchedRoute = "unmatched"
+
+// Metrics holds the collectors of the application and the registry they are registered in
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec   // Requests by route, method and status
+	httpDuration *prometheus.HistogramVec // Request latency by route, method and status
+
+	repoDuration *prometheus.HistogramVec // Repository operation latency by repository and method
+	repoErrors   *prometheus.CounterVec   // Repository operation errors by repository, method and kind
+}
+
+// New creates the collectors and registers them, along with the Go runtime
+// and process collectors, in a new registry
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Number of HTTP requests by echo route name, method and status.",
+		}, []string{"route", "method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Latency of HTTP requests by echo route name, method and status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		repoDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "repository_operation_duration_seconds",
+			Help:    "Latency of repository operations by repository and method.",
+			Buckets: []float64{.00001, .00005, .0001, .0005, .001, .005, .01, .05, .1},
+		}, []string{"repository", "method"}),
+		repoErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "repository_operation_errors_total",
+			Help: "Number of failed repository operations by repository, method and error kind.",
+		}, []string{"repository", "method", "error"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.repoDuration,
+		m.repoErrors,
+	)
+	return m
+}
+
+// Registry returns the registry the collectors are registered in
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler serves the metrics in the Prometheus text format
+func (m *Metrics) Handler() echo.HandlerFunc {
+	return echo.WrapHandler(promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+}
+
+// Middleware returns a middleware that counts and times the requests by echo
+// route name, method and status
+//
+// Handler errors are passed to the echo error handler first so that the
+// status recorded is the one sent to the client.
+func (m *Metrics) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+
+			route := routes.Name(c)
+			if route == "" {
+				route = unmatchedRoute
+			}
+			labels := prometheus.Labels{
+				"route":  route,
+				"method": c.Request().Method,
+				"status": strconv.Itoa(c.Response().Status),
+			}
+			m.httpRequests.With(labels).Inc()
+			m.httpDuration.With(labels).Observe(time.Since(start).Seconds())
+			return nil
+		}
+	}
+}
+
+// RegisterStoreGauges registers gauges for the number of entities in the named
+// store and, when nextID is not nil, for the next ID it will generate
+func (m *Metrics) RegisterStoreGauges(
+	repository string,
+	size func() int,
+	nextID func() int64,
+) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name:        "repository_store_size",
+		Help:        "Number of entities in the store.",
+		ConstLabels: prometheus.Labels{"repository": repository},
+	}, func() float64 { return float64(size()) }))
+
+	if nextID != nil {
+		m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "repository_next_id",
+			Help:        "Next sequential ID the store will assign.",
+			ConstLabels: prometheus.Labels{"repository": repository},
+		}, func() float64 { return float64(nextID()) }))
+	}
+}
+
+// RegisterCacheMetrics registers the counters of the cache in front of the named
+// repository, and a gauge for its size
+func (m *Metrics) RegisterCacheMetrics(repository string, stats func() cache.Stats) {
+	labels := prometheus.Labels{"repository": repository}
+	counters := []struct {
+		name  string
+		help  string
+		value func(cache.Stats) uint64
+	}{
+		{
+			"repository_cache_hits_total",
+			"Reads by ID served by the cache.",
+			func(s cache.Stats) uint64 { return s.Hits },
+		},
+		{
+			"repository_cache_misses_total",
+			"Reads by ID not served by the cache.",
+			func(s cache.Stats) uint64 { return s.Misses },
+		},
+		{
+			"repository_cache_evictions_total",
+			"Least recently used entities evicted from the full cache.",
+			func(s cache.Stats) uint64 { return s.Evictions },
+		},
+	}
+	for _, counter := range counters {
+		counter := counter // Captured by the function below
+		m.registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name:        counter.name,
+			Help:        counter.help,
+			ConstLabels: labels,
+		}, func() float64 { return float64(counter.value(stats())) }))
+	}
+
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name:        "repository_cache_size",
+		Help:        "Number of entities in the cache.",
+		ConstLabels: labels,
+	}, func() float64 { return float64(stats().Size) }))
+}
+
+// observe records the latency and outcome of a repository operation
+func (m *Metrics) observe(repository, method string, start time.Time, err error) {
+	m.repoDuration.WithLabelValues(repository, method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		m.repoErrors.WithLabelValues(repository, method, errorKind(err)).Inc()
+	}
+}
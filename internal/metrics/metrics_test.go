@@ -0,0 +1,147 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/cache"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/idgen"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/respository"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	m := New()
+	app := echo.New()
+	app.Use(m.Middleware())
+	app.GET("/employees/:id", func(c echo.Context) error {
+		if c.Param("id") == "missing" {
+			return echo.NewHTTPError(http.StatusNotFound, "employee not found")
+		}
+		return c.String(http.StatusOK, "ok")
+	}).Name = "employee.get"
+	app.GET("/metrics", m.Handler())
+
+	for _, path := range []string{"/employees/1", "/employees/2", "/employees/missing", "/unknown/path"} {
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	// Requests are labelled by route name and the status sent to the client
+	assert.Equal(t, 2.0, testutil.ToFloat64(
+		m.httpRequests.WithLabelValues("employee.get", http.MethodGet, "200"),
+	), "expected 2 successful requests")
+	assert.Equal(t, 1.0, testutil.ToFloat64(
+		m.httpRequests.WithLabelValues("employee.get", http.MethodGet, "404"),
+	), "expected 1 handler error")
+	assert.Equal(t, 1.0, testutil.ToFloat64(
+		m.httpRequests.WithLabelValues(unmatchedRoute, http.MethodGet, "404"),
+	), "expected unknown paths to share one label")
+
+	// The metrics are served in the Prometheus text format
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(),
+		`http_request_duration_seconds_count{method="GET",route="employee.get",status="200"} 2`)
+	assert.Contains(t, rec.Body.String(), "go_goroutines", "runtime metrics should be exported")
+}
+
+func TestInstrumentedEmployeeRepository(t *testing.T) {
+	m := New()
+	gen := idgen.NewSequential()
+	store := respository.NewEmployeeInMemoryRepositoryWithIDGenerator(gen)
+	repo := InstrumentEmployeeRepository(store, m)
+	m.RegisterStoreGauges("employee", store.Len, gen.Peek)
+
+	// The decorator returns the results of the wrapped repository
+	emp, err := repo.CreateEmployee("Ganesh Agrawal", "ganesh@example.com", "Engineer", 1234.00)
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, models.ID("1"), emp.ID, "ID should be 1")
+
+	repo.CreateEmployee("Harshit Kumar", "ganesh@example.com", "Engineer", 1235.00)
+	repo.GetEmployeeByID("1")
+	repo.GetEmployeeByID("99")
+	repo.UpdateEmployee("1", "Ganesh A", "ganesh@example.com", "Manager", 2000.00)
+	repo.DeleteEmployee("99")
+	employees, total := repo.GetAllEmployees(1, 10)
+	assert.Len(t, employees, 1)
+	assert.Equal(t, 1, total, "total should be 1")
+
+	// Every call is timed, errors are counted by kind
+	app := echo.New()
+	app.GET("/metrics", m.Handler())
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for method, count := range map[string]int{
+		"CreateEmployee":  2,
+		"GetEmployeeByID": 2,
+		"UpdateEmployee":  1,
+		"DeleteEmployee":  1,
+		"GetAllEmployees": 1,
+	} {
+		assert.Contains(t, rec.Body.String(), fmt.Sprintf(
+			`repository_operation_duration_seconds_count{method="%s",repository="employee"} %d`,
+			method,
+			count,
+		), "every call to %s should be timed", method)
+	}
+	assert.Equal(t, 1.0, testutil.ToFloat64(
+		m.repoErrors.WithLabelValues("employee", "CreateEmployee", "duplicate"),
+	), "expected 1 duplicate error")
+	assert.Equal(t, 1.0, testutil.ToFloat64(
+		m.repoErrors.WithLabelValues("employee", "GetEmployeeByID", "not_found"),
+	), "expected 1 not found error")
+	assert.Equal(t, 1.0, testutil.ToFloat64(
+		m.repoErrors.WithLabelValues("employee", "DeleteEmployee", "not_found"),
+	), "expected 1 not found error")
+
+	// The gauges follow the store
+	expected := `
+# HELP repository_next_id Next sequential ID the store will assign.
+# TYPE repository_next_id gauge
+repository_next_id{repository="employee"} 2
+# HELP repository_store_size Number of entities in the store.
+# TYPE repository_store_size gauge
+repository_store_size{repository="employee"} 1
+`
+	assert.Nil(t, testutil.GatherAndCompare(
+		m.Registry(),
+		strings.NewReader(expected),
+		"repository_next_id",
+		"repository_store_size",
+	))
+}
+
+func TestRegisterCacheMetrics(t *testing.T) {
+	m := New()
+	m.RegisterCacheMetrics("employee", func() cache.Stats {
+		return cache.Stats{Hits: 3, Misses: 2, Evictions: 1, Invalidations: 4, Size: 5}
+	})
+
+	expected := `
+# HELP repository_cache_evictions_total Least recently used entities evicted from the full cache.
+# TYPE repository_cache_evictions_total counter
+repository_cache_evictions_total{repository="employee"} 1
+# HELP repository_cache_hits_total Reads by ID served by the cache.
+# TYPE repository_cache_hits_total counter
+repository_cache_hits_total{repository="employee"} 3
+# HELP repository_cache_misses_total Reads by ID not served by the cache.
+# TYPE repository_cache_misses_total counter
+repository_cache_misses_total{repository="employee"} 2
+# HELP repository_cache_size Number of entities in the cache.
+# TYPE repository_cache_size gauge
+repository_cache_size{repository="employee"} 5
+`
+	assert.Nil(t, testutil.GatherAndCompare(m.Registry(), strings.NewReader(expected),
+		"repository_cache_evictions_total",
+		"repository_cache_hits_total",
+		"repository_cache_misses_total",
+		"repository_cache_size",
+	))
+}
@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/respository"
+)
+
+// Ensure type implements the interface
+var _ respository.IEmployeeRepository = (*InstrumentedEmployeeRepository)(nil)
+
+var _ respository.ContextBinder[respository.IEmployeeRepository] = (*InstrumentedEmployeeRepository)(
+	nil,
+)
+
+var _ respository.HistoryReader[models.Employee] = (*InstrumentedEmployeeRepository)(nil)
+
+// InstrumentedEmployeeRepository is an IEmployeeRepository decorator recording
+// the latency and errors of every call
+type InstrumentedEmployeeRepository struct {
+	next    respository.IEmployeeRepository
+	metrics *Metrics
+}
+
+// InstrumentEmployeeRepository wraps the repository with metrics
+func InstrumentEmployeeRepository(
+	next respository.IEmployeeRepository,
+	metrics *Metrics,
+) *InstrumentedEmployeeRepository {
+	return &InstrumentedEmployeeRepository{next: next, metrics: metrics}
+}
+
+// WithContext binds the wrapped repository to the context
+func (r *InstrumentedEmployeeRepository) WithContext(
+	ctx context.Context,
+) respository.IEmployeeRepository {
+	return &InstrumentedEmployeeRepository{
+		next:    respository.BindContext(ctx, r.next),
+		metrics: r.metrics,
+	}
+}
+
+func (r *InstrumentedEmployeeRepository) CreateEmployee(
+	name string,
+	email string,
+	position string,
+	salary float64,
+) (employee models.Employee, err error) {
+	defer r.observe("CreateEmployee", time.Now(), &err)
+	return r.next.CreateEmployee(name, email, position, salary)
+}
+
+func (r *InstrumentedEmployeeRepository) GetEmployeeByID(
+	id models.ID,
+) (employee models.Employee, err error) {
+	defer r.observe("GetEmployeeByID", time.Now(), &err)
+	return r.next.GetEmployeeByID(id)
+}
+
+func (r *InstrumentedEmployeeRepository) UpdateEmployee(
+	id models.ID,
+	name string,
+	email string,
+	position string,
+	salary float64,
+) (employee models.Employee, err error) {
+	defer r.observe("UpdateEmployee", time.Now(), &err)
+	return r.next.UpdateEmployee(id, name, email, position, salary)
+}
+
+func (r *InstrumentedEmployeeRepository) DeleteEmployee(id models.ID) (err error) {
+	defer r.observe("DeleteEmployee", time.Now(), &err)
+	return r.next.DeleteEmployee(id)
+}
+
+func (r *InstrumentedEmployeeRepository) GetAllEmployees(
+	page int,
+	limit int,
+) ([]models.Employee, int) {
+	defer r.observe("GetAllEmployees", time.Now(), nil)
+	return r.next.GetAllEmployees(page, limit)
+}
+
+func (r *InstrumentedEmployeeRepository) GetByIDAsOf(
+	id models.ID,
+	asOf time.Time,
+) (employee models.Employee, err error) {
+	defer r.observe("GetByIDAsOf", time.Now(), &err)
+	return respository.HistoryOf[models.Employee](r.next).GetByIDAsOf(id, asOf)
+}
+
+func (r *InstrumentedEmployeeRepository) GetAllAsOf(
+	asOf time.Time,
+	page int,
+	limit int,
+) (employees []models.Employee, total int, err error) {
+	defer r.observe("GetAllAsOf", time.Now(), &err)
+	return respository.HistoryOf[models.Employee](r.next).GetAllAsOf(asOf, page, limit)
+}
+
+// observe records the call, err points to the named result of the caller
+func (r *InstrumentedEmployeeRepository) observe(method string, start time.Time, err *error) {
+	var callErr error
+	if err != nil {
+		callErr = *err
+	}
+	r.metrics.observe("employee", method, start, callErr)
+}
+
+// errorKind classifies a repository error into a small fixed set of label values
+func errorKind(err error) string {
+	switch {
+	case errors.Is(err, respository.ErrRecordNotFound):
+		return "not_found"
+	case errors.Is(err, respository.ErrDuplicate):
+		return "duplicate"
+	default:
+		return "other"
+	}
+}
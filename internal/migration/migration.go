@@ -0,0 +1,314 @@
+// Package migration moves the employees between backends with dual writes,
+// shadow reads and a backfill
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/respository"
+)
+
+// Side is a backend of the migration
+type Side string
+
+// Sides
+const (
+	SidePrimary   Side = "primary"   // Backend migrated from, the source of truth
+	SideSecondary Side = "secondary" // Backend migrated to
+)
+
+// ParseSide parses a side
+func ParseSide(s string) (Side, error) {
+	switch Side(s) {
+	case SidePrimary, SideSecondary:
+		return Side(s), nil
+	}
+	return "", fmt.Errorf("unknown side %q, expected %q or %q", s, SidePrimary, SideSecondary)
+}
+
+// Secondary is the backend migrated to, employees are copied into it under
+// the IDs given by the primary
+type Secondary interface {
+	respository.IEmployeeRepository
+	respository.IEmployeeImporter
+}
+
+// Config configures the reads of a migration
+type Config struct {
+	ReadFrom    Side // Side the reads are served from
+	ShadowReads bool // Also read from the other side and log the mismatches
+}
+
+// Ensure type implements the interface
+var _ respository.IEmployeeRepository = (*DualWriteEmployeeRepository)(nil)
+
+var _ respository.ContextBinder[respository.IEmployeeRepository] = (*DualWriteEmployeeRepository)(
+	nil,
+)
+
+var _ respository.HistoryReader[models.Employee] = (*DualWriteEmployeeRepository)(nil)
+
+// DualWriteEmployeeRepository is an IEmployeeRepository writing to a primary
+// and a secondary backend
+//
+// Writes are applied to the primary first, and only mirrored to the secondary
+// when they succeed. Failures of the secondary are logged, never returned, as
+// the primary stays the source of truth until the migration is over. Writes
+// are serialized so that both backends apply them in the same order.
+type DualWriteEmployeeRepository struct {
+	primary   respository.IEmployeeRepository
+	secondary Secondary
+	cfg       Config
+	logger    *slog.Logger
+	writes    *sync.Mutex // Shared by the context bound copies, held by the backfill too
+}
+
+// NewDualWriteEmployeeRepository creates a repository writing to both backends
+func NewDualWriteEmployeeRepository(
+	primary respository.IEmployeeRepository,
+	secondary Secondary,
+	cfg Config,
+	logger *slog.Logger,
+) *DualWriteEmployeeRepository {
+	return &DualWriteEmployeeRepository{
+		primary:   primary,
+		secondary: secondary,
+		cfg:       cfg,
+		logger:    logger,
+		writes:    &sync.Mutex{},
+	}
+}
+
+// WithContext binds both backends to the context
+//
+// The secondary is only bound when it stays a Secondary once bound.
+func (r *DualWriteEmployeeRepository) WithContext(
+	ctx context.Context,
+) respository.IEmployeeRepository {
+	bound := *r
+	bound.primary = respository.BindContext(ctx, r.primary)
+	var secondary respository.IEmployeeRepository = r.secondary
+	if secondary, ok := respository.BindContext(ctx, secondary).(Secondary); ok {
+		bound.secondary = secondary
+	}
+	return &bound
+}
+
+func (r *DualWriteEmployeeRepository) CreateEmployee(
+	name string,
+	email string,
+	position string,
+	salary float64,
+) (models.Employee, error) {
+	r.writes.Lock()
+	defer r.writes.Unlock()
+
+	employee, err := r.primary.CreateEmployee(name, email, position, salary)
+	if err != nil {
+		return employee, err
+	}
+
+	// Copy the employee under the ID given by the primary
+	if err := r.secondary.ImportEmployee(employee); err != nil {
+		r.secondaryFailed("CreateEmployee", employee.ID, err)
+	}
+	return employee, nil
+}
+
+func (r *DualWriteEmployeeRepository) GetEmployeeByID(id models.ID) (models.Employee, error) {
+	read := func(repo respository.IEmployeeRepository) (models.Employee, error) {
+		return repo.GetEmployeeByID(id)
+	}
+	reads := readBoth(r, read)
+
+	if r.cfg.ShadowReads {
+		found, shadowFound := reads.err == nil, reads.shadowErr == nil
+		switch {
+		case found != shadowFound:
+			r.mismatch("GetEmployeeByID", "id", id, "found", found, "shadow_found", shadowFound)
+		case found && reads.result != reads.shadow:
+			r.mismatch("GetEmployeeByID", "id", id, "fields", diff(reads.result, reads.shadow))
+		}
+	}
+	return reads.result, reads.err
+}
+
+func (r *DualWriteEmployeeRepository) UpdateEmployee(
+	id models.ID,
+	name string,
+	email string,
+	position string,
+	salary float64,
+) (models.Employee, error) {
+	r.writes.Lock()
+	defer r.writes.Unlock()
+
+	employee, err := r.primary.UpdateEmployee(id, name, email, position, salary)
+	if err != nil {
+		return employee, err
+	}
+
+	// Employees not backfilled yet are copied
+	_, err = r.secondary.UpdateEmployee(id, name, email, position, salary)
+	if errors.Is(err, respository.ErrRecordNotFound) {
+		err = r.secondary.ImportEmployee(employee)
+	}
+	if err != nil {
+		r.secondaryFailed("UpdateEmployee", id, err)
+	}
+	return employee, nil
+}
+
+func (r *DualWriteEmployeeRepository) DeleteEmployee(id models.ID) error {
+	r.writes.Lock()
+	defer r.writes.Unlock()
+
+	if err := r.primary.DeleteEmployee(id); err != nil {
+		return err
+	}
+
+	// Employees not backfilled yet are already missing
+	err := r.secondary.DeleteEmployee(id)
+	if err != nil && !errors.Is(err, respository.ErrRecordNotFound) {
+		r.secondaryFailed("DeleteEmployee", id, err)
+	}
+	return nil
+}
+
+func (r *DualWriteEmployeeRepository) GetAllEmployees(
+	page int,
+	limit int,
+) ([]models.Employee, int) {
+	type result struct {
+		employees []models.Employee
+		total     int
+	}
+	read := func(repo respository.IEmployeeRepository) (result, error) {
+		employees, total := repo.GetAllEmployees(page, limit)
+		return result{employees, total}, nil
+	}
+	reads := readBoth(r, read)
+	list, shadow := reads.result, reads.shadow
+
+	if r.cfg.ShadowReads {
+		switch {
+		case list.total != shadow.total:
+			r.mismatch("GetAllEmployees", "total", list.total, "shadow_total", shadow.total)
+		case !slices.Equal(list.employees, shadow.employees):
+			r.mismatch("GetAllEmployees", "page", page, "limit", limit)
+		}
+	}
+	return list.employees, list.total
+}
+
+// GetByIDAsOf reads the past version from the side the reads are served from,
+// without shadow reads: the sides only agree on the versions since the backfill
+func (r *DualWriteEmployeeRepository) GetByIDAsOf(
+	id models.ID,
+	asOf time.Time,
+) (models.Employee, error) {
+	return respository.HistoryOf[models.Employee](r.readSide()).GetByIDAsOf(id, asOf)
+}
+
+// GetAllAsOf reads the past versions from the side the reads are served from,
+// without shadow reads
+func (r *DualWriteEmployeeRepository) GetAllAsOf(
+	asOf time.Time,
+	page int,
+	limit int,
+) ([]models.Employee, int, error) {
+	return respository.HistoryOf[models.Employee](r.readSide()).GetAllAsOf(asOf, page, limit)
+}
+
+// Backfill replaces the content of the secondary with the employees of the
+// primary, under the same IDs and in the same order, and returns their number
+//
+// Writes wait for the backfill, so that none is lost or applied out of order.
+func (r *DualWriteEmployeeRepository) Backfill(ctx context.Context) (int, error) {
+	r.writes.Lock()
+	defer r.writes.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	employees, _ := r.primary.GetAllEmployees(1, 0)
+	if err := r.secondary.ReplaceEmployees(employees); err != nil {
+		return 0, fmt.Errorf("backfill failed: %w", err)
+	}
+	r.logger.Info("migration backfill complete", "employees", len(employees))
+	return len(employees), nil
+}
+
+// reads are the results of a read from both sides
+type reads[R any] struct {
+	result    R // Read from the side the reads are served from
+	err       error
+	shadow    R // Read from the other side, with shadow reads
+	shadowErr error
+}
+
+// readSide returns the backend of the side the reads are served from
+func (r *DualWriteEmployeeRepository) readSide() respository.IEmployeeRepository {
+	if r.cfg.ReadFrom == SideSecondary {
+		return r.secondary
+	}
+	return r.primary
+}
+
+// readBoth reads from the side the reads are served from and, with shadow
+// reads, from the other side
+func readBoth[R any](
+	r *DualWriteEmployeeRepository,
+	read func(repo respository.IEmployeeRepository) (R, error),
+) reads[R] {
+	var from, other respository.IEmployeeRepository = r.primary, r.secondary
+	if r.cfg.ReadFrom == SideSecondary {
+		from, other = other, from
+	}
+
+	var result reads[R]
+	result.result, result.err = read(from)
+	if r.cfg.ShadowReads {
+		result.shadow, result.shadowErr = read(other)
+	}
+	return result
+}
+
+// mismatch logs a difference between the sides
+func (r *DualWriteEmployeeRepository) mismatch(method string, args ...any) {
+	r.logger.Warn(
+		"migration shadow read mismatch",
+		append([]any{"method", method, "read_from", r.cfg.ReadFrom}, args...)...,
+	)
+}
+
+// secondaryFailed logs a write the secondary missed
+func (r *DualWriteEmployeeRepository) secondaryFailed(method string, id models.ID, err error) {
+	r.logger.Error("migration secondary write failed", "method", method, "id", id, "error", err)
+}
+
+// diff returns the names of the fields that differ, without their values
+// which can be personal data
+func diff(a models.Employee, b models.Employee) []string {
+	var fields []string
+	if a.Name != b.Name {
+		fields = append(fields, "name")
+	}
+	if a.Email != b.Email {
+		fields = append(fields, "email")
+	}
+	if a.Position != b.Position {
+		fields = append(fields, "position")
+	}
+	if a.Salary != b.Salary {
+		fields = append(fields, "salary")
+	}
+	return fields
+}
@@ -0,0 +1,145 @@
+package migration
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/idgen"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/respository"
+	"github.com/stretchr/testify/assert"
+)
+
+// newMigration creates a dual-write repository between two in-memory
+// repositories, logging to the returned buffer
+func newMigration(cfg Config) (
+	*DualWriteEmployeeRepository,
+	*respository.EmployeeInMemoryRepository,
+	*respository.EmployeeInMemoryRepository,
+	*bytes.Buffer,
+) {
+	primary := respository.NewEmployeeInMemoryRepository()
+	secondary := respository.NewEmployeeInMemoryRepositoryWithIDGenerator(idgen.NewSequential())
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	return NewDualWriteEmployeeRepository(
+		primary,
+		secondary,
+		cfg,
+		logger,
+	), primary, secondary, &logs
+}
+
+// ids returns the IDs of the employees, in order
+func ids(employees []models.Employee) []models.ID {
+	result := make([]models.ID, 0, len(employees))
+	for _, employee := range employees {
+		result = append(result, employee.ID)
+	}
+	return result
+}
+
+func TestDualWrite(t *testing.T) {
+	repo, primary, secondary, logs := newMigration(Config{ReadFrom: SidePrimary})
+
+	ganesh, err := repo.CreateEmployee("Ganesh", "ganesh@example.com", "Engineer", 1000)
+	assert.Nil(t, err)
+	harshit, _ := repo.CreateEmployee("Harshit", "harshit@example.com", "Engineer", 1000)
+	_, err = repo.UpdateEmployee(ganesh.ID, "Ganesh", "ganesh@example.com", "Lead", 2000)
+	assert.Nil(t, err)
+	assert.Nil(t, repo.DeleteEmployee(harshit.ID))
+
+	// Both sides hold the same employees
+	employees, _ := primary.GetAllEmployees(1, 0)
+	copies, _ := secondary.GetAllEmployees(1, 0)
+	assert.Equal(t, employees, copies)
+	assert.Equal(t, "Lead", copies[0].Position)
+
+	// Failed writes are not mirrored
+	_, err = repo.CreateEmployee("Someone", "ganesh@example.com", "Intern", 1)
+	assert.ErrorIs(t, err, respository.ErrDuplicate)
+	assert.Equal(t, 1, secondary.Len())
+	assert.Empty(t, logs.String(), "secondary should not miss any write")
+}
+
+func TestDualWrite_SecondaryFailuresAreLogged(t *testing.T) {
+	repo, _, secondary, logs := newMigration(Config{ReadFrom: SidePrimary})
+
+	// The secondary already holds the email, e.g. it was written to directly
+	secondary.ImportEmployee(models.Employee{ID: "99", Email: "ganesh@example.com"})
+	_, err := repo.CreateEmployee("Ganesh", "ganesh@example.com", "Engineer", 1000)
+	assert.Nil(t, err, "primary is the source of truth")
+	assert.Contains(t, logs.String(), "migration secondary write failed")
+}
+
+func TestDualWrite_ShadowReads(t *testing.T) {
+	repo, _, secondary, logs := newMigration(Config{ReadFrom: SideSecondary, ShadowReads: true})
+
+	ganesh, _ := repo.CreateEmployee("Ganesh", "ganesh@example.com", "Engineer", 1000)
+	employee, err := repo.GetEmployeeByID(ganesh.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, ganesh, employee)
+	repo.GetAllEmployees(1, 10)
+	assert.Empty(t, logs.String(), "sides should match")
+
+	// A write bypassing the migration makes the sides differ
+	secondary.UpdateEmployee(ganesh.ID, "Ganesh", "ganesh@example.com", "Engineer", 5000)
+	employee, _ = repo.GetEmployeeByID(ganesh.ID)
+	assert.Equal(t, 5000.0, employee.Salary, "reads should be served from the secondary")
+	assert.Contains(t, logs.String(), "migration shadow read mismatch")
+	assert.Contains(t, logs.String(), "fields=[salary]")
+	assert.NotContains(t, logs.String(), "5000", "values should not be logged")
+
+	logs.Reset()
+	secondary.DeleteEmployee(ganesh.ID)
+	_, err = repo.GetEmployeeByID(ganesh.ID)
+	assert.ErrorIs(t, err, respository.ErrRecordNotFound)
+	assert.Contains(t, logs.String(), "shadow_found=true")
+
+	logs.Reset()
+	repo.GetAllEmployees(1, 10)
+	assert.Contains(t, logs.String(), "total=0 shadow_total=1")
+}
+
+func TestDualWrite_Backfill(t *testing.T) {
+	primary := respository.NewEmployeeInMemoryRepository()
+	ganesh, _ := primary.CreateEmployee("Ganesh", "ganesh@example.com", "Engineer", 1000)
+	harshit, _ := primary.CreateEmployee("Harshit", "harshit@example.com", "Engineer", 1000)
+	rahul, _ := primary.CreateEmployee("Rahul", "rahul@example.com", "Engineer", 1000)
+	primary.DeleteEmployee(ganesh.ID)
+
+	secondary := respository.NewEmployeeInMemoryRepository()
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	repo := NewDualWriteEmployeeRepository(
+		primary,
+		secondary,
+		Config{ReadFrom: SidePrimary},
+		logger,
+	)
+
+	// Writes before the backfill only reach the secondary for their employees
+	_, err := repo.UpdateEmployee(rahul.ID, "Rahul", "rahul@example.com", "Lead", 2000)
+	assert.Nil(t, err)
+	copies, _ := secondary.GetAllEmployees(1, 0)
+	assert.Equal(t, []models.ID{rahul.ID}, ids(copies), "updated employee should be copied")
+
+	copied, err := repo.Backfill(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 2, copied)
+
+	// IDs and insertion order are preserved
+	employees, _ := primary.GetAllEmployees(1, 0)
+	copies, _ = secondary.GetAllEmployees(1, 0)
+	assert.Equal(t, employees, copies)
+	assert.Equal(t, []models.ID{harshit.ID, rahul.ID}, ids(copies))
+
+	// New employees get the same ID on both sides
+	created, err := repo.CreateEmployee("Someone", "someone@example.com", "Intern", 1)
+	assert.Nil(t, err)
+	mirrored, err := secondary.GetEmployeeByID(created.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, created, mirrored)
+	assert.Equal(t, models.ID("4"), created.ID)
+}
@@ -0,0 +1,180 @@
+// Package outbox relays the writes recorded in the employee outbox to the publishers
+package outbox
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/events"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/respository"
+)
+
+// batchSize is the number of messages read from the outbox at once
+const batchSize = 100
+
+// Publisher publishes the events relayed from the outbox
+//
+// Delivery is at-least-once: an event is published again when the relay did
+// not get to acknowledge it, e.g. after a crash, and publishers or their
+// consumers drop duplicates by event ID.
+type Publisher interface {
+	Publish(ctx context.Context, event events.Event) error
+}
+
+// PublisherFunc is a function implementing Publisher
+type PublisherFunc func(ctx context.Context, event events.Event) error
+
+func (f PublisherFunc) Publish(ctx context.Context, event events.Event) error {
+	return f(ctx, event)
+}
+
+// Settler is a Publisher settling the events after Publish returns, e.g.
+// delivering them from a queue
+//
+// The outbox is only acknowledged up to the events it settled, so that the
+// events it did not get to settle are published again after a restart.
+type Settler interface {
+	Publisher
+	// Settled returns the sequence number up to which every event published was settled
+	Settled() uint64
+}
+
+// LogPublisher logs the events, without the employees
+func LogPublisher(logger *slog.Logger) Publisher {
+	return PublisherFunc(func(ctx context.Context, event events.Event) error {
+		logger.InfoContext(
+			ctx,
+			"employee event",
+			"event_id", event.ID,
+			"seq", event.Seq,
+			"type", event.Type,
+			"employee_id", event.Employee.ID,
+		)
+		return nil
+	})
+}
+
+// eventTypes maps the outbox write operations to event types
+var eventTypes = map[respository.WriteOp]events.Type{
+	respository.OpCreate: events.TypeCreated,
+	respository.OpUpdate: events.TypeUpdated,
+	respository.OpDelete: events.TypeDeleted,
+}
+
+// publisher is a registered publisher and the sequence number of the last
+// message it published
+type publisher struct {
+	name   string
+	next   Publisher
+	cursor uint64
+}
+
+// settled returns the sequence number of the last message the publisher settled
+func (p *publisher) settled() uint64 {
+	if settler, ok := p.next.(Settler); ok {
+		return min(p.cursor, settler.Settled())
+	}
+	return p.cursor
+}
+
+// Relay drains the outbox to the publishers, in order
+//
+// Every publisher keeps its own position in the outbox, a failing publisher is
+// retried on the next drain without holding back the others. Messages are
+// acknowledged, and dropped from the outbox, once every publisher published
+// them, and every Settler settled them.
+type Relay struct {
+	outbox     *respository.Outbox[models.Employee]
+	interval   time.Duration // Delay between drains when no write is notified
+	logger     *slog.Logger
+	mu         sync.Mutex // Serializes the drains
+	publishers []*publisher
+}
+
+// NewRelay creates a new relay without publishers
+func NewRelay(
+	outbox *respository.Outbox[models.Employee],
+	interval time.Duration,
+	logger *slog.Logger,
+) *Relay {
+	return &Relay{outbox: outbox, interval: interval, logger: logger}
+}
+
+// Register adds a publisher, it must be called before the relay runs
+func (r *Relay) Register(name string, next Publisher) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.publishers = append(r.publishers, &publisher{name: name, next: next})
+}
+
+// Run drains the outbox after every write, and on every interval to retry the
+// failed publishers, until the context is done
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.outbox.Notify():
+		case <-ticker.C:
+		}
+		_ = r.Drain(ctx) // Failures are logged and retried
+	}
+}
+
+// Drain publishes the pending messages to every publisher and acknowledges the
+// messages published by all of them
+func (r *Relay) Drain(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Without publishers, there is nobody to wait for
+	if len(r.publishers) == 0 {
+		if pending := r.outbox.Pending(0, 0); len(pending) > 0 {
+			r.outbox.Ack(pending[len(pending)-1].Seq)
+		}
+		return nil
+	}
+
+	var errs []error
+	acked := ^uint64(0)
+	for _, p := range r.publishers {
+		if err := r.drain(ctx, p); err != nil {
+			r.logger.Warn("outbox publish failed", "publisher", p.name, "error", err)
+			errs = append(errs, err)
+		}
+		acked = min(acked, p.settled())
+	}
+	r.outbox.Ack(acked)
+	return errors.Join(errs...)
+}
+
+// drain publishes the messages following the cursor of the publisher, it
+// stops at the first failure so that the order is kept
+func (r *Relay) drain(ctx context.Context, p *publisher) error {
+	for {
+		messages := r.outbox.Pending(p.cursor, batchSize)
+		if len(messages) == 0 {
+			return nil
+		}
+		for _, message := range messages {
+			event := events.Event{
+				ID:       message.ID,
+				Seq:      message.Seq,
+				Type:     eventTypes[message.Op],
+				Time:     message.Time,
+				Employee: message.Entity,
+			}
+			if err := p.next.Publish(ctx, event); err != nil {
+				return err
+			}
+			p.cursor = message.Seq
+		}
+	}
+}
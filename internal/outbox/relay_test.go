@@ -0,0 +1,157 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/events"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/respository"
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder is a publisher recording the events, it fails while failing is set
+type recorder struct {
+	events  []events.Event
+	failing bool
+}
+
+func (r *recorder) Publish(_ context.Context, event events.Event) error {
+	if r.failing {
+		return errors.New("unavailable")
+	}
+	r.events = append(r.events, event)
+	return nil
+}
+
+// newRepository creates an employee repository recording its writes in a new outbox
+func newRepository() (
+	*respository.EmployeeInMemoryRepository,
+	*respository.Outbox[models.Employee],
+) {
+	repo := respository.NewEmployeeInMemoryRepository()
+	outbox := respository.NewOutbox[models.Employee]()
+	repo.SetOutbox(outbox)
+	return repo, outbox
+}
+
+func newRelay(outbox *respository.Outbox[models.Employee]) *Relay {
+	return NewRelay(outbox, time.Hour, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestRelay_Drain(t *testing.T) {
+	repo, outbox := newRepository()
+	relay := newRelay(outbox)
+	bus, webhook := &recorder{}, &recorder{}
+	relay.Register("bus", bus)
+	relay.Register("webhook", webhook)
+
+	employee, _ := repo.CreateEmployee("Harshit", "harshit@example.com", "Engineer", 1000)
+	repo.UpdateEmployee(employee.ID, "Harshit", "harshit@example.com", "Lead", 2000)
+
+	// A failing publisher does not hold back the others
+	webhook.failing = true
+	assert.NotNil(t, relay.Drain(context.Background()), "error should not be nil")
+	if assert.Len(t, bus.events, 2) {
+		assert.Equal(t, events.TypeCreated, bus.events[0].Type)
+		assert.Equal(t, events.TypeUpdated, bus.events[1].Type)
+		assert.Equal(t, "Lead", bus.events[1].Employee.Position)
+		assert.Equal(t, uint64(2), bus.events[1].Seq)
+		assert.NotEmpty(t, bus.events[1].ID, "events should have a deduplication ID")
+	}
+	assert.Empty(t, webhook.events)
+	assert.Equal(t, 2, outbox.Len(), "messages should be kept until every publisher has them")
+
+	// The failed publisher resumes in order, without duplicates for the others
+	webhook.failing = false
+	repo.DeleteEmployee(employee.ID)
+	assert.Nil(t, relay.Drain(context.Background()), "error should be nil")
+	assert.Len(t, bus.events, 3)
+	assert.Equal(t, bus.events, webhook.events, "publishers should get the same events")
+	assert.Equal(t, events.TypeDeleted, webhook.events[2].Type)
+	assert.Equal(t, 0, outbox.Len(), "published messages should be acknowledged")
+}
+
+// settler is a recorder settling the events up to settled
+type settler struct {
+	recorder
+	settled uint64
+}
+
+func (s *settler) Settled() uint64 {
+	return s.settled
+}
+
+func TestRelay_DrainSettlers(t *testing.T) {
+	repo, outbox := newRepository()
+	relay := newRelay(outbox)
+	webhook := &settler{}
+	relay.Register("webhook", webhook)
+
+	repo.CreateEmployee("Harshit", "harshit@example.com", "Engineer", 1000)
+	repo.CreateEmployee("Ganesh", "ganesh@example.com", "Engineer", 1000)
+
+	// Published messages are kept until they are settled
+	assert.Nil(t, relay.Drain(context.Background()), "error should be nil")
+	assert.Len(t, webhook.events, 2)
+	assert.Equal(t, 2, outbox.Len(), "unsettled messages should be kept")
+
+	webhook.settled = 1
+	assert.Nil(t, relay.Drain(context.Background()), "error should be nil")
+	assert.Len(t, webhook.events, 2, "messages should not be published twice")
+	assert.Equal(t, 1, outbox.Len(), "settled messages should be acknowledged")
+}
+
+func TestRelay_DrainWithoutPublishers(t *testing.T) {
+	repo, outbox := newRepository()
+	repo.CreateEmployee("Harshit", "harshit@example.com", "Engineer", 1000)
+
+	assert.Nil(t, newRelay(outbox).Drain(context.Background()), "error should be nil")
+	assert.Equal(t, 0, outbox.Len(), "messages should not pile up")
+}
+
+func TestRelay_RedeliversAfterRestart(t *testing.T) {
+	repo, outbox := newRepository()
+	repo.CreateEmployee("Harshit", "harshit@example.com", "Engineer", 1000)
+	published := outbox.Pending(0, 0)[0]
+
+	// The process stops before the relay acknowledges the message
+	var snapshot bytes.Buffer
+	assert.Nil(t, repo.Snapshot(&snapshot), "error should be nil")
+	restored, restoredOutbox := newRepository()
+	assert.Nil(t, restored.Restore(&snapshot), "error should be nil")
+
+	relay := newRelay(restoredOutbox)
+	bus := &recorder{}
+	relay.Register("bus", bus)
+	assert.Nil(t, relay.Drain(context.Background()), "error should be nil")
+	if assert.Len(t, bus.events, 1) {
+		assert.Equal(t, published.ID, bus.events[0].ID, "redelivery should keep the ID")
+	}
+}
+
+func TestRelay_Run(t *testing.T) {
+	repo, outbox := newRepository()
+	relay := newRelay(outbox)
+	broker := events.NewBroker(10)
+	relay.Register("bus", broker)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		relay.Run(ctx)
+	}()
+
+	// Writes are relayed without waiting for the interval
+	repo.CreateEmployee("Harshit", "harshit@example.com", "Engineer", 1000)
+	assert.Eventually(t, func() bool { return broker.Seq() == 1 }, time.Second, time.Millisecond)
+
+	cancel()
+	<-done
+}
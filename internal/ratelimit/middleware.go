@@ -0,0 +1,109 @@
+package ratelimit
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/auth"
+	"github.com/labstack/echo/v4"
+)
+
+// Rate limit response headers
+const (
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+)
+
+// Middleware returns a middleware that rate limits requests with the limiter
+//
+// Clients are identified by the subject of their auth.Claims, so it must run
+// after the authentication middlewares. Requests without claims, on routes
+// without authentication, are keyed by client IP. GET, HEAD and OPTIONS
+// requests use the read budget, every other method uses the write budget.
+// Rejected requests get 429 Too Many Requests with a Retry-After header.
+func Middleware(limiter *Limiter) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			client, roles := "ip:"+c.RealIP(), []auth.Role(nil)
+			if claims, ok := auth.ClaimsFromContext(c); ok && claims.Subject != "" {
+				client, roles = "sub:"+claims.Subject, claims.Roles
+			}
+
+			result := limiter.Take(client, roles, isWrite(c.Request().Method))
+			setHeaders(c, result)
+			if !result.Allowed {
+				return tooManyRequests(c, result)
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// AuthFailureMiddleware returns a middleware that throttles the clients failing
+// authentication, keyed by client IP, e.g. to slow down credential brute force
+//
+// It must run before the authentication middlewares. Every request answered
+// with 401 Unauthorized takes a token from the default write budget of its IP,
+// and requests from an IP without tokens left get 429 Too Many Requests before
+// their credentials are checked. Authenticated requests take no token.
+func AuthFailureMiddleware(limiter *Limiter) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			client := "auth-failure:ip:" + c.RealIP()
+			if result := limiter.Peek(client, nil, true); !result.Allowed {
+				setHeaders(c, result)
+				return tooManyRequests(c, result)
+			}
+
+			err := next(c)
+			if unauthorized(c, err) {
+				limiter.Take(client, nil, true)
+			}
+			return err
+		}
+	}
+}
+
+// setHeaders sets the rate limit headers of the result
+func setHeaders(c echo.Context, result Result) {
+	header := c.Response().Header()
+	header.Set(HeaderRateLimitLimit, strconv.Itoa(result.Limit))
+	header.Set(HeaderRateLimitRemaining, strconv.Itoa(result.Remaining))
+	header.Set(HeaderRateLimitReset, ceilSeconds(result.Reset))
+}
+
+// tooManyRequests returns 429 Too Many Requests with a Retry-After header
+func tooManyRequests(c echo.Context, result Result) error {
+	c.Response().Header().Set(echo.HeaderRetryAfter, ceilSeconds(result.RetryAfter))
+	return c.JSON(http.StatusTooManyRequests, map[string]string{
+		"error": "rate limit exceeded, retry after " + ceilSeconds(result.RetryAfter) + "s",
+	})
+}
+
+// unauthorized reports whether the request was answered, or failed, with 401 Unauthorized
+func unauthorized(c echo.Context, err error) bool {
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code == http.StatusUnauthorized
+	}
+	return c.Response().Committed && c.Response().Status == http.StatusUnauthorized
+}
+
+func isWrite(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	default:
+		return true
+	}
+}
+
+// ceilSeconds formats the duration as a whole number of seconds, rounded up
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
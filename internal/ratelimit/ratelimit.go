@@ -0,0 +1,270 @@
+// Package ratelimit provides per-client token bucket rate limiting
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/auth"
+)
+
+// Limit is the budget of a token bucket
+type Limit struct {
+	PerMinute int `json:"per_minute" yaml:"per_minute"` // Sustained number of requests per minute
+	Burst     int `json:"burst"      yaml:"burst"`      // Bucket capacity, defaults to PerMinute when 0
+}
+
+// capacity returns the size of the bucket
+func (l Limit) capacity() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return float64(l.PerMinute)
+}
+
+// rate returns the number of tokens added to the bucket per second
+func (l Limit) rate() float64 {
+	return float64(l.PerMinute) / 60
+}
+
+// Budget holds separate limits for read and write routes
+type Budget struct {
+	Read  Limit `json:"read"  yaml:"read"`
+	Write Limit `json:"write" yaml:"write"`
+}
+
+// Limits configures the budgets per role
+//
+// A caller with several roles gets the most generous of their budgets, callers
+// without a configured role (including API keys and anonymous clients) get the
+// default budget.
+type Limits struct {
+	Default Budget               `json:"default"         yaml:"default"`
+	Roles   map[auth.Role]Budget `json:"roles,omitempty" yaml:"roles,omitempty"`
+}
+
+// DefaultLimits are the limits used when none are configured
+var DefaultLimits = Limits{
+	Default: Budget{
+		Read:  Limit{PerMinute: 300, Burst: 50},
+		Write: Limit{PerMinute: 60, Burst: 10},
+	},
+	Roles: map[auth.Role]Budget{
+		auth.RoleAdmin: {
+			Read:  Limit{PerMinute: 1200, Burst: 200},
+			Write: Limit{PerMinute: 300, Burst: 50},
+		},
+	},
+}
+
+// Validate checks that every limit allows at least one request per minute
+func (l Limits) Validate() error {
+	check := func(name string, limit Limit) error {
+		if limit.PerMinute <= 0 {
+			return fmt.Errorf("%s: per_minute must be greater than 0", name)
+		}
+		if limit.Burst < 0 {
+			return fmt.Errorf("%s: burst must not be negative", name)
+		}
+		return nil
+	}
+
+	errs := []error{
+		check("default.read", l.Default.Read),
+		check("default.write", l.Default.Write),
+	}
+	for role, budget := range l.Roles {
+		errs = append(errs,
+			check(fmt.Sprintf("roles.%s.read", role), budget.Read),
+			check(fmt.Sprintf("roles.%s.write", role), budget.Write),
+		)
+	}
+	return errors.Join(errs...)
+}
+
+// budgetFor returns the budget of a caller with the given roles
+func (l Limits) budgetFor(roles []auth.Role) Budget {
+	budget, found := l.Default, false
+	for _, role := range roles {
+		candidate, ok := l.Roles[role]
+		if !ok {
+			continue
+		}
+		if !found {
+			budget, found = candidate, true
+			continue
+		}
+		if candidate.Read.rate() > budget.Read.rate() {
+			budget.Read = candidate.Read
+		}
+		if candidate.Write.rate() > budget.Write.rate() {
+			budget.Write = candidate.Write
+		}
+	}
+	return budget
+}
+
+// Result is the outcome of taking a token from a bucket
+type Result struct {
+	Allowed    bool
+	Limit      int           // Capacity of the bucket
+	Remaining  int           // Tokens left in the bucket
+	Reset      time.Duration // Time until the bucket is full again
+	RetryAfter time.Duration // Time until the next token, when not allowed
+}
+
+// bucket is a token bucket, its limit is passed in on every use so that
+// configuration changes apply to existing buckets
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  Limit // Limit applied on the last use
+}
+
+func (b *bucket) take(limit Limit, now time.Time) Result {
+	capacity, rate := limit.capacity(), limit.rate()
+	b.limit = limit
+
+	// Refill the bucket for the elapsed time
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	result := Result{Limit: int(capacity)}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((capacity - b.tokens) / rate)
+
+	return result
+}
+
+// peek returns the result of a take at the given time, without taking a token
+func (b *bucket) peek(limit Limit, now time.Time) Result {
+	preview := *b
+	return preview.take(limit, now)
+}
+
+// full reports whether the bucket would be full at the given time
+func (b *bucket) full(now time.Time) bool {
+	return b.tokens+now.Sub(b.last).Seconds()*b.limit.rate() >= b.limit.capacity()
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// sweepInterval is how often idle buckets are dropped
+const sweepInterval = time.Minute
+
+// Limiter keeps one read and one write bucket per client
+//
+// The limits can be replaced at any time with SetLimits.
+type Limiter struct {
+	mu        sync.Mutex
+	limits    Limits
+	buckets   map[string]*bucket // Keyed by class and client identity
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewLimiter creates a new limiter with the given limits
+func NewLimiter(limits Limits) *Limiter {
+	return &Limiter{
+		limits:  limits,
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Limits returns a copy of the current limits
+func (l *Limiter) Limits() Limits {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limits := l.limits
+	limits.Roles = maps.Clone(l.limits.Roles)
+	return limits
+}
+
+// SetLimits replaces the limits, existing buckets keep their tokens
+func (l *Limiter) SetLimits(limits Limits) error {
+	if err := limits.Validate(); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.limits = limits
+	l.limits.Roles = maps.Clone(limits.Roles) // The caller keeps its map
+	return nil
+}
+
+// Take takes a token from the bucket of the client for a read or write request
+func (l *Limiter) Take(client string, roles []auth.Role, write bool) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	limit, b := l.bucketFor(client, roles, write, now)
+	result := b.take(limit, now)
+
+	l.sweep(now)
+
+	return result
+}
+
+// Peek returns the result Take would return, without taking a token
+func (l *Limiter) Peek(client string, roles []auth.Role, write bool) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	limit, b := l.bucketFor(client, roles, write, now)
+	return b.peek(limit, now)
+}
+
+// sweep drops the buckets that have refilled completely, as they are
+// equivalent to new ones. The caller must hold the lock.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if b.full(now) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// bucketFor returns the limit and the bucket of the client for a read or write
+// request, creating a full bucket if needed. The caller must hold the lock.
+func (l *Limiter) bucketFor(
+	client string,
+	roles []auth.Role,
+	write bool,
+	now time.Time,
+) (Limit, *bucket) {
+	budget := l.limits.budgetFor(roles)
+	limit, key := budget.Read, "read:"+client
+	if write {
+		limit, key = budget.Write, "write:"+client
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: limit.capacity(), last: now}
+		l.buckets[key] = b
+	}
+	return limit, b
+}
@@ -0,0 +1,161 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/auth"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+var testLimits = Limits{
+	Default: Budget{
+		Read:  Limit{PerMinute: 60, Burst: 2},
+		Write: Limit{PerMinute: 60, Burst: 1},
+	},
+	Roles: map[auth.Role]Budget{
+		auth.RoleAdmin: {
+			Read:  Limit{PerMinute: 120, Burst: 4},
+			Write: Limit{PerMinute: 120, Burst: 2},
+		},
+	},
+}
+
+// newTestLimiter creates a limiter with a clock controlled by the test
+func newTestLimiter() (*Limiter, *time.Time) {
+	now := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewLimiter(testLimits)
+	limiter.now = func() time.Time { return now }
+	return limiter, &now
+}
+
+func TestLimiter_Take(t *testing.T) {
+	limiter, now := newTestLimiter()
+
+	// The read bucket allows a burst of 2
+	result := limiter.Take("alice", nil, false)
+	assert.True(t, result.Allowed, "first request should be allowed")
+	assert.Equal(t, 2, result.Limit, "limit should be 2")
+	assert.Equal(t, 1, result.Remaining, "remaining should be 1")
+
+	result = limiter.Take("alice", nil, false)
+	assert.True(t, result.Allowed, "second request should be allowed")
+	assert.Equal(t, 0, result.Remaining, "remaining should be 0")
+
+	result = limiter.Take("alice", nil, false)
+	assert.False(t, result.Allowed, "third request should be rejected")
+	assert.Equal(t, time.Second, result.RetryAfter, "retry after should be 1s")
+
+	// Writes and other clients have their own buckets
+	assert.True(t, limiter.Take("alice", nil, true).Allowed, "write should be allowed")
+	assert.True(t, limiter.Take("bob", nil, false).Allowed, "other client should be allowed")
+
+	// The bucket refills over time
+	*now = now.Add(time.Second)
+	assert.True(t, limiter.Take("alice", nil, false).Allowed, "request should be allowed")
+	assert.False(t, limiter.Take("alice", nil, false).Allowed, "request should be rejected")
+}
+
+func TestLimiter_Roles(t *testing.T) {
+	limiter, _ := newTestLimiter()
+
+	// Admins get the larger budget, unknown roles get the default one
+	result := limiter.Take("root", []auth.Role{auth.RoleViewer, auth.RoleAdmin}, false)
+	assert.Equal(t, 4, result.Limit, "limit should be 4 for admins")
+
+	result = limiter.Take("carol", []auth.Role{auth.RoleViewer}, false)
+	assert.Equal(t, 2, result.Limit, "limit should be 2 for viewers")
+}
+
+func TestLimiter_SetLimits(t *testing.T) {
+	limiter, _ := newTestLimiter()
+
+	assert.True(t, limiter.Take("alice", nil, true).Allowed, "write should be allowed")
+	assert.False(t, limiter.Take("alice", nil, true).Allowed, "write should be rejected")
+
+	// Invalid limits are rejected
+	err := limiter.SetLimits(Limits{})
+	assert.NotNil(t, err, "error should not be nil")
+
+	// New limits apply to existing buckets without a restart
+	limits := testLimits
+	limits.Default.Write = Limit{PerMinute: 6000, Burst: 100}
+	assert.Nil(t, limiter.SetLimits(limits), "error should be nil")
+
+	result := limiter.Take("alice", nil, true)
+	assert.Equal(t, 100, result.Limit, "limit should be 100")
+	assert.Equal(t, limits, limiter.Limits(), "limits should be replaced")
+
+	// The limits returned are a copy
+	limiter.Limits().Roles[auth.RoleViewer] = Budget{}
+	_, ok := limiter.Limits().Roles[auth.RoleViewer]
+	assert.False(t, ok, "limits should not be changed through a copy")
+}
+
+func TestMiddleware(t *testing.T) {
+	limiter, _ := newTestLimiter()
+	handler := Middleware(limiter)(func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	serve := func(method string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/employees", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		rec := httptest.NewRecorder()
+		_ = handler(echo.New().NewContext(req, rec))
+		return rec
+	}
+
+	rec := serve(http.MethodPost)
+	assert.Equal(t, http.StatusNoContent, rec.Code, "status should be 204")
+	assert.Equal(t, "1", rec.Header().Get(HeaderRateLimitLimit))
+	assert.Equal(t, "0", rec.Header().Get(HeaderRateLimitRemaining))
+	assert.Equal(t, "1", rec.Header().Get(HeaderRateLimitReset))
+
+	rec = serve(http.MethodPut)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code, "status should be 429")
+	assert.Equal(t, "1", rec.Header().Get(echo.HeaderRetryAfter))
+
+	// Reads use another budget
+	rec = serve(http.MethodGet)
+	assert.Equal(t, http.StatusNoContent, rec.Code, "status should be 204")
+	assert.Equal(t, "2", rec.Header().Get(HeaderRateLimitLimit))
+}
+
+func TestAuthFailureMiddleware(t *testing.T) {
+	limiter, now := newTestLimiter()
+	status := http.StatusUnauthorized
+	handler := AuthFailureMiddleware(limiter)(func(c echo.Context) error {
+		return c.NoContent(status)
+	})
+
+	serve := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/employees", nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		_ = handler(echo.New().NewContext(req, rec))
+		return rec
+	}
+
+	// The default write budget allows a single failure
+	rec := serve("10.0.0.1:1234")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "status should be 401")
+	rec = serve("10.0.0.1:1234")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code, "status should be 429")
+	assert.Equal(t, "1", rec.Header().Get(echo.HeaderRetryAfter))
+
+	// Other IPs have their own budget
+	rec = serve("10.0.0.2:1234")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "status should be 401")
+
+	// Authenticated requests take no token
+	*now = now.Add(time.Second)
+	status = http.StatusNoContent
+	for i := 0; i < 3; i++ {
+		rec = serve("10.0.0.1:1234")
+		assert.Equal(t, http.StatusNoContent, rec.Code, "status should be 204")
+	}
+}
@@ -0,0 +1,20 @@
+// Package routes provides helpers to inspect the echo route matched by a request
+package routes
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+// Name returns the name of the route matched by the request
+//
+// It returns an empty string when the request did not match a registered route,
+// e.g. when echo answers with 404 Not Found or 405 Method Not Allowed.
+func Name(c echo.Context) string {
+	path, method := c.Path(), c.Request().Method
+	for _, route := range c.Echo().Routes() {
+		if route.Method == method && route.Path == path {
+			return route.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,137 @@
+// Package server runs the HTTP server and shuts it down gracefully
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Hook is called once the server has stopped serving requests, e.g. to flush
+// or snapshot a repository before exit
+type Hook func(ctx context.Context) error
+
+// namedHook is a shutdown hook with the name used in errors
+type namedHook struct {
+	name string
+	hook Hook
+}
+
+// Server runs an echo application until its context is cancelled
+//
+// On shutdown it fails readiness, waits for the pre-stop delay, stops accepting
+// connections, drains the in-flight requests within the shutdown timeout and
+// then runs the shutdown hooks in registration order.
+type Server struct {
+	app             *echo.Echo
+	logger          *slog.Logger
+	shutdownTimeout time.Duration // Deadline to drain in-flight requests, and then to run the hooks
+	preStopDelay    time.Duration // Delay between failing readiness and closing the listener
+	hooks           []namedHook   // Called after draining, in registration order
+	ready           atomic.Bool   // Whether the server accepts new requests
+}
+
+// New creates a new server for the echo application
+func New(app *echo.Echo, shutdownTimeout time.Duration, logger *slog.Logger) *Server {
+	return &Server{app: app, shutdownTimeout: shutdownTimeout, logger: logger}
+}
+
+// SetPreStopDelay keeps accepting requests for the delay once readiness fails
+// on shutdown, so that load balancers stop routing to the server before its
+// listener is closed
+//
+// It must be called before Run.
+func (s *Server) SetPreStopDelay(delay time.Duration) {
+	s.preStopDelay = delay
+}
+
+// OnShutdown registers a hook called after the in-flight requests are drained
+//
+// Hooks must be registered before Run is called.
+func (s *Server) OnShutdown(name string, hook Hook) {
+	s.hooks = append(s.hooks, namedHook{name: name, hook: hook})
+}
+
+// Ready reports whether the server is serving and not shutting down
+func (s *Server) Ready() bool {
+	return s.ready.Load()
+}
+
+// Run serves on the address until the context is cancelled or the server fails,
+// then shuts down gracefully
+//
+// It returns nil after a clean shutdown. Requests still running at the drain
+// deadline are aborted and reported, the hooks run in any case.
+func (s *Server) Run(ctx context.Context, address string) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.app.Start(address)
+	}()
+
+	// The server is only ready once it listens, i.e. accepts connections
+	var errs []error
+	for s.app.ListenerAddr() == nil {
+		select {
+		case err := <-serveErr:
+			errs = append(errs, fmt.Errorf("server failed: %w", err))
+			return errors.Join(append(errs, s.runHooks())...)
+		case <-time.After(time.Millisecond):
+		}
+	}
+	s.ready.Store(true)
+
+	// Wait for a shutdown request or a server failure
+	select {
+	case <-ctx.Done():
+	case err := <-serveErr:
+		s.ready.Store(false)
+		errs = append(errs, fmt.Errorf("server failed: %w", err))
+		return errors.Join(append(errs, s.runHooks())...)
+	}
+
+	// Fail readiness and keep serving until the load balancers noticed it
+	s.ready.Store(false)
+	if s.preStopDelay > 0 {
+		s.logger.Info("shutting down, waiting for the pre-stop delay", "delay", s.preStopDelay.String())
+		select {
+		case <-time.After(s.preStopDelay):
+		case err := <-serveErr:
+			errs = append(errs, fmt.Errorf("server failed: %w", err))
+			return errors.Join(append(errs, s.runHooks())...)
+		}
+	}
+
+	// Stop accepting connections and drain the in-flight requests
+	s.logger.Info("shutting down, draining in-flight requests", "timeout", s.shutdownTimeout.String())
+	drainCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+	if err := s.app.Shutdown(drainCtx); err != nil {
+		errs = append(errs, fmt.Errorf("drain failed: %w", err))
+		s.app.Close() // Abort the remaining requests
+	}
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		errs = append(errs, fmt.Errorf("server failed: %w", err))
+	}
+
+	return errors.Join(append(errs, s.runHooks())...)
+}
+
+// runHooks calls every hook within the shutdown timeout and reports their errors
+func (s *Server) runHooks() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+
+	var errs []error
+	for _, h := range s.hooks {
+		if err := h.hook(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("shutdown hook %s failed: %w", h.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
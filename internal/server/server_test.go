@@ -0,0 +1,234 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// discard is a logger that drops every line
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// newTestApp creates an echo application with a /slow route that signals when
+// it starts and waits for release before answering
+func newTestApp(started chan<- struct{}, release <-chan struct{}) *echo.Echo {
+	app := echo.New()
+	app.HideBanner = true
+	app.HidePort = true
+	app.GET("/slow", func(c echo.Context) error {
+		started <- struct{}{}
+		<-release
+		return c.String(http.StatusOK, "done")
+	})
+	return app
+}
+
+// waitForAddress waits until the application listens and returns its address
+func waitForAddress(t *testing.T, app *echo.Echo) string {
+	for i := 0; i < 100; i++ {
+		if addr := app.ListenerAddr(); addr != nil {
+			return addr.String()
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("server did not start")
+	return ""
+}
+
+func TestServer_GracefulShutdown(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	app := newTestApp(started, release)
+	srv := New(app, 5*time.Second, discard)
+
+	// Record the order of the shutdown steps
+	var mu sync.Mutex
+	var steps []string
+	record := func(step string) {
+		mu.Lock()
+		defer mu.Unlock()
+		steps = append(steps, step)
+	}
+	srv.OnShutdown("flush", func(ctx context.Context) error {
+		_, hasDeadline := ctx.Deadline()
+		assert.True(t, hasDeadline, "hook context should have a deadline")
+		record("flush")
+		return nil
+	})
+	srv.OnShutdown("snapshot", func(context.Context) error {
+		record("snapshot")
+		return nil
+	})
+
+	// Start the server
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Run(ctx, "127.0.0.1:0") }()
+	addr := waitForAddress(t, app)
+	assert.True(t, srv.Ready(), "server should be ready")
+
+	// Start an in-flight request
+	type result struct {
+		status int
+		err    error
+	}
+	inFlight := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err != nil {
+			inFlight <- result{err: err}
+			return
+		}
+		resp.Body.Close()
+		inFlight <- result{status: resp.StatusCode}
+	}()
+	<-started
+
+	// Request the shutdown
+	cancel()
+	assert.Eventually(t, func() bool { return !srv.Ready() }, time.Second, 5*time.Millisecond,
+		"readiness should fail on shutdown")
+
+	// New connections are refused while the request drains
+	assert.Eventually(t, func() bool {
+		conn, err := net.DialTimeout("tcp", addr, 100*time.Millisecond)
+		if err == nil {
+			conn.Close()
+		}
+		return err != nil
+	}, time.Second, 5*time.Millisecond, "new connections should be refused")
+
+	select {
+	case <-done:
+		t.Fatal("server should wait for the in-flight request")
+	default:
+	}
+	mu.Lock()
+	assert.Empty(t, steps, "hooks should not run before the requests are drained")
+	mu.Unlock()
+
+	// The in-flight request completes, then the hooks run
+	record("request")
+	close(release)
+	res := <-inFlight
+	assert.Nil(t, res.err, "in-flight request should not fail")
+	assert.Equal(t, http.StatusOK, res.status, "in-flight request should complete")
+
+	assert.Nil(t, <-done, "shutdown should be clean")
+	assert.Equal(
+		t,
+		[]string{"request", "flush", "snapshot"},
+		steps,
+		"hooks should run after draining",
+	)
+}
+
+func TestServer_DrainDeadline(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+	app := newTestApp(started, release)
+	srv := New(app, 100*time.Millisecond, discard)
+
+	hookCalled := false
+	srv.OnShutdown("snapshot", func(context.Context) error {
+		hookCalled = true
+		return nil
+	})
+	srv.OnShutdown("broken", func(context.Context) error {
+		return errors.New("disk full")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Run(ctx, "127.0.0.1:0") }()
+	addr := waitForAddress(t, app)
+
+	// Start a request that outlives the drain deadline
+	go http.Get("http://" + addr + "/slow")
+	<-started
+	cancel()
+
+	err := <-done
+	assert.NotNil(t, err, "error should not be nil")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "drain should time out")
+	assert.Contains(
+		t,
+		err.Error(),
+		"shutdown hook broken failed: disk full",
+		"hook errors should be reported",
+	)
+	assert.True(t, hookCalled, "hooks should run even when the drain times out")
+}
+
+func TestServer_StartFailure(t *testing.T) {
+	// Occupy a port so that the server cannot listen on it
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	app := echo.New()
+	app.HideBanner = true
+	srv := New(app, time.Second, discard)
+	hookCalled := false
+	srv.OnShutdown("snapshot", func(context.Context) error {
+		hookCalled = true
+		return nil
+	})
+
+	err = srv.Run(context.Background(), listener.Addr().String())
+	assert.NotNil(t, err, "error should not be nil")
+	assert.Contains(t, err.Error(), "server failed", "start failure should be reported")
+	assert.False(t, srv.Ready(), "server should not be ready")
+	assert.True(t, hookCalled, "hooks should run when the server fails")
+}
+
+func TestServer_PreStopDelay(t *testing.T) {
+	app := echo.New()
+	app.HideBanner = true
+	app.HidePort = true
+	app.GET("/ping", func(c echo.Context) error { return c.NoContent(http.StatusNoContent) })
+	srv := New(app, time.Second, discard)
+	srv.SetPreStopDelay(300 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Run(ctx, "127.0.0.1:0") }()
+
+	// The server is only ready once it accepts connections
+	assert.Eventually(t, srv.Ready, time.Second, time.Millisecond, "server should be ready")
+	addr := app.ListenerAddr()
+	if assert.NotNil(t, addr, "server should listen once ready") {
+		conn, err := net.Dial("tcp", addr.String())
+		assert.Nil(t, err, "server should accept connections once ready")
+		if err == nil {
+			conn.Close()
+		}
+	}
+
+	// Requests are still served while readiness fails, until the delay is over
+	cancel()
+	assert.Eventually(t, func() bool { return !srv.Ready() }, time.Second, time.Millisecond,
+		"readiness should fail on shutdown")
+	resp, err := http.Get("http://" + addr.String() + "/ping")
+	if assert.Nil(t, err, "requests should be served during the pre-stop delay") {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	}
+	select {
+	case <-done:
+		t.Fatal("server should wait for the pre-stop delay")
+	default:
+	}
+
+	assert.Nil(t, <-done, "shutdown should be clean")
+}
@@ -0,0 +1,62 @@
+package tracing
+
+import (
+	"net/http"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/routes"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware returns a middleware that starts a server span per request, named
+// after the echo route name (e.g. "employee.get")
+//
+// The parent span is extracted from the request headers with the propagator,
+// e.g. the W3C traceparent header. The span is stored in the request context so
+// that handlers and repositories can start child spans. Handler errors are passed
+// to the echo error handler first so that the status recorded is the one sent
+// to the client.
+func Middleware(
+	tp trace.TracerProvider,
+	propagator propagation.TextMapPropagator,
+) echo.MiddlewareFunc {
+	tracer := tp.Tracer(instrumentationName)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := propagator.Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+
+			name := routes.Name(c)
+			if name == "" {
+				name = "HTTP " + req.Method // Unknown paths share one name
+			}
+			ctx, span := tracer.Start(
+				ctx,
+				name,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(req.Method),
+					semconv.URLPath(req.URL.Path),
+					semconv.HTTPRoute(c.Path()),
+				),
+			)
+			defer span.End()
+
+			c.SetRequest(req.WithContext(ctx))
+			if err := next(c); err != nil {
+				span.RecordError(err)
+				c.Error(err)
+			}
+
+			status := c.Response().Status
+			span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+			return nil
+		}
+	}
+}
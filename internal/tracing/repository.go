@@ -0,0 +1,172 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/respository"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Span attributes of the repository calls
+const (
+	AttrEmployeeID  = attribute.Key("employee.id")
+	AttrPage        = attribute.Key("repository.page")
+	AttrLimit       = attribute.Key("repository.limit")
+	AttrResultCount = attribute.Key("repository.result_count")
+	AttrTotal       = attribute.Key("repository.total")
+	AttrAsOf        = attribute.Key("repository.as_of")
+)
+
+// Ensure type implements the interface
+var _ respository.IEmployeeRepository = (*TracedEmployeeRepository)(nil)
+var _ respository.ContextBinder[respository.IEmployeeRepository] = (*TracedEmployeeRepository)(nil)
+var _ respository.HistoryReader[models.Employee] = (*TracedEmployeeRepository)(nil)
+
+// TracedEmployeeRepository is an IEmployeeRepository decorator starting a span
+// per call, as a child of the span in the bound context
+type TracedEmployeeRepository struct {
+	next   respository.IEmployeeRepository
+	tracer trace.Tracer
+	ctx    context.Context // Context holding the parent span
+}
+
+// TraceEmployeeRepository wraps the repository with tracing
+//
+// Calls start root spans until the repository is bound to a request context
+// with WithContext.
+func TraceEmployeeRepository(
+	next respository.IEmployeeRepository,
+	tp trace.TracerProvider,
+) *TracedEmployeeRepository {
+	return &TracedEmployeeRepository{
+		next:   next,
+		tracer: tp.Tracer(instrumentationName),
+		ctx:    context.Background(),
+	}
+}
+
+// WithContext binds the repository, and the repository it wraps, to the context
+func (r *TracedEmployeeRepository) WithContext(
+	ctx context.Context,
+) respository.IEmployeeRepository {
+	return &TracedEmployeeRepository{
+		next:   respository.BindContext(ctx, r.next),
+		tracer: r.tracer,
+		ctx:    ctx,
+	}
+}
+
+func (r *TracedEmployeeRepository) CreateEmployee(
+	name string,
+	email string,
+	position string,
+	salary float64,
+) (models.Employee, error) {
+	span := r.start("CreateEmployee")
+	defer span.End()
+
+	employee, err := r.next.CreateEmployee(name, email, position, salary)
+	if err == nil {
+		span.SetAttributes(AttrEmployeeID.String(string(employee.ID)))
+	}
+	return employee, end(span, err)
+}
+
+func (r *TracedEmployeeRepository) GetEmployeeByID(id models.ID) (models.Employee, error) {
+	span := r.start("GetEmployeeByID", AttrEmployeeID.String(string(id)))
+	defer span.End()
+
+	employee, err := r.next.GetEmployeeByID(id)
+	return employee, end(span, err)
+}
+
+func (r *TracedEmployeeRepository) UpdateEmployee(
+	id models.ID,
+	name string,
+	email string,
+	position string,
+	salary float64,
+) (models.Employee, error) {
+	span := r.start("UpdateEmployee", AttrEmployeeID.String(string(id)))
+	defer span.End()
+
+	employee, err := r.next.UpdateEmployee(id, name, email, position, salary)
+	return employee, end(span, err)
+}
+
+func (r *TracedEmployeeRepository) DeleteEmployee(id models.ID) error {
+	span := r.start("DeleteEmployee", AttrEmployeeID.String(string(id)))
+	defer span.End()
+
+	return end(span, r.next.DeleteEmployee(id))
+}
+
+func (r *TracedEmployeeRepository) GetAllEmployees(
+	page int,
+	limit int,
+) ([]models.Employee, int) {
+	span := r.start("GetAllEmployees", AttrPage.Int(page), AttrLimit.Int(limit))
+	defer span.End()
+
+	employees, total := r.next.GetAllEmployees(page, limit)
+	span.SetAttributes(AttrResultCount.Int(len(employees)), AttrTotal.Int(total))
+	return employees, total
+}
+
+func (r *TracedEmployeeRepository) GetByIDAsOf(
+	id models.ID,
+	asOf time.Time,
+) (models.Employee, error) {
+	span := r.start(
+		"GetByIDAsOf",
+		AttrEmployeeID.String(string(id)),
+		AttrAsOf.String(asOf.Format(time.RFC3339)),
+	)
+	defer span.End()
+
+	employee, err := respository.HistoryOf[models.Employee](r.next).GetByIDAsOf(id, asOf)
+	return employee, end(span, err)
+}
+
+func (r *TracedEmployeeRepository) GetAllAsOf(
+	asOf time.Time,
+	page int,
+	limit int,
+) ([]models.Employee, int, error) {
+	span := r.start(
+		"GetAllAsOf",
+		AttrAsOf.String(asOf.Format(time.RFC3339)),
+		AttrPage.Int(page),
+		AttrLimit.Int(limit),
+	)
+	defer span.End()
+
+	employees, total, err := respository.HistoryOf[models.Employee](r.next).
+		GetAllAsOf(asOf, page, limit)
+	span.SetAttributes(AttrResultCount.Int(len(employees)), AttrTotal.Int(total))
+	return employees, total, end(span, err)
+}
+
+// start starts the span of a call, named after the interface method
+func (r *TracedEmployeeRepository) start(method string, attrs ...attribute.KeyValue) trace.Span {
+	_, span := r.tracer.Start(
+		r.ctx,
+		"IEmployeeRepository."+method,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(attrs...),
+	)
+	return span
+}
+
+// end records the error of a call on its span and returns it
+func end(span trace.Span, err error) error {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
@@ -0,0 +1,101 @@
+// Package tracing provides OpenTelemetry tracing for the HTTP API and the repositories
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// instrumentationName is the name of the tracers created by this package
+const instrumentationName = "github.com/iamganeshagrawal/go-crud-api-assignment/internal/tracing"
+
+// Supported span exporters
+const (
+	ExporterNone   = "none"   // Spans are not exported
+	ExporterStdout = "stdout" // Spans are written as JSON to the standard output
+	ExporterOTLP   = "otlp"   // Spans are sent to an OTLP/HTTP collector
+)
+
+// Config configures the export of the spans
+type Config struct {
+	Exporter string `json:"exporter"      yaml:"exporter"`
+	// Collector URL, e.g. http://localhost:4318, defaults to $OTEL_EXPORTER_OTLP_ENDPOINT
+	OTLPEndpoint string `json:"otlp_endpoint" yaml:"otlp_endpoint"`
+	ServiceName  string `json:"service_name"  yaml:"service_name"`
+}
+
+// DefaultConfig is the configuration used when none is given, spans are not exported
+var DefaultConfig = Config{
+	Exporter:    ExporterNone,
+	ServiceName: "go-crud-api",
+}
+
+// Validate checks the exporter and the service name
+func (c Config) Validate() error {
+	switch c.Exporter {
+	case ExporterNone, ExporterStdout, ExporterOTLP:
+	default:
+		return fmt.Errorf(
+			"exporter: %q is not one of %q, %q, %q",
+			c.Exporter,
+			ExporterNone,
+			ExporterStdout,
+			ExporterOTLP,
+		)
+	}
+	if c.ServiceName == "" {
+		return fmt.Errorf("service_name: is required")
+	}
+	return nil
+}
+
+// NewTracerProvider creates a tracer provider exporting the spans as configured,
+// stdout is the writer of the stdout exporter
+//
+// The provider must be shut down on exit to flush the pending spans.
+func NewTracerProvider(
+	ctx context.Context,
+	config Config,
+	stdout io.Writer,
+) (*sdktrace.TracerProvider, error) {
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName(config.ServiceName),
+		)),
+	}
+
+	switch config.Exporter {
+	case ExporterNone:
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(stdout))
+		if err != nil {
+			return nil, fmt.Errorf("stdout exporter: %w", err)
+		}
+		options = append(options, sdktrace.WithBatcher(exporter))
+	case ExporterOTLP:
+		var exporterOptions []otlptracehttp.Option
+		if config.OTLPEndpoint != "" {
+			exporterOptions = append(
+				exporterOptions,
+				otlptracehttp.WithEndpointURL(config.OTLPEndpoint),
+			)
+		}
+		exporter, err := otlptracehttp.New(ctx, exporterOptions...)
+		if err != nil {
+			return nil, fmt.Errorf("otlp exporter: %w", err)
+		}
+		options = append(options, sdktrace.WithBatcher(exporter))
+	default:
+		return nil, fmt.Errorf("unknown span exporter %q", config.Exporter)
+	}
+
+	return sdktrace.NewTracerProvider(options...), nil
+}
@@ -0,0 +1,164 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/respository"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newRecorder returns a tracer provider recording the ended spans in memory
+func newRecorder() (*sdktrace.TracerProvider, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	return sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)), recorder
+}
+
+// attributes returns the attributes of the span as a map
+func attributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestMiddleware_WithRepository(t *testing.T) {
+	tp, recorder := newRecorder()
+	store := respository.NewEmployeeInMemoryRepository()
+	store.CreateEmployee("Ganesh Agrawal", "ganesh@example.com", "Engineer", 1234.00)
+	store.CreateEmployee("Harshit Kumar", "harshit@example.com", "Engineer", 1235.00)
+	var repo respository.IEmployeeRepository = TraceEmployeeRepository(store, tp)
+
+	app := echo.New()
+	app.Use(Middleware(tp, propagation.TraceContext{}))
+	app.GET("/employees", func(c echo.Context) error {
+		employees, total := respository.BindContext(c.Request().Context(), repo).
+			GetAllEmployees(1, 10)
+		return c.JSON(http.StatusOK, map[string]any{"data": employees, "total": total})
+	}).Name = "employee.list"
+	app.GET("/employees/:id", func(c echo.Context) error {
+		_, err := respository.BindContext(c.Request().Context(), repo).
+			GetEmployeeByID("99")
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}).Name = "employee.get"
+
+	// The incoming W3C traceparent is the parent of the server span
+	req := httptest.NewRequest(http.MethodGet, "/employees", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2, "expected a server span and a repository span")
+	repoSpan, serverSpan := spans[0], spans[1]
+
+	assert.Equal(
+		t,
+		"employee.list",
+		serverSpan.Name(),
+		"server span should be named after the route",
+	)
+	assert.Equal(t, trace.SpanKindServer, serverSpan.SpanKind())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", serverSpan.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", serverSpan.Parent().SpanID().String(), "remote parent")
+	assert.Equal(t, int64(200), attributes(serverSpan)[semconv.HTTPResponseStatusCodeKey].AsInt64())
+	assert.Equal(t, "/employees", attributes(serverSpan)[semconv.HTTPRouteKey].AsString())
+
+	assert.Equal(t, "IEmployeeRepository.GetAllEmployees", repoSpan.Name())
+	assert.Equal(t, serverSpan.SpanContext().SpanID(), repoSpan.Parent().SpanID(),
+		"repository span should be a child of the server span")
+	attrs := attributes(repoSpan)
+	assert.Equal(t, int64(1), attrs[AttrPage].AsInt64(), "page should be recorded")
+	assert.Equal(t, int64(10), attrs[AttrLimit].AsInt64(), "limit should be recorded")
+	assert.Equal(t, int64(2), attrs[AttrResultCount].AsInt64(), "result count should be recorded")
+	assert.Equal(t, int64(2), attrs[AttrTotal].AsInt64(), "total should be recorded")
+
+	// Errors are recorded on both spans
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/employees/99", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	spans = recorder.Ended()[2:]
+	assert.Len(t, spans, 2, "expected a server span and a repository span")
+	assert.Equal(t, "IEmployeeRepository.GetEmployeeByID", spans[0].Name())
+	assert.Equal(t, codes.Error, spans[0].Status().Code, "repository span should fail")
+	assert.Equal(t, "99", attributes(spans[0])[AttrEmployeeID].AsString())
+	assert.Equal(t, "employee.get", spans[1].Name())
+	assert.Equal(t, codes.Error, spans[1].Status().Code, "server span should fail on 5xx")
+	assert.False(t, spans[1].Parent().IsValid(), "server span should be a root span")
+}
+
+func TestMiddleware_UnmatchedRoute(t *testing.T) {
+	tp, recorder := newRecorder()
+	app := echo.New()
+	app.Use(Middleware(tp, propagation.TraceContext{}))
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/unknown/1", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "HTTP GET", spans[0].Name(), "unknown paths should share one span name")
+	assert.Equal(t, codes.Unset, spans[0].Status().Code, "4xx should not fail the span")
+}
+
+func TestTracedEmployeeRepository_Unbound(t *testing.T) {
+	tp, recorder := newRecorder()
+	repo := TraceEmployeeRepository(respository.NewEmployeeInMemoryRepository(), tp)
+
+	emp, err := repo.CreateEmployee("Ganesh Agrawal", "ganesh@example.com", "Engineer", 1234.00)
+	assert.Nil(t, err, "error should be nil")
+	assert.Nil(t, repo.DeleteEmployee(emp.ID), "error should be nil")
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "IEmployeeRepository.CreateEmployee", spans[0].Name())
+	assert.Equal(t, string(emp.ID), attributes(spans[0])[AttrEmployeeID].AsString())
+	assert.False(t, spans[0].Parent().IsValid(), "unbound calls should start root spans")
+	assert.Equal(t, "IEmployeeRepository.DeleteEmployee", spans[1].Name())
+}
+
+func TestNewTracerProvider(t *testing.T) {
+	// The stdout exporter writes the spans on shutdown
+	var out bytes.Buffer
+	tp, err := NewTracerProvider(context.Background(), Config{
+		Exporter:    ExporterStdout,
+		ServiceName: "test-service",
+	}, &out)
+	assert.Nil(t, err, "error should be nil")
+	_, span := tp.Tracer("test").Start(context.Background(), "test-span")
+	span.End()
+	assert.Nil(t, tp.Shutdown(context.Background()), "error should be nil")
+	assert.Contains(t, out.String(), `"Name":"test-span"`, "span should be exported")
+	assert.Contains(t, out.String(), "test-service", "service name should be exported")
+
+	// The OTLP exporter connects lazily
+	tp, err = NewTracerProvider(context.Background(), Config{
+		Exporter:     ExporterOTLP,
+		OTLPEndpoint: "http://localhost:4318",
+		ServiceName:  "test-service",
+	}, nil)
+	assert.Nil(t, err, "error should be nil")
+	assert.NotNil(t, tp)
+
+	// Unknown exporters are rejected
+	_, err = NewTracerProvider(context.Background(), Config{Exporter: "zipkin"}, nil)
+	assert.NotNil(t, err, "error should not be nil")
+	assert.NotNil(t, Config{Exporter: "zipkin", ServiceName: "x"}.Validate())
+	assert.NotNil(t, Config{Exporter: ExporterNone}.Validate(), "service name should be required")
+	assert.Nil(t, DefaultConfig.Validate())
+}
@@ -0,0 +1,210 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/events"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/outbox"
+)
+
+// Ensure type implements the interface
+var _ outbox.Settler = (*Dispatcher)(nil)
+
+// Run attempts the deliveries until the context is done
+func (d *Dispatcher) Run(ctx context.Context) {
+	defer close(d.done)
+
+	var workers sync.WaitGroup
+	for i := 0; i < max(d.cfg.Workers, 1); i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			d.work(ctx)
+		}()
+	}
+	workers.Wait()
+}
+
+// Publish creates a delivery of the event for every webhook subscribed to its type
+//
+// The delivery ID is the ID of the event, so that an event published again,
+// e.g. redelivered by the outbox relay, is not delivered twice to a webhook
+// still holding the delivery in its log. The event is settled once all its
+// deliveries are.
+func (d *Dispatcher) Publish(_ context.Context, event events.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("webhook payload failed: %w", err)
+	}
+
+	webhooks, _ := d.repo.GetAll(1, 0)
+	now := d.now().UTC()
+	var deliveries []*Delivery
+
+	d.mu.Lock()
+	for _, webhook := range webhooks {
+		if !slices.Contains(webhook.Events, string(event.Type)) {
+			continue
+		}
+
+		// Append to the log of the webhook, dropping the oldest deliveries
+		log := d.logs[webhook.ID]
+		if slices.ContainsFunc(log, func(other *Delivery) bool { return other.ID == event.ID }) {
+			continue // Duplicate
+		}
+		delivery := &Delivery{
+			ID:        event.ID,
+			WebhookID: webhook.ID,
+			EventSeq:  event.Seq,
+			EventType: string(event.Type),
+			Status:    StatusPending,
+			CreatedAt: now,
+			UpdatedAt: now,
+			body:      body,
+		}
+		log = append(log, delivery)
+		if len(log) > logSize {
+			log = slices.Delete(log, 0, len(log)-logSize)
+		}
+		d.logs[webhook.ID] = log
+		deliveries = append(deliveries, delivery)
+	}
+	if len(deliveries) > 0 {
+		d.unsettled[event.Seq] += len(deliveries)
+	}
+	d.published = max(d.published, event.Seq)
+	d.mu.Unlock()
+
+	for _, delivery := range deliveries {
+		d.enqueue(delivery)
+	}
+	return nil
+}
+
+// enqueue queues the delivery for an attempt, unless the dispatcher stopped
+func (d *Dispatcher) enqueue(delivery *Delivery) {
+	select {
+	case d.queue <- delivery:
+	case <-d.done:
+	}
+}
+
+// work attempts the queued deliveries until the context is done
+func (d *Dispatcher) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case delivery := <-d.queue:
+			d.attempt(ctx, delivery)
+		}
+	}
+}
+
+// attempt sends the delivery once and schedules a retry when it fails
+func (d *Dispatcher) attempt(ctx context.Context, delivery *Delivery) {
+	// Deliveries of deleted webhooks are dropped
+	webhook, err := d.repo.GetByID(delivery.WebhookID)
+	if err != nil {
+		d.mu.Lock()
+		d.settle(delivery)
+		d.mu.Unlock()
+		return
+	}
+
+	statusCode, err := d.send(ctx, webhook.URL, webhook.Secret, delivery)
+	if ctx.Err() != nil {
+		return // Shutting down, unsettled, the event is published again on restart
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now().UTC()
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = ""
+	delivery.NextAttemptAt = nil
+	delivery.UpdatedAt = now
+
+	switch {
+	case err == nil:
+		delivery.Status = StatusSucceeded
+		d.settle(delivery)
+	case delivery.Attempts >= d.cfg.MaxAttempts:
+		delivery.Status = StatusDeadLettered
+		d.settle(delivery)
+		delivery.LastError = err.Error()
+		d.logger.Warn(
+			"webhook delivery dead-lettered",
+			"webhook_id", webhook.ID,
+			"delivery_id", delivery.ID,
+			"attempts", delivery.Attempts,
+			"error", err,
+		)
+	default:
+		delay := d.backoff(delivery.Attempts)
+		next := now.Add(delay)
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = &next
+		time.AfterFunc(delay, func() { d.enqueue(delivery) })
+	}
+}
+
+// send posts the signed payload to the URL and returns the response status
+//
+// Any status other than 2xx is an error.
+func (d *Dispatcher) send(
+	ctx context.Context,
+	url string,
+	secret string,
+	delivery *Delivery,
+) (int, error) {
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		url,
+		bytes.NewReader(delivery.body),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := d.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderDeliveryID, delivery.ID)
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(secret, timestamp, delivery.body))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	// Read the body to reuse the connection
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
+
+// backoff returns the delay before the retry following the given number of attempts
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.cfg.InitialBackoff
+	for i := 1; i < attempts && delay < d.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, d.cfg.MaxBackoff)
+}
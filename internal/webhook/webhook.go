@@ -0,0 +1,300 @@
+// Package webhook delivers the employee events to the URLs of downstream systems
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/events"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/respository"
+)
+
+// Headers of the deliveries
+const (
+	HeaderDeliveryID = "X-Webhook-ID"        // Delivery ID, the ID of the event
+	HeaderEvent      = "X-Webhook-Event"     // Event type
+	HeaderTimestamp  = "X-Webhook-Timestamp" // Unix time of the attempt, covered by the signature
+	HeaderSignature  = "X-Webhook-Signature" // "sha256=" and the hex HMAC of "<timestamp>.<body>"
+)
+
+var (
+	ErrInvalidEventType = errors.New("invalid event type")
+	ErrDeliveryNotFound = errors.New("delivery not found")
+	ErrNotDeadLettered  = errors.New("delivery is not dead-lettered")
+)
+
+// logSize is the number of deliveries kept in the log of each webhook
+const logSize = 100
+
+// Config configures the deliveries
+type Config struct {
+	MaxAttempts    int           // Attempts before a delivery is dead-lettered
+	InitialBackoff time.Duration // Delay before the first retry, doubled on every retry
+	MaxBackoff     time.Duration // Maximum delay between retries
+	Timeout        time.Duration // Deadline of each attempt
+	Workers        int           // Number of concurrent attempts
+}
+
+// DefaultConfig is the configuration used when none is given
+var DefaultConfig = Config{
+	MaxAttempts:    6,
+	InitialBackoff: time.Second,
+	MaxBackoff:     5 * time.Minute,
+	Timeout:        10 * time.Second,
+	Workers:        4,
+}
+
+// Delivery statuses
+const (
+	StatusPending      = "pending"       // Waiting for its first attempt or a retry
+	StatusSucceeded    = "succeeded"     // The receiver answered with a 2xx status
+	StatusDeadLettered = "dead_lettered" // Every attempt failed, it is only retried on demand
+)
+
+// Delivery is the delivery of an event to a webhook, along with its attempts
+type Delivery struct {
+	ID             string     `json:"id"`
+	WebhookID      models.ID  `json:"webhook_id"`
+	EventSeq       uint64     `json:"event_seq"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	LastStatusCode int        `json:"last_status_code,omitempty"` // HTTP status of the last attempt
+	LastError      string     `json:"last_error,omitempty"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	body    []byte // Event sent on every attempt
+	settled bool   // Succeeded, dead-lettered or dropped once, later redeliveries are not tracked
+}
+
+// Dispatcher manages the webhooks and delivers the events to them
+//
+// Deliveries are kept in memory. As an outbox.Settler it only lets the relay
+// acknowledge the events whose deliveries succeeded or were dead-lettered, so
+// that the pending ones are published again after a restart.
+type Dispatcher struct {
+	repo   respository.IWebhookRepository
+	client *http.Client
+	cfg    Config
+	logger *slog.Logger
+	now    func() time.Time
+	// Saves the webhooks, nil when they are not persisted
+	persist func() error
+
+	mu        sync.Mutex
+	logs      map[models.ID][]*Delivery // Latest deliveries of each webhook, oldest first
+	published uint64                    // Sequence number of the last event published
+	unsettled map[uint64]int            // Deliveries not settled yet, by event sequence number
+
+	queue chan *Delivery // Deliveries due for an attempt
+	done  chan struct{}  // Closed when Run returns
+}
+
+// NewDispatcher creates a new dispatcher, the timeout of the configuration is
+// applied to the client
+func NewDispatcher(
+	repo respository.IWebhookRepository,
+	client *http.Client,
+	cfg Config,
+	logger *slog.Logger,
+) *Dispatcher {
+	client.Timeout = cfg.Timeout
+	return &Dispatcher{
+		repo:      repo,
+		client:    client,
+		cfg:       cfg,
+		logger:    logger,
+		now:       time.Now,
+		logs:      make(map[models.ID][]*Delivery),
+		unsettled: make(map[uint64]int),
+		queue:     make(chan *Delivery, 1024),
+		done:      make(chan struct{}),
+	}
+}
+
+// SetPersist makes create and delete save the webhooks with persist before they
+// return, so that a deleted webhook never receives deliveries again after a crash.
+// Failing to save is reported as an error, the change stays in memory.
+//
+// It must be called before the dispatcher is used.
+func (d *Dispatcher) SetPersist(persist func() error) {
+	d.persist = persist
+}
+
+// Create registers a new webhook for the event types
+func (d *Dispatcher) Create(
+	url string,
+	eventTypes []string,
+	secret string,
+) (models.Webhook, error) {
+	for _, eventType := range eventTypes {
+		if _, err := events.ParseType(eventType); err != nil {
+			return models.Webhook{}, fmt.Errorf("%w: %v", ErrInvalidEventType, err)
+		}
+	}
+
+	webhook, err := d.repo.Create(models.Webhook{
+		URL:       url,
+		Events:    slices.Clone(eventTypes),
+		Secret:    secret,
+		CreatedAt: d.now().UTC(),
+	})
+	if err == nil {
+		err = d.save("create", webhook.ID)
+	}
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	return webhook, nil
+}
+
+// Get retrieves a webhook by ID
+func (d *Dispatcher) Get(id models.ID) (models.Webhook, error) {
+	return d.repo.GetByID(id)
+}
+
+// List retrieves all webhooks
+func (d *Dispatcher) List(page int, limit int) ([]models.Webhook, int) {
+	return d.repo.GetAll(page, limit)
+}
+
+// Delete removes a webhook along with its delivery log, its pending deliveries are dropped
+func (d *Dispatcher) Delete(id models.ID) error {
+	if err := d.repo.Delete(id); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	delete(d.logs, id)
+	d.mu.Unlock()
+
+	return d.save("delete", id)
+}
+
+// save persists the webhooks after the operation on the webhook, if they are persisted
+func (d *Dispatcher) save(op string, id models.ID) error {
+	if d.persist == nil {
+		return nil
+	}
+	if err := d.persist(); err != nil {
+		return fmt.Errorf("webhook with ID %s %s not persisted: %w", id, op, err)
+	}
+	return nil
+}
+
+// Deliveries retrieves the latest deliveries of a webhook, newest first
+func (d *Dispatcher) Deliveries(id models.ID) ([]Delivery, error) {
+	if _, err := d.repo.GetByID(id); err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	log := d.logs[id]
+	deliveries := make([]Delivery, 0, len(log))
+	for i := len(log) - 1; i >= 0; i-- {
+		deliveries = append(deliveries, *log[i])
+	}
+	return deliveries, nil
+}
+
+// Redeliver schedules a new attempt of a dead-lettered delivery, with a fresh
+// budget of attempts
+func (d *Dispatcher) Redeliver(id models.ID, deliveryID string) (Delivery, error) {
+	if _, err := d.repo.GetByID(id); err != nil {
+		return Delivery{}, err
+	}
+
+	d.mu.Lock()
+	index := slices.IndexFunc(d.logs[id], func(delivery *Delivery) bool {
+		return delivery.ID == deliveryID
+	})
+	if index < 0 {
+		d.mu.Unlock()
+		return Delivery{}, fmt.Errorf("delivery %s: %w", deliveryID, ErrDeliveryNotFound)
+	}
+	delivery := d.logs[id][index]
+	if delivery.Status != StatusDeadLettered {
+		d.mu.Unlock()
+		return *delivery, fmt.Errorf("delivery %s: %w", deliveryID, ErrNotDeadLettered)
+	}
+	delivery.Status = StatusPending
+	delivery.Attempts = 0
+	delivery.UpdatedAt = d.now().UTC()
+	snapshot := *delivery
+	d.mu.Unlock()
+
+	d.enqueue(delivery)
+	return snapshot, nil
+}
+
+// Settled returns the sequence number up to which the deliveries of every event
+// published succeeded, were dead-lettered or dropped with their webhook
+func (d *Dispatcher) Settled() uint64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	settled := d.published
+	for seq := range d.unsettled {
+		settled = min(settled, seq-1)
+	}
+	return settled
+}
+
+// Flush waits until the deliveries of every event published are settled, or
+// until the context is done, e.g. before stopping the dispatcher
+func (d *Dispatcher) Flush(ctx context.Context) error {
+	for {
+		d.mu.Lock()
+		unsettled := len(d.unsettled)
+		d.mu.Unlock()
+		if unsettled == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d events with pending webhook deliveries: %w", unsettled, ctx.Err())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// settle records that the delivery will not be attempted again, the caller must hold the lock
+func (d *Dispatcher) settle(delivery *Delivery) {
+	if delivery.settled {
+		return
+	}
+	delivery.settled = true
+	if d.unsettled[delivery.EventSeq]--; d.unsettled[delivery.EventSeq] <= 0 {
+		delete(d.unsettled, delivery.EventSeq)
+	}
+}
+
+// Sign returns the signature of the body sent at the timestamp, the value of
+// the signature header
+//
+// Receivers compute it with their copy of the secret and compare it in constant
+// time, they should also reject old timestamps to prevent replays.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
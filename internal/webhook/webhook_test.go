@@ -0,0 +1,305 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/events"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/idgen"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/respository"
+	"github.com/stretchr/testify/assert"
+)
+
+// receiver is a webhook receiver answering with the given statuses in turn,
+// and then with 200 OK
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+	received chan struct{}
+}
+
+func newReceiver(statuses ...int) *receiver {
+	return &receiver{statuses: statuses, received: make(chan struct{}, 100)}
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	r.mu.Unlock()
+
+	w.WriteHeader(status)
+	r.received <- struct{}{}
+}
+
+// wait waits for n requests
+func (r *receiver) wait(t *testing.T, n int) {
+	for i := 0; i < n; i++ {
+		select {
+		case <-r.received:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("receiver got %d requests, expected %d", i, n)
+		}
+	}
+}
+
+// startDispatcher runs a dispatcher with fast retries until the test ends
+func startDispatcher(t *testing.T) *Dispatcher {
+	dispatcher := NewDispatcher(
+		respository.NewWebhookInMemoryRepository(idgen.NewSequential()),
+		&http.Client{},
+		Config{
+			MaxAttempts:    3,
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     5 * time.Millisecond,
+			Timeout:        time.Second,
+			Workers:        2,
+		},
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go dispatcher.Run(ctx)
+	t.Cleanup(func() {
+		cancel()
+		<-dispatcher.done
+	})
+	return dispatcher
+}
+
+// waitStatus waits for the latest delivery of the webhook to reach the status
+func waitStatus(t *testing.T, dispatcher *Dispatcher, id models.ID, status string) Delivery {
+	var deliveries []Delivery
+	assert.Eventually(t, func() bool {
+		deliveries, _ = dispatcher.Deliveries(id)
+		return len(deliveries) > 0 && deliveries[0].Status == status
+	}, 5*time.Second, time.Millisecond, "delivery should be %s", status)
+	if len(deliveries) == 0 {
+		t.FailNow()
+	}
+	return deliveries[0]
+}
+
+// publish publishes an event with the sequence number as ID
+func publish(t *testing.T, dispatcher *Dispatcher, seq uint64, eventType events.Type) {
+	event := events.Event{
+		ID:       "event-" + strconv.FormatUint(seq, 10),
+		Seq:      seq,
+		Type:     eventType,
+		Employee: models.Employee{ID: "1", Name: "Harshit", Salary: 1000},
+	}
+	assert.Nil(t, dispatcher.Publish(context.Background(), event))
+}
+
+func TestDispatcher_SignedDelivery(t *testing.T) {
+	dispatcher := startDispatcher(t)
+	receiver := newReceiver()
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	webhook, err := dispatcher.Create(
+		server.URL,
+		[]string{"created", "deleted"},
+		"0123456789abcdef",
+	)
+	assert.Nil(t, err)
+
+	// Updates are not subscribed to
+	publish(t, dispatcher, 1, events.TypeUpdated)
+	publish(t, dispatcher, 2, events.TypeCreated)
+	receiver.wait(t, 1)
+
+	req, body := receiver.requests[0], receiver.bodies[0]
+	assert.Equal(t, "created", req.Header.Get(HeaderEvent))
+	timestamp, err := strconv.ParseInt(req.Header.Get(HeaderTimestamp), 10, 64)
+	assert.Nil(t, err)
+	assert.Equal(t, Sign("0123456789abcdef", timestamp, body), req.Header.Get(HeaderSignature))
+	assert.NotEqual(t, Sign("another secret", timestamp, body), req.Header.Get(HeaderSignature))
+
+	var event events.Event
+	assert.Nil(t, json.Unmarshal(body, &event))
+	assert.Equal(
+		t,
+		"event-2",
+		req.Header.Get(HeaderDeliveryID),
+		"event ID should be the delivery ID",
+	)
+	assert.Equal(t, "event-2", event.ID)
+	assert.Equal(t, uint64(2), event.Seq)
+	assert.Equal(t, 1000.0, event.Employee.Salary, "payload should carry the full employee")
+
+	delivery := waitStatus(t, dispatcher, webhook.ID, StatusSucceeded)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, http.StatusOK, delivery.LastStatusCode)
+
+	// Events published again are not delivered twice
+	publish(t, dispatcher, 2, events.TypeCreated)
+	deliveries, _ := dispatcher.Deliveries(webhook.ID)
+	assert.Len(t, deliveries, 1, "duplicate event should be dropped")
+}
+
+func TestDispatcher_RetriesWithTheSameDeliveryID(t *testing.T) {
+	dispatcher := startDispatcher(t)
+	receiver := newReceiver(http.StatusInternalServerError, http.StatusServiceUnavailable)
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	webhook, err := dispatcher.Create(server.URL, []string{"created"}, "0123456789abcdef")
+	assert.Nil(t, err)
+
+	publish(t, dispatcher, 1, events.TypeCreated)
+	receiver.wait(t, 3)
+
+	delivery := waitStatus(t, dispatcher, webhook.ID, StatusSucceeded)
+	assert.Equal(t, 3, delivery.Attempts, "delivery should succeed on the third attempt")
+	for _, req := range receiver.requests {
+		assert.Equal(t, delivery.ID, req.Header.Get(HeaderDeliveryID), "ID should not change")
+	}
+}
+
+func TestDispatcher_DeadLetterAndRedeliver(t *testing.T) {
+	dispatcher := startDispatcher(t)
+	receiver := newReceiver(http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	webhook, err := dispatcher.Create(server.URL, []string{"deleted"}, "0123456789abcdef")
+	assert.Nil(t, err)
+
+	publish(t, dispatcher, 1, events.TypeDeleted)
+	receiver.wait(t, 3)
+
+	delivery := waitStatus(t, dispatcher, webhook.ID, StatusDeadLettered)
+	assert.Equal(t, 3, delivery.Attempts, "delivery should be dead-lettered after 3 attempts")
+	assert.Equal(t, http.StatusBadGateway, delivery.LastStatusCode)
+	assert.Contains(t, delivery.LastError, "unexpected status 502")
+
+	// Dead-lettered deliveries are only retried on demand
+	_, err = dispatcher.Redeliver(webhook.ID, "unknown")
+	assert.ErrorIs(t, err, ErrDeliveryNotFound)
+
+	_, err = dispatcher.Redeliver(webhook.ID, delivery.ID)
+	assert.Nil(t, err)
+	receiver.wait(t, 1)
+	delivery = waitStatus(t, dispatcher, webhook.ID, StatusSucceeded)
+	assert.Equal(t, 1, delivery.Attempts, "redelivery should get a fresh budget of attempts")
+
+	_, err = dispatcher.Redeliver(webhook.ID, delivery.ID)
+	assert.ErrorIs(t, err, ErrNotDeadLettered)
+}
+
+func TestDispatcher_Settled(t *testing.T) {
+	dispatcher := startDispatcher(t)
+	receiver := newReceiver(http.StatusInternalServerError)
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	_, err := dispatcher.Create(server.URL, []string{"created"}, "0123456789abcdef")
+	assert.Nil(t, err)
+	unreachable, err := dispatcher.Create(
+		"http://127.0.0.1:1",
+		[]string{"deleted"},
+		"0123456789abcdef",
+	)
+	assert.Nil(t, err)
+
+	// Events without deliveries are settled right away
+	publish(t, dispatcher, 1, events.TypeUpdated)
+	assert.Equal(t, uint64(1), dispatcher.Settled())
+
+	// Events are settled once their deliveries succeeded, after retries
+	publish(t, dispatcher, 2, events.TypeCreated)
+	assert.Equal(t, uint64(1), dispatcher.Settled(), "queued deliveries should not be settled")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.Nil(t, dispatcher.Flush(ctx))
+	assert.Equal(t, uint64(2), dispatcher.Settled())
+
+	// Dead-lettered deliveries are settled, and so are those of deleted webhooks
+	publish(t, dispatcher, 3, events.TypeDeleted)
+	assert.Nil(t, dispatcher.Flush(ctx))
+	waitStatus(t, dispatcher, unreachable.ID, StatusDeadLettered)
+	assert.Equal(t, uint64(3), dispatcher.Settled())
+
+	dispatcher.cfg.MaxAttempts = 100
+	publish(t, dispatcher, 4, events.TypeDeleted)
+	short, cancelShort := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancelShort()
+	assert.ErrorIs(t, dispatcher.Flush(short), context.DeadlineExceeded)
+	assert.Equal(t, uint64(3), dispatcher.Settled(), "retried deliveries should not be settled")
+	assert.Nil(t, dispatcher.Delete(unreachable.ID))
+	assert.Nil(t, dispatcher.Flush(ctx))
+	assert.Equal(t, uint64(4), dispatcher.Settled())
+}
+
+func TestDispatcher_CreateAndDelete(t *testing.T) {
+	dispatcher := startDispatcher(t)
+
+	_, err := dispatcher.Create("http://localhost", []string{"hired"}, "0123456789abcdef")
+	assert.ErrorIs(t, err, ErrInvalidEventType)
+
+	webhook, err := dispatcher.Create("http://localhost", []string{"created"}, "0123456789abcdef")
+	assert.Nil(t, err)
+	assert.Nil(t, dispatcher.Delete(webhook.ID))
+
+	_, err = dispatcher.Deliveries(webhook.ID)
+	assert.ErrorIs(t, err, respository.ErrRecordNotFound)
+}
+
+func TestDispatcher_Persist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "webhooks.json")
+	dispatcher := startDispatcher(t)
+	repo := dispatcher.repo.(*respository.WebhookInMemoryRepository)
+	dispatcher.SetPersist(func() error { return respository.SaveSnapshotFile(path, repo) })
+
+	// Register two webhooks and delete one, nothing else saves the webhooks
+	kept, _ := dispatcher.Create("http://localhost/kept", []string{"created"}, "0123456789abcdef")
+	deleted, _ := dispatcher.Create("http://localhost/deleted", []string{"created"}, "0123456789abcdef")
+	assert.Nil(t, dispatcher.Delete(deleted.ID))
+
+	// Restart from the saved webhooks, only the kept one is registered
+	restarted := respository.NewWebhookInMemoryRepository(idgen.NewSequential())
+	assert.Nil(t, respository.LoadSnapshotFile(path, restarted))
+	webhooks, _ := restarted.GetAll(1, 0)
+	assert.Equal(t, []models.Webhook{kept}, webhooks)
+}
+
+func TestDispatcher_Backoff(t *testing.T) {
+	dispatcher := &Dispatcher{
+		cfg: Config{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second},
+	}
+
+	var delays []time.Duration
+	for attempts := 1; attempts <= 5; attempts++ {
+		delays = append(delays, dispatcher.backoff(attempts))
+	}
+	assert.Equal(
+		t,
+		[]time.Duration{
+			time.Second,
+			2 * time.Second,
+			4 * time.Second,
+			5 * time.Second,
+			5 * time.Second,
+		},
+		delays,
+	)
+}
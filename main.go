@@ -1,230 +1,591 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"flag"
+	"log"
+	"log/slog"
 	"net/http"
-	"strconv"
-
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/admin"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/apikey"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/auth"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/cache"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/chaos"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/config"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/events"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/eventsourcing"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/health"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/hooks"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/idgen"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/logging"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/metrics"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/migration"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/outbox"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/ratelimit"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/server"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/tracing"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/webhook"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
 	"github.com/iamganeshagrawal/go-crud-api-assignment/respository"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
 )
 
 func main() {
-	// Create a new in-memory repository
-	empRepo := respository.NewEmployeeInMemoryRepository()
+	// Load the configuration from the defaults, the config file, the environment and the flags
+	cfg, err := config.Load(os.Args[0], os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	// Create a new employee controller
-	empController := NewEmployeeController(empRepo)
+	// Log JSON lines to stdout, the level can be changed at runtime
+	logLevel := new(slog.LevelVar)
+	level, _ := logging.ParseLevel(cfg.Log.Level) // Already validated
+	logLevel.Set(level)
+	logger := logging.New(os.Stdout, logLevel)
+	slog.SetDefault(logger)
 
-	// Create a new echo application
-	app := echo.New()
+	// Create the ID generator
+	idGenerator, err := idgen.New(cfg.IDs.Strategy, cfg.IDs.SnowflakeNode)
+	if err != nil {
+		log.Fatal(err)
+	}
+	models.SetNumericIDs(cfg.IDs.NumericIDs && cfg.IDs.Strategy == idgen.StrategySequential)
 
-	// add middleware
-	app.Pre(middleware.RemoveTrailingSlash()) // Remove trailing slash from the URL
-	app.Use(middleware.Logger())              // Log all requests
-	app.Use(middleware.Recover())             // Recover from panics
+	// Create the JWT bearer authentication middleware
+	jwtConfig := auth.JWTConfig{
+		HMACSecret: []byte(cfg.Auth.JWTSecret),
+		Issuer:     cfg.Auth.JWTIssuer,
+		Audience:   cfg.Auth.JWTAudience,
+	}
+	if cfg.Auth.JWTJWKSFile != "" {
+		if jwtConfig.JWKS, err = auth.LoadJWKS(cfg.Auth.JWTJWKSFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	authenticate, err := auth.JWT(jwtConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	// Define routes
-	// Grouping routes under /api/v1
-	apiV1Group := app.Group("/api/v1")
-	empGroup := apiV1Group.Group("/employees")
+	// Create a new in-memory repository, its writes are recorded in the outbox
+	// along with the employees so that no event is lost
+	empRepo := respository.NewEmployeeInMemoryRepositoryWithIDGenerator(idGenerator)
+
+	// With the eventsourced backend, the employees are folded from the event log
+	// into the in-memory repository, and the writes are recorded once logged
+	var eventSourced *eventsourcing.EventSourcedEmployeeRepository
+	var eventStore *eventsourcing.FileStore
+	if cfg.Storage.Backend == config.BackendEventSourced {
+		if err := os.MkdirAll(cfg.Storage.DataDir, 0o755); err != nil {
+			log.Fatal(err)
+		}
+		eventStore, err = eventsourcing.OpenFileStore(cfg.Storage.DataDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		eventSourced, err = eventsourcing.Open(
+			eventStore,
+			idGenerator,
+			cfg.Storage.EventSnapshotEvery,
+			logger.With("component", "eventsourcing"),
+		)
+		if err != nil {
+			log.Fatal(err)
+		}
+		empRepo = eventSourced.Projection()
+	}
+	// Writes a publisher failed to get to for too long are dropped from the outbox
+	employeeOutbox := respository.NewOutbox[models.Employee]()
+	employeeOutbox.SetCapacity(
+		cfg.Events.OutboxCapacity,
+		func(message respository.OutboxMessage[models.Employee]) {
+			logger.Error(
+				"outbox message dead-lettered",
+				"message_id", message.ID,
+				"seq", message.Seq,
+				"op", message.Op,
+				"employee_id", message.Entity.ID,
+			)
+		},
+	)
+	if eventSourced != nil {
+		eventSourced.SetOutbox(employeeOutbox)
+	} else {
+		empRepo.SetOutbox(employeeOutbox)
+	}
 
-	// Define employee routes
-	empGroup.PUT("/:id", empController.UpdateEmployee).Name = "employee.update"
-	empGroup.DELETE("/:id", empController.DeleteEmployee).Name = "employee.delete"
-	empGroup.GET("/:id", empController.GetEmployeeByID).Name = "employee.get"
-	empGroup.POST("", empController.CreateEmployee).Name = "employee.create"
-	empGroup.GET("", empController.GetAllEmployees).Name = "employee.list"
+	// Past versions of the employees answer the as_of reads, the event log already retains them
+	var employeeHistory *respository.History[models.Employee]
+	if eventSourced == nil {
+		employeeHistory = respository.NewHistory[models.Employee]()
+		empRepo.SetHistory(employeeHistory)
+	}
 
-	// Ping or Health check endpoint
-	app.GET("/ping", func(c echo.Context) error {
-		return c.String(http.StatusOK, "pong")
-	}).Name = "ping"
+	// With the file backend, restore the employees from the last snapshot
+	snapshotFile := filepath.Join(cfg.Storage.DataDir, "employees.json")
+	if cfg.Storage.Backend == config.BackendFile {
+		if err := os.MkdirAll(cfg.Storage.DataDir, 0o755); err != nil {
+			log.Fatal(err)
+		}
+		if err := respository.LoadSnapshotFile(snapshotFile, empRepo); err != nil {
+			log.Fatal(err)
+		}
+	}
 
-	// List all routes in the application (For debugging)
-	app.GET("/", func(c echo.Context) error {
-		routes := app.Routes()
-		return c.JSON(http.StatusOK, routes)
-	}).Name = "index"
+	// Fan out the changes made to the employees, the last ones are retained to resume streams
+	broker := events.NewBroker(cfg.Events.BufferSize)
 
-	// Start the echo application
-	app.Logger.Fatal(app.Start(":8080"))
-}
+	// Create the Prometheus metrics, served by the admin server
+	appMetrics := metrics.New()
 
-// EmployeeController is the controller for handling employee requests
-type EmployeeController struct {
-	repo respository.IEmployeeRepository
-}
+	// During a migration, mirror the writes to the secondary file backend
+	var storedEmployees respository.IEmployeeRepository = empRepo
+	if eventSourced != nil {
+		storedEmployees = eventSourced
+	}
+	var dualWrite *migration.DualWriteEmployeeRepository
+	var secondaryRepo *respository.EmployeeInMemoryRepository
+	secondaryFile := filepath.Join(cfg.Migration.SecondaryDataDir, "employees.json")
+	if cfg.Migration.SecondaryDataDir != "" {
+		secondaryIDGenerator, err := idgen.New(cfg.IDs.Strategy, cfg.IDs.SnowflakeNode)
+		if err != nil {
+			log.Fatal(err)
+		}
+		secondaryRepo = respository.NewEmployeeInMemoryRepositoryWithIDGenerator(
+			secondaryIDGenerator,
+		)
+		if err := os.MkdirAll(cfg.Migration.SecondaryDataDir, 0o755); err != nil {
+			log.Fatal(err)
+		}
+		if err := respository.LoadSnapshotFile(secondaryFile, secondaryRepo); err != nil {
+			log.Fatal(err)
+		}
+
+		readFrom, _ := migration.ParseSide(cfg.Migration.ReadFrom) // Validated with the config
+		dualWrite = migration.NewDualWriteEmployeeRepository(
+			storedEmployees,
+			secondaryRepo,
+			migration.Config{ReadFrom: readFrom, ShadowReads: cfg.Migration.ShadowReads},
+			logger.With("component", "migration"),
+		)
+		storedEmployees = dualWrite
+	}
 
-// NewEmployeeController creates a new employee controller
-func NewEmployeeController(repo respository.IEmployeeRepository) *EmployeeController {
-	return &EmployeeController{repo: repo}
-}
+	// For resilience testing, inject the faults toggled on the admin server into the storage
+	var chaosInjector *chaos.Injector
+	if cfg.Admin.Chaos {
+		chaosInjector = chaos.NewInjector()
+		logger.Warn("fault injection into the employee storage is available on the admin server")
+	}
 
-// CreateEmployee creates a new employee
-//
-// POST /api/v1/employees
-func (ec *EmployeeController) CreateEmployee(c echo.Context) error {
-	var body CreateEmployeeRequest
-	if err := c.Bind(&body); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]interface{}{
-			"error": "invalid request body",
-		})
+	// Cache the employees read by ID in front of the storage, below the injected faults
+	storedEmployees, cachedEmployees := decorateStorage(
+		storedEmployees,
+		cfg.Storage.CacheSize,
+		time.Duration(cfg.Storage.CacheTTL),
+		chaosInjector,
+	)
+	if cachedEmployees != nil {
+		appMetrics.RegisterCacheMetrics("employee", cachedEmployees.Stats)
 	}
 
-	// Validate the request body
-	if err := body.Validate(); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]any{"error": err})
+	// Run the side effects and business rules around the employee writes
+	employeeHooks := hooks.NewRegistry()
+	employeeHooks.Before("normalize-names", 0, hooks.NormalizeNames, hooks.OpCreate, hooks.OpUpdate)
+	hookedEmployees := employeeHooks.Wrap(storedEmployees)
+
+	// Record the latency and errors of the employee repository, and the size of its store
+	employees := metrics.InstrumentEmployeeRepository(hookedEmployees, appMetrics)
+	var nextID func() int64
+	if sequential, ok := idGenerator.(*idgen.Sequential); ok {
+		nextID = sequential.Peek
 	}
+	appMetrics.RegisterStoreGauges("employee", empRepo.Len, nextID)
 
-	// Create an employee from the request body
-	emp, err := ec.repo.CreateEmployee(body.Name, body.Position, body.Salary)
+	// Trace the calls to the employee repository
+	tracerProvider, err := tracing.NewTracerProvider(context.Background(), cfg.Tracing, os.Stdout)
 	if err != nil {
-		return err
+		log.Fatal(err)
 	}
+	otel.SetTracerProvider(tracerProvider)
+	tracedEmployees := tracing.TraceEmployeeRepository(employees, tracerProvider)
 
-	// Return the created employee
-	return c.JSON(http.StatusCreated, emp)
-}
-
-// GetEmployeeByID retrieves an employee by ID
-//
-// GET /api/v1/employees/:id
-func (ec *EmployeeController) GetEmployeeByID(c echo.Context) error {
-	// Get the employee ID from the URL
-	idStr := c.Param("id")
-	id, err := strconv.Atoi(idStr)
+	// Create the API key manager, keys are stored the same way as employees
+	// (restored from the data dir unless in memory, with IDs following the same
+	// strategy) and can only be granted the employee routes and the changes feed
+	apiKeyIDGenerator, err := idgen.New(cfg.IDs.Strategy, cfg.IDs.SnowflakeNode)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid employee ID"})
+		log.Fatal(err)
 	}
-
-	// Retrieve the employee from the repository
-	employee, err := ec.repo.GetEmployeeByID(id)
-	if err != nil && errors.Is(err, respository.ErrRecordNotFound) {
-		return c.JSON(http.StatusNotFound, map[string]string{"error": "employee not found"})
+	apiKeyRepo := respository.NewAPIKeyInMemoryRepository(apiKeyIDGenerator)
+	apiKeysFile := filepath.Join(cfg.Storage.DataDir, "api_keys.json")
+	if cfg.Storage.Backend != config.BackendMemory {
+		if err := respository.LoadSnapshotFile(apiKeysFile, apiKeyRepo); err != nil {
+			log.Fatal(err)
+		}
 	}
+	apiKeyManager := apikey.NewManager(
+		apiKeyRepo,
+		[]string{
+			"employee.list",
+			"employee.get",
+			"employee.events",
+			"change.list",
+			"employee.create",
+			"employee.update",
+			"employee.delete",
+			auth.PermissionEmployeeSalary,
+		},
+	)
+	apiKeyController := NewAPIKeyController(apiKeyManager, cfg.Pagination)
+
+	// Create the per-client rate limiter, its limits can be changed at runtime
+	limiter := ratelimit.NewLimiter(cfg.RateLimits)
+	rateLimitController := NewRateLimitController(limiter)
+
+	// Create the webhook dispatcher, webhooks are stored the same way as API keys
+	webhookIDGenerator, err := idgen.New(cfg.IDs.Strategy, cfg.IDs.SnowflakeNode)
 	if err != nil {
-		return err
+		log.Fatal(err)
+	}
+	webhookRepo := respository.NewWebhookInMemoryRepository(webhookIDGenerator)
+	webhooksFile := filepath.Join(cfg.Storage.DataDir, "webhooks.json")
+	if cfg.Storage.Backend != config.BackendMemory {
+		if err := respository.LoadSnapshotFile(webhooksFile, webhookRepo); err != nil {
+			log.Fatal(err)
+		}
+	}
+	dispatcher := webhook.NewDispatcher(
+		webhookRepo,
+		&http.Client{},
+		webhook.Config{
+			MaxAttempts:    cfg.Webhooks.MaxAttempts,
+			InitialBackoff: time.Duration(cfg.Webhooks.InitialBackoff),
+			MaxBackoff:     time.Duration(cfg.Webhooks.MaxBackoff),
+			Timeout:        time.Duration(cfg.Webhooks.Timeout),
+			Workers:        webhook.DefaultConfig.Workers,
+		},
+		logger,
+	)
+	webhookController := NewWebhookController(dispatcher, cfg.Pagination)
+
+	// Relay the outbox to the event streams, the webhooks and the logs
+	relay := outbox.NewRelay(employeeOutbox, time.Duration(cfg.Events.RelayInterval), logger)
+	relay.Register("bus", broker)
+	relay.Register("webhook", dispatcher)
+	relay.Register("log", outbox.LogPublisher(logger))
+
+	// Salaries are only shown to the roles allowed to see them
+	presentEmployee := func(c echo.Context, employee models.Employee) any {
+		if !auth.Can(c, auth.DefaultPolicy, auth.PermissionEmployeeSalary) {
+			return employee.Redacted()
+		}
+		return employee
 	}
 
-	// Return the employee
-	return c.JSON(http.StatusOK, employee)
-}
+	// Create a new employee controller on top of the generic CRUD handlers
+	empController := NewCRUDController[models.Employee, CreateEmployeeRequest](
+		"employee",
+		respository.AsRepository(tracedEmployees),
+	).WithPresenter(presentEmployee).WithPagination(cfg.Pagination)
 
-// UpdateEmployee updates an employee by ID
-//
-// PUT /api/v1/employees/:id
-func (ec *EmployeeController) UpdateEmployee(c echo.Context) error {
-	// Get the employee ID from the URL
-	idStr := c.Param("id")
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid employee ID"})
-	}
+	// Create the controller streaming the employee changes
+	eventsController := NewEventsController(
+		broker,
+		time.Duration(cfg.Events.HeartbeatInterval),
+		presentEmployee,
+	)
 
-	var body UpdateEmployeeRequest
-	if err := c.Bind(&body); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]interface{}{
-			"error": "invalid request body",
-		})
-	}
+	// Create the controller of the changes feed, it shares the retained events
+	changesController := NewChangesController(broker, cfg.Pagination, presentEmployee)
 
-	// Validate the request body
-	if err := body.Validate(); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]any{"error": err})
+	// Create a new echo application
+	app := echo.New()
+	app.Server.ReadTimeout = time.Duration(cfg.Server.ReadTimeout)
+	app.Server.WriteTimeout = time.Duration(cfg.Server.WriteTimeout)
+	app.Server.IdleTimeout = time.Duration(cfg.Server.IdleTimeout)
+
+	// Client IPs are the peer addresses, X-Forwarded-For is only read from the trusted proxies
+	app.IPExtractor = echo.ExtractIPDirect()
+	if trustedProxies, _ := cfg.Server.TrustedProxyNets(); len(trustedProxies) > 0 {
+		options := []echo.TrustOption{
+			echo.TrustLoopback(false),
+			echo.TrustLinkLocal(false),
+			echo.TrustPrivateNet(false),
+		}
+		for _, trusted := range trustedProxies {
+			options = append(options, echo.TrustIPRange(trusted))
+		}
+		app.IPExtractor = echo.ExtractIPFromXFFHeader(options...)
 	}
 
-	// Update the employee in the repository
-	employee, err := ec.repo.UpdateEmployee(id, body.Name, body.Position, body.Salary)
-	if err != nil && errors.Is(err, respository.ErrRecordNotFound) {
-		return c.JSON(http.StatusNotFound, map[string]string{"error": "employee not found"})
-	}
-	if err != nil {
-		return err
-	}
+	// Create the server, it drains the in-flight requests on SIGINT or SIGTERM
+	srv := server.New(app, time.Duration(cfg.Server.ShutdownTimeout), logger)
+	srv.SetPreStopDelay(time.Duration(cfg.Server.PreStopDelay))
+	app.Server.RegisterOnShutdown(broker.Close) // End the event streams, they never drain
 
-	// Return the updated employee
-	// We can also return 204 No Content if we don't want to return the updated employee
-	return c.JSON(http.StatusOK, employee)
-}
+	// add middleware
+	app.Pre(middleware.RemoveTrailingSlash()) // Remove trailing slash from the URL
+	app.Use(logging.RequestID())              // Propagate or generate the X-Request-ID header
+	// Trace all requests, the parent span is read from the W3C traceparent header
+	app.Use(tracing.Middleware(tracerProvider, propagation.TraceContext{}))
+	app.Use(appMetrics.Middleware())    // Count and time all requests
+	app.Use(logging.Middleware(logger)) // Log all requests, without their bodies
+	app.Use(middleware.Recover())       // Recover from panics
 
-// DeleteEmployee deletes an employee by ID
-//
-// DELETE /api/v1/employees/:id
-func (ec *EmployeeController) DeleteEmployee(c echo.Context) error {
-	// Get the employee ID from the URL
-	idStr := c.Param("id")
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid employee ID"})
-	}
+	// Define routes
+	// Role-based access control on the route names
+	authorize := auth.Authorize(auth.DefaultPolicy)
+
+	// Grouping routes under /api/v1, all of them require an API key or a bearer
+	// token granting a role that is allowed to call the route, and are rate limited
+	// per client. Failed authentications are rate limited per IP beforehand.
+	apiV1Group := app.Group(
+		"/api/v1",
+		ratelimit.AuthFailureMiddleware(limiter),
+		apikey.Middleware(apiKeyManager),
+		authenticate,
+		ratelimit.Middleware(limiter),
+		authorize,
+	)
+	empGroup := apiV1Group.Group("/employees")
+	changesGroup := apiV1Group.Group("/changes")
+	apiKeyGroup := apiV1Group.Group("/admin/api-keys")
+	rateLimitGroup := apiV1Group.Group("/admin/rate-limits")
+	webhookGroup := apiV1Group.Group("/admin/webhooks")
 
-	// Delete the employee from the repository
-	err = ec.repo.DeleteEmployee(id)
-	if err != nil && errors.Is(err, respository.ErrRecordNotFound) {
-		// Return 404 if the employee is not found
-		// Or we can treat this as a successful deletion as well
-		// and return 204 instead of 404
-		return c.JSON(http.StatusNotFound, map[string]string{"error": "employee not found"})
-	}
-	if err != nil {
-		return err
-	}
+	// Define employee routes
+	eventsController.Register(empGroup)
+	empController.Register(empGroup)
 
-	// Return 204 if the employee is successfully deleted
-	return c.NoContent(http.StatusNoContent)
-}
+	// Define changes feed routes
+	changesController.Register(changesGroup)
 
-// GetAllEmployees retrieves all employees
-//
-// GET /api/v1/employees
-func (ec *EmployeeController) GetAllEmployees(c echo.Context) error {
-	// Get the page and limit query parameters
-	pageStr := c.QueryParam("page")
-	limitStr := c.QueryParam("limit")
+	// Define API key admin routes
+	apiKeyController.Register(apiKeyGroup)
 
-	// Set default page to 1
-	if pageStr == "" {
-		pageStr = "1"
-	}
+	// Define rate limit admin routes
+	rateLimitController.Register(rateLimitGroup)
 
-	// Set default limit to 10
-	if limitStr == "" {
-		limitStr = "10"
-	}
+	// Define webhook admin routes
+	webhookController.Register(webhookGroup)
 
-	// Convert page and limit to integer
-	page, err := strconv.Atoi(pageStr)
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid page number"})
-	}
-	if page <= 0 {
-		return c.JSON(
-			http.StatusBadRequest,
-			map[string]string{"error": "page number should be greater than 0"},
+	// Readiness checks, backends register their own
+	healthChecks := health.NewRegistry(time.Duration(cfg.Health.CheckTimeout))
+	healthChecks.Register("draining", health.Draining(srv.Ready))
+	healthChecks.Register("employee-repository", health.CheckerFunc(empRepo.Ping))
+	if cfg.Storage.Backend != config.BackendMemory {
+		healthChecks.Register(
+			"disk-space",
+			health.DiskSpace(cfg.Storage.DataDir, uint64(cfg.Health.MinFreeDiskBytes)),
 		)
 	}
 
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid limit number"})
+	// Liveness and readiness probes (public)
+	app.GET("/healthz/live", health.Live).Name = "health.live"
+	app.GET("/healthz/ready", healthChecks.Ready).Name = "health.ready"
+
+	// Ping or Health check endpoint (public), it fails once the server is shutting down
+	app.GET("/ping", func(c echo.Context) error {
+		if !srv.Ready() {
+			return c.String(http.StatusServiceUnavailable, "shutting down")
+		}
+		return c.String(http.StatusOK, "pong")
+	}).Name = "ping"
+
+	// Create the admin server, it is only reachable on its own address
+	adminServer := admin.New(cfg.Admin.Token)
+	adminServer.HandleRoutes(app)
+	adminServer.HandleConfig(func() any {
+		// The rate limits are read from the limiter as they can be changed at runtime
+		effective := cfg.Masked()
+		effective.RateLimits = limiter.Limits()
+		return effective
+	})
+	adminServer.HandleMetrics(appMetrics.Handler())
+	adminServer.HandleHealth(healthChecks.Details)
+	adminServer.HandleLogLevel(logLevel)
+	if dualWrite != nil {
+		adminServer.HandleBackfill(dualWrite.Backfill)
+	}
+	if chaosInjector != nil {
+		adminServer.HandleChaos(chaosInjector)
+	}
+	adminServer.HandleCompaction(map[string]admin.Compactor{
+		"employee": admin.CompactorFunc(func(context.Context) error {
+			empRepo.Compact()
+			// Prune the versions past the retention
+			if employeeHistory != nil && cfg.Storage.HistoryRetention > 0 {
+				before := time.Now().Add(-time.Duration(cfg.Storage.HistoryRetention))
+				pruned := employeeHistory.Prune(before)
+				logger.Info("employee history pruned", "versions", pruned, "before", before)
+			}
+			// With the file backend, rewrite the snapshot from the compacted store
+			if cfg.Storage.Backend == config.BackendFile {
+				return respository.SaveSnapshotFile(snapshotFile, empRepo)
+			}
+			// With the eventsourced backend, snapshot the compacted store
+			if eventSourced != nil {
+				return eventSourced.Snapshot()
+			}
+			return nil
+		}),
+	})
+
+	// Stop on SIGINT or SIGTERM, a second signal exits immediately
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	// Relay the outbox until the last request is drained, and once more afterwards
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	relayDone := make(chan struct{})
+	go func() {
+		defer close(relayDone)
+		relay.Run(relayCtx)
+	}()
+	srv.OnShutdown("outbox relay", func(ctx context.Context) error {
+		stopRelay()
+		<-relayDone
+		return relay.Drain(ctx)
+	})
+
+	// Deliver the events to the webhooks. On shutdown, the deliveries of the events relayed
+	// until then get the rest of the deadline to settle, and the outbox is only acknowledged
+	// up to the settled ones: the others are delivered again after a restart.
+	webhookCtx, stopWebhooks := context.WithCancel(context.Background())
+	webhooksDone := make(chan struct{})
+	go func() {
+		defer close(webhooksDone)
+		dispatcher.Run(webhookCtx)
+	}()
+	srv.OnShutdown("webhooks", func(ctx context.Context) error {
+		if err := dispatcher.Flush(ctx); err != nil {
+			logger.Warn("webhook deliveries left pending", "error", err)
+		}
+		stopWebhooks()
+		select {
+		case <-webhooksDone:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		return relay.Drain(ctx) // Acknowledge the events settled since the last drain
+	})
+
+	// Snapshot the file backends periodically and once more after the last request,
+	// the API keys and the webhooks are snapshotted with the eventsourced backend too.
+	// The returned save takes a snapshot on demand, the snapshots of a file are
+	// serialized so that an older one never replaces a newer one.
+	persist := func(name string, path string, repo respository.Snapshotter) func() error {
+		mu := &sync.Mutex{}
+		save := func() error {
+			mu.Lock()
+			defer mu.Unlock()
+			return respository.SaveSnapshotFile(path, repo)
+		}
+		go func() {
+			ticker := time.NewTicker(time.Duration(cfg.Storage.SnapshotInterval))
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if err := save(); err != nil {
+						logger.Error(name+" failed", "error", err)
+					}
+				}
+			}
+		}()
+		srv.OnShutdown(name, func(context.Context) error {
+			return save()
+		})
+		return save
 	}
-	if limit <= 0 {
-		limit = -1 // -1 means no limit
+	if cfg.Storage.Backend == config.BackendFile {
+		persist("employee snapshot", snapshotFile, empRepo)
+	}
+	if cfg.Storage.Backend != config.BackendMemory {
+		// The API key changes are saved before they are acknowledged
+		apiKeyManager.SetPersist(persist("api key snapshot", apiKeysFile, apiKeyRepo))
+		dispatcher.SetPersist(persist("webhook snapshot", webhooksFile, webhookRepo))
+	}
+	if secondaryRepo != nil {
+		persist("migration snapshot", secondaryFile, secondaryRepo)
 	}
 
-	// Retrieve employees from the repository
-	employees, total := ec.repo.GetAllEmployees(page, limit)
+	// Snapshot the event log, so that the next start only folds the events following it
+	if eventSourced != nil {
+		srv.OnShutdown("event snapshot", func(context.Context) error {
+			return errors.Join(eventSourced.Snapshot(), eventStore.Close())
+		})
+	}
 
-	// Create a list response
-	response := ListResponse{
-		Page:  page,
-		Limit: limit,
-		Total: total,
-		Data:  employees,
+	// Flush the pending spans
+	srv.OnShutdown("tracing", tracerProvider.Shutdown)
+
+	// Start the admin server next to the echo application, both stop on the same signal
+	// and neither runs without the other
+	adminErr := make(chan error, 1)
+	if cfg.Admin.Address == "" {
+		adminErr <- nil
+	} else {
+		adminSrv := server.New(
+			adminServer.Echo(),
+			time.Duration(cfg.Server.ShutdownTimeout),
+			logger.With("server", "admin"),
+		)
+		go func() {
+			err := adminSrv.Run(ctx, cfg.Admin.Address)
+			stop() // Stop the echo application too if the admin server failed to start
+			adminErr <- err
+		}()
 	}
 
-	// Return the list response
-	return c.JSON(http.StatusOK, response)
+	// Start the echo application and wait for the graceful shutdown
+	err = srv.Run(ctx, cfg.Server.Address)
+	stop() // Stop the admin server too if the echo application failed to start
+	if err := errors.Join(err, <-adminErr); err != nil {
+		logger.Error("shutdown failed", "error", err)
+		os.Exit(1)
+	}
+	logger.Info("shutdown complete")
+}
+
+// decorateStorage wraps the stored employees with the cache, unless its size is
+// 0, and then with the faults of the injector, if any
+//
+// The faults are injected above the cache, so that cache hits fail too.
+func decorateStorage(
+	stored respository.IEmployeeRepository,
+	cacheSize int,
+	cacheTTL time.Duration,
+	injector *chaos.Injector,
+) (respository.IEmployeeRepository, *cache.CachedEmployeeRepository) {
+	var cached *cache.CachedEmployeeRepository
+	if cacheSize > 0 {
+		cached = cache.CacheEmployeeRepository(stored, cacheSize, cacheTTL)
+		stored = cached
+	}
+	if injector != nil {
+		stored = injector.Wrap(stored)
+	}
+	return stored, cached
 }
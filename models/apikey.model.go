@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Ensure type implements the interface
+var _ Entity[APIKey] = APIKey{}
+
+// APIKey is a credential for service-to-service clients
+//
+// Only a hash of the key is stored. The prefix is stored in clear text so that
+// the key can be looked up without scanning every hash.
+type APIKey struct {
+	ID         ID         `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Hash       string     `json:"-"`      // Hex encoded SHA-256 of the full key
+	Scopes     []string   `json:"scopes"` // Route names the key may call, e.g. "employee.list"
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+func (k APIKey) GetID() ID {
+	return k.ID
+}
+
+func (k APIKey) WithID(id ID) APIKey {
+	k.ID = id
+	return k
+}
+
+// Expired reports whether the key is expired at the given time
+func (k APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// Revoked reports whether the key has been revoked
+func (k APIKey) Revoked() bool {
+	return k.RevokedAt != nil
+}
@@ -1,8 +1,33 @@
 package models
 
+// Ensure type implements the interface
+var _ Entity[Employee] = Employee{}
+
 type Employee struct {
-	ID       int     `json:"id"`
+	ID       ID      `json:"id"`
 	Name     string  `json:"name"`
+	Email    string  `json:"email"` // Work email, unique across employees
 	Position string  `json:"position"`
 	Salary   float64 `json:"salary"`
 }
+
+func (e Employee) GetID() ID {
+	return e.ID
+}
+
+func (e Employee) WithID(id ID) Employee {
+	e.ID = id
+	return e
+}
+
+// RedactedEmployee is the representation of an employee without the salary,
+// for clients that are not allowed to see it
+type RedactedEmployee struct {
+	Employee
+	Salary *float64 `json:"salary,omitempty"` // Shadows Employee.Salary and is always nil
+}
+
+// Redacted returns the employee without the salary
+func (e Employee) Redacted() RedactedEmployee {
+	return RedactedEmployee{Employee: e}
+}
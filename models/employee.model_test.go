@@ -0,0 +1,20 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmployee_Redacted(t *testing.T) {
+	employee := Employee{ID: "1", Name: "Ganesh Agrawal", Salary: 1234.00}
+
+	data, err := json.Marshal(employee.Redacted())
+	assert.Nil(t, err, "error should be nil")
+	assert.JSONEq(
+		t,
+		`{"id":"1","name":"Ganesh Agrawal","email":"","position":""}`,
+		string(data),
+	)
+}
@@ -0,0 +1,10 @@
+package models
+
+// Entity is implemented by every model that can be stored in a generic repository
+//
+// T is the model type itself, so WithID can return a copy of the concrete model
+// without type assertions.
+type Entity[T any] interface {
+	GetID() ID
+	WithID(id ID) T
+}
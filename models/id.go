@@ -0,0 +1,116 @@
+package models
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// ID is the identifier of an entity
+//
+// IDs are opaque strings. Depending on the configured ID generation strategy
+// they may look like "42", a UUID, a ULID or a snowflake.
+type ID string
+
+// numericIDs enables the compatibility mode for clients that expect numeric IDs
+var numericIDs atomic.Bool
+
+// SetNumericIDs toggles the compatibility mode for numeric clients
+//
+// When enabled, IDs made only of digits are encoded as JSON numbers (as they were
+// before IDs became strings) and non-numeric IDs are rejected by ParseID.
+func SetNumericIDs(enabled bool) {
+	numericIDs.Store(enabled)
+}
+
+// NumericIDs reports whether the compatibility mode for numeric clients is enabled
+func NumericIDs() bool {
+	return numericIDs.Load()
+}
+
+// ParseID parses an ID received from a client, e.g. from a URL parameter
+//
+// In compatibility mode, numeric IDs are canonicalized, e.g. "007" is ID 7.
+func ParseID(s string) (ID, error) {
+	if s == "" {
+		return "", errors.New("empty ID")
+	}
+	if NumericIDs() {
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return "", errors.New("ID must be numeric")
+		}
+		return ID(strconv.FormatUint(n, 10)), nil
+	}
+	return ID(s), nil
+}
+
+func (id ID) String() string {
+	return string(id)
+}
+
+// Compare orders the IDs the way they were generated: numeric IDs by value,
+// e.g. "2" before "10", then the other IDs in lexicographic order, which is the
+// order of the UUIDv7 and ULID strategies. The empty ID comes first.
+func (id ID) Compare(other ID) int {
+	numeric, otherNumeric := id.numeric(), other.numeric()
+	switch {
+	case id == "" || other == "":
+		return cmp.Compare(len(id), len(other))
+	case numeric && otherNumeric && len(id) != len(other):
+		return cmp.Compare(len(id), len(other)) // Without leading zeros, longer is greater
+	case numeric != otherNumeric:
+		if numeric {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(string(id), string(other))
+}
+
+// numeric reports whether the ID is a number without leading zeros
+func (id ID) numeric() bool {
+	if id == "" || (len(id) > 1 && id[0] == '0') {
+		return false
+	}
+	for _, c := range []byte(id) {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (id ID) MarshalJSON() ([]byte, error) {
+	if NumericIDs() {
+		if n, err := strconv.ParseUint(string(id), 10, 64); err == nil {
+			return []byte(strconv.FormatUint(n, 10)), nil
+		}
+	}
+	return json.Marshal(string(id))
+}
+
+// UnmarshalJSON accepts both JSON strings and JSON numbers
+func (id *ID) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] != '"' {
+		var number json.Number
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&number); err != nil {
+			return err
+		}
+		*id = ID(number.String())
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*id = ID(s)
+	return nil
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestID_JSON(t *testing.T) {
+	defer SetNumericIDs(false)
+
+	// IDs are encoded as strings by default
+	SetNumericIDs(false)
+	data, err := json.Marshal(Employee{ID: "42", Name: "Ganesh Agrawal"})
+	assert.Nil(t, err, "error should be nil")
+	assert.JSONEq(t, `{"id":"42","name":"Ganesh Agrawal","email":"","position":"","salary":0}`, string(data))
+
+	// Numeric IDs are encoded as numbers in compatibility mode
+	SetNumericIDs(true)
+	data, err = json.Marshal(Employee{ID: "42"})
+	assert.Nil(t, err, "error should be nil")
+	assert.JSONEq(t, `{"id":42,"name":"","email":"","position":"","salary":0}`, string(data))
+	data, err = json.Marshal(ID("007"))
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, `7`, string(data), "numbers should not have leading zeros")
+
+	// Non-numeric IDs stay strings in compatibility mode
+	data, err = json.Marshal(ID("01HV6ZQ3"))
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, `"01HV6ZQ3"`, string(data))
+
+	// Both numbers and strings are accepted when decoding
+	var employee Employee
+	assert.Nil(t, json.Unmarshal([]byte(`{"id":7}`), &employee))
+	assert.Equal(t, ID("7"), employee.ID, "ID should be 7")
+	assert.Nil(t, json.Unmarshal([]byte(`{"id":"abc"}`), &employee))
+	assert.Equal(t, ID("abc"), employee.ID, "ID should be abc")
+}
+
+func TestParseID(t *testing.T) {
+	defer SetNumericIDs(false)
+
+	SetNumericIDs(false)
+	id, err := ParseID("018f2b9c-7e4a-7c1d-9b2f-3a4b5c6d7e8f")
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, ID("018f2b9c-7e4a-7c1d-9b2f-3a4b5c6d7e8f"), id)
+
+	_, err = ParseID("")
+	assert.NotNil(t, err, "error should not be nil for an empty ID")
+
+	// Only numeric IDs are accepted in compatibility mode
+	SetNumericIDs(true)
+	id, err = ParseID("12")
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, ID("12"), id)
+	id, err = ParseID("007")
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, ID("7"), id, "numeric IDs should be canonicalized")
+
+	_, err = ParseID("abc")
+	assert.NotNil(t, err, "error should not be nil for a non-numeric ID")
+}
+
+func TestID_Compare(t *testing.T) {
+	// Numeric IDs are ordered by value, before the other IDs
+	assert.Negative(t, ID("2").Compare("10"))
+	assert.Positive(t, ID("10").Compare("9"))
+	assert.Zero(t, ID("10").Compare("10"))
+	assert.Negative(t, ID("10").Compare("01HV3K8Z6Q7W2X9Y4T5R1S0P3M"))
+
+	// Other IDs are ordered lexicographically, like the UUIDv7 and ULID strategies generate them
+	assert.Negative(t, ID("01HV3K8Z6Q7W2X9Y4T5R1S0P3M").Compare("01HV3K8Z6Q7W2X9Y4T5R1S0P3N"))
+	assert.Positive(t, ID("007").Compare("7"), "IDs with leading zeros should not be numeric")
+	assert.Negative(t, ID("").Compare("1"), "the empty ID should come first")
+}
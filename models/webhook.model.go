@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Ensure type implements the interface
+var _ Entity[Webhook] = Webhook{}
+
+// Webhook is a subscription of a downstream system to the employee events
+//
+// The secret is stored in clear text as it is the key of the HMAC signatures of
+// the deliveries, it is never returned.
+type Webhook struct {
+	ID        ID        `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"` // Event types delivered, e.g. "created"
+	Secret    string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (w Webhook) GetID() ID {
+	return w.ID
+}
+
+func (w Webhook) WithID(id ID) Webhook {
+	w.ID = id
+	return w
+}
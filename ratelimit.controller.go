@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/ratelimit"
+	"github.com/labstack/echo/v4"
+)
+
+// RateLimitController is the controller for changing the rate limits at runtime
+type RateLimitController struct {
+	limiter *ratelimit.Limiter
+}
+
+// NewRateLimitController creates a new rate limit controller
+func NewRateLimitController(limiter *ratelimit.Limiter) *RateLimitController {
+	return &RateLimitController{limiter: limiter}
+}
+
+// Register registers the rate limit routes on the group
+func (rc *RateLimitController) Register(group *echo.Group) {
+	group.GET("", rc.GetRateLimits).Name = "ratelimit.get"
+	group.PUT("", rc.UpdateRateLimits).Name = "ratelimit.update"
+}
+
+// GetRateLimits retrieves the current rate limits
+//
+// GET /api/v1/admin/rate-limits
+func (rc *RateLimitController) GetRateLimits(c echo.Context) error {
+	return c.JSON(http.StatusOK, rc.limiter.Limits())
+}
+
+// UpdateRateLimits replaces the rate limits, they apply immediately
+//
+// PUT /api/v1/admin/rate-limits
+func (rc *RateLimitController) UpdateRateLimits(c echo.Context) error {
+	var body ratelimit.Limits
+	if err := c.Bind(&body); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "invalid request body",
+		})
+	}
+
+	// Validate and apply the limits
+	if err := rc.limiter.SetLimits(body); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	// Return the applied limits
+	return c.JSON(http.StatusOK, rc.limiter.Limits())
+}
@@ -1,10 +1,21 @@
 package main
 
-import validation "github.com/go-ozzo/ozzo-validation/v4"
+import (
+	"regexp"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+)
+
+// Ensure type implements the interface
+var _ Form[models.Employee] = CreateEmployeeRequest{}
 
 // CreateEmployeeRequest is the request body for creating an employee
 type CreateEmployeeRequest struct {
 	Name     string  `json:"name"`
+	Email    string  `json:"email"`
 	Position string  `json:"position"`
 	Salary   float64 `json:"salary"`
 }
@@ -13,13 +24,64 @@ func (form CreateEmployeeRequest) Validate() error {
 	return validation.ValidateStruct(
 		&form,
 		validation.Field(&form.Name, validation.Required, validation.Length(3, 0)),
+		validation.Field(&form.Email, is.EmailFormat),
 		validation.Field(&form.Position, validation.Required, validation.Length(3, 0)),
 		validation.Field(&form.Salary, validation.Required, validation.Min(float64(0))),
 	)
 }
 
+// ToModel converts the request body into an employee
+func (form CreateEmployeeRequest) ToModel() models.Employee {
+	return models.Employee{
+		Name:     form.Name,
+		Email:    form.Email,
+		Position: form.Position,
+		Salary:   form.Salary,
+	}
+}
+
 // UpdateEmployeeRequest is the request body for updating an employee
 //
 // It is the same as CreateEmployeeRequest. This is because the fields that can be updated.
 // If you want to add more fields to the update request, you can do so here
 type UpdateEmployeeRequest = CreateEmployeeRequest
+
+// CreateAPIKeyRequest is the request body for creating an API key
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (form CreateAPIKeyRequest) Validate() error {
+	return validation.ValidateStruct(
+		&form,
+		validation.Field(&form.Name, validation.Required, validation.Length(3, 0)),
+		validation.Field(&form.Scopes, validation.Required),
+		validation.Field(&form.ExpiresAt, validation.Min(time.Now())),
+	)
+}
+
+// webhookURLScheme matches the URLs webhooks can be delivered to
+var webhookURLScheme = regexp.MustCompile(`^https?://`)
+
+// CreateWebhookRequest is the request body for registering a webhook
+type CreateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"` // Key of the HMAC-SHA256 signatures, never returned
+}
+
+func (form CreateWebhookRequest) Validate() error {
+	return validation.ValidateStruct(
+		&form,
+		validation.Field(
+			&form.URL,
+			validation.Required,
+			is.RequestURL,
+			validation.Match(webhookURLScheme).Error("must be an http or https URL"),
+		),
+		validation.Field(&form.Events, validation.Required),
+		validation.Field(&form.Secret, validation.Required, validation.Length(16, 0)),
+	)
+}
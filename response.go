@@ -1,8 +1,45 @@
 package main
 
+import (
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/events"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+)
+
 type ListResponse struct {
 	Page  int         `json:"page"`
 	Limit int         `json:"limit"`
 	Total int         `json:"total"`
 	Data  interface{} `json:"data"`
 }
+
+// APIKeyResponse is the response of creating or rotating an API key
+type APIKeyResponse struct {
+	Key    string        `json:"key"` // Plain text key, only returned once
+	APIKey models.APIKey `json:"api_key"`
+}
+
+// EventResponse is the data of an employee change sent on the event stream
+type EventResponse struct {
+	Seq      uint64      `json:"seq"`
+	Type     events.Type `json:"type"`
+	Time     time.Time   `json:"time"`
+	Employee any         `json:"employee"` // Presented employee, without the salary for some roles
+}
+
+// ChangesResponse is a page of the changes feed
+type ChangesResponse struct {
+	Changes   []ChangeResponse `json:"changes"`
+	NextSince string           `json:"next_since"` // Cursor to pass as since on the next call
+	HasMore   bool             `json:"has_more"`   // More changes are available right away
+}
+
+// ChangeResponse is a change record of the changes feed
+type ChangeResponse struct {
+	Seq      uint64    `json:"seq"`
+	Op       string    `json:"op"` // "upsert" or "delete"
+	ID       models.ID `json:"id"`
+	Time     time.Time `json:"time"`
+	Employee any       `json:"employee,omitempty"` // Presented employee, omitted on tombstones
+}
@@ -0,0 +1,118 @@
+package respository
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/idgen"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+)
+
+// Ensure type implements the interface
+var _ IAPIKeyRepository = (*APIKeyInMemoryRepository)(nil)
+var _ Snapshotter = (*APIKeyInMemoryRepository)(nil)
+
+// APIKeyInMemoryRepository is an in-memory repository for API keys
+type APIKeyInMemoryRepository struct {
+	*InMemoryRepository[models.APIKey]
+	prefixes *apiKeyPrefixIndex // Unique index on the key prefix
+}
+
+// NewAPIKeyInMemoryRepository creates a new in-memory repository for API keys
+func NewAPIKeyInMemoryRepository(idGenerator idgen.IDGenerator) *APIKeyInMemoryRepository {
+	prefixes := &apiKeyPrefixIndex{ids: make(map[string]models.ID)}
+	return &APIKeyInMemoryRepository{
+		InMemoryRepository: NewInMemoryRepository[models.APIKey]("api key", idGenerator, prefixes),
+		prefixes:           prefixes,
+	}
+}
+
+// GetByPrefix retrieves an API key by its prefix
+func (repo *APIKeyInMemoryRepository) GetByPrefix(prefix string) (models.APIKey, error) {
+	var (
+		key models.APIKey
+		ok  bool
+	)
+	repo.View(func(get func(id models.ID) (models.APIKey, bool)) {
+		var id models.ID
+		if id, ok = repo.prefixes.ids[prefix]; ok {
+			key, ok = get(id)
+		}
+	})
+
+	if !ok {
+		return models.APIKey{}, fmt.Errorf(
+			"api key with prefix %s not found: %w",
+			prefix,
+			ErrRecordNotFound,
+		)
+	}
+	return key, nil
+}
+
+// storedAPIKey is an API key as saved in the snapshots, with the hash the
+// responses never include
+type storedAPIKey struct {
+	models.APIKey
+	Hash string `json:"hash"` // Shadows APIKey.Hash
+}
+
+// Snapshot writes every API key with its hash, in insertion order, as a JSON array
+func (repo *APIKeyInMemoryRepository) Snapshot(w io.Writer) error {
+	keys, _ := repo.GetAll(1, 0)
+	stored := make([]storedAPIKey, 0, len(keys))
+	for _, key := range keys {
+		stored = append(stored, storedAPIKey{APIKey: key, Hash: key.Hash})
+	}
+
+	if err := json.NewEncoder(w).Encode(stored); err != nil {
+		return fmt.Errorf("api key snapshot failed: %w", err)
+	}
+	return nil
+}
+
+// Restore loads the API keys written by Snapshot into the empty repository
+func (repo *APIKeyInMemoryRepository) Restore(r io.Reader) error {
+	var stored []storedAPIKey
+	if err := json.NewDecoder(r).Decode(&stored); err != nil {
+		return fmt.Errorf("api key restore failed: %w", err)
+	}
+	if repo.Len() > 0 {
+		return fmt.Errorf("api key restore failed: repository is not empty")
+	}
+
+	for _, key := range stored {
+		key.APIKey.Hash = key.Hash
+		if err := repo.Import(key.APIKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Ensure type implements the interface
+var _ UniqueIndex[models.APIKey] = (*apiKeyPrefixIndex)(nil)
+
+// apiKeyPrefixIndex is the unique index of API keys on their prefix
+//
+// This implementation is non-thread-safe, the owning repository must hold its lock.
+type apiKeyPrefixIndex struct {
+	ids map[string]models.ID // Prefix to API key ID
+}
+
+// Check rejects the key if another key already uses its prefix
+func (idx *apiKeyPrefixIndex) Check(key models.APIKey) error {
+	if id, ok := idx.ids[key.Prefix]; ok && id != key.ID {
+		return &DuplicateError{Field: "prefix", ExistingID: id}
+	}
+	return nil
+}
+
+func (idx *apiKeyPrefixIndex) Add(key models.APIKey) {
+	idx.ids[key.Prefix] = key.ID
+}
+
+func (idx *apiKeyPrefixIndex) Remove(key models.APIKey) {
+	delete(idx.ids, key.Prefix)
+}
@@ -0,0 +1,15 @@
+package respository
+
+import (
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+)
+
+// IAPIKeyRepository is an interface for API key repository
+type IAPIKeyRepository interface {
+	Repository[models.APIKey]
+	UpdateFunc(
+		id models.ID,
+		fn func(current models.APIKey) (models.APIKey, error),
+	) (models.APIKey, error)
+	GetByPrefix(prefix string) (models.APIKey, error)
+}
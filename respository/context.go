@@ -0,0 +1,25 @@
+package respository
+
+import "context"
+
+// ContextBinder is implemented by repositories that use the context of the
+// request, e.g. to attach spans to its trace
+//
+// WithContext returns a view of the repository bound to the context. Decorators
+// must implement it and bind the repository they wrap, so that the context
+// reaches every layer.
+type ContextBinder[R any] interface {
+	WithContext(ctx context.Context) R
+}
+
+// BindContext returns the repository bound to the context when it supports it,
+// and the repository itself otherwise
+//
+// R is the interface the repository is used as, e.g. IEmployeeRepository, as
+// WithContext returns that interface.
+func BindContext[R any](ctx context.Context, repo R) R {
+	if binder, ok := any(repo).(ContextBinder[R]); ok {
+		return binder.WithContext(ctx)
+	}
+	return repo
+}
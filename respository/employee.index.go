@@ -0,0 +1,94 @@
+package respository
+
+import (
+	"strings"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/datatypes"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+)
+
+// salaryIndexKey is the key of the secondary index on employee salary.
+// The employee ID breaks ties so that equal salaries do not collide, and are
+// in the order the employees were created.
+type salaryIndexKey struct {
+	salary float64
+	id     models.ID
+}
+
+func salaryIndexLess(a, b salaryIndexKey) bool {
+	if a.salary != b.salary {
+		return a.salary < b.salary
+	}
+	return a.id.Compare(b.id) < 0
+}
+
+// nameIndexKey is the key of the secondary index on employee name.
+// The employee ID breaks ties so that equal names do not collide, and are in
+// the order the employees were created.
+type nameIndexKey struct {
+	name string
+	id   models.ID
+}
+
+func nameIndexLess(a, b nameIndexKey) bool {
+	if a.name != b.name {
+		return a.name < b.name
+	}
+	return a.id.Compare(b.id) < 0
+}
+
+// Ensure type implements the interface
+var _ UniqueIndex[models.Employee] = (*employeeIndexes)(nil)
+
+// employeeIndexes holds the secondary indexes of the employee store:
+// sorted indexes on salary and name, and a unique index on email
+//
+// This implementation is non-thread-safe, the owning repository must hold its lock.
+type employeeIndexes struct {
+	salary *datatypes.BTree[salaryIndexKey]
+	name   *datatypes.BTree[nameIndexKey]
+	email  map[string]models.ID // Normalized email to employee ID
+}
+
+func newEmployeeIndexes() *employeeIndexes {
+	return &employeeIndexes{
+		salary: datatypes.NewBTree(salaryIndexLess),
+		name:   datatypes.NewBTree(nameIndexLess),
+		email:  make(map[string]models.ID),
+	}
+}
+
+// normalizeEmail returns the form of the email used for uniqueness checks
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Check rejects the employee if another employee already uses its email,
+// employees without an email never conflict
+func (idx *employeeIndexes) Check(employee models.Employee) error {
+	if normalizeEmail(employee.Email) == "" {
+		return nil
+	}
+	if id, ok := idx.email[normalizeEmail(employee.Email)]; ok && id != employee.ID {
+		return &DuplicateError{Field: "email", ExistingID: id}
+	}
+	return nil
+}
+
+// Add indexes the employee
+func (idx *employeeIndexes) Add(employee models.Employee) {
+	idx.salary.Set(salaryIndexKey{salary: employee.Salary, id: employee.ID})
+	idx.name.Set(nameIndexKey{name: employee.Name, id: employee.ID})
+	if email := normalizeEmail(employee.Email); email != "" {
+		idx.email[email] = employee.ID
+	}
+}
+
+// Remove drops the employee from the indexes
+func (idx *employeeIndexes) Remove(employee models.Employee) {
+	idx.salary.Delete(salaryIndexKey{salary: employee.Salary, id: employee.ID})
+	idx.name.Delete(nameIndexKey{name: employee.Name, id: employee.ID})
+	if idx.email[normalizeEmail(employee.Email)] == employee.ID {
+		delete(idx.email, normalizeEmail(employee.Email))
+	}
+}
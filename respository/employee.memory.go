@@ -1,126 +1,88 @@
 package respository
 
 import (
-	"fmt"
-	"sync"
+	"strings"
 
-	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/datatypes"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/idgen"
 	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
 )
 
 // Ensure type implements the interface
 var _ IEmployeeRepository = (*EmployeeInMemoryRepository)(nil)
+var _ IEmployeeImporter = (*EmployeeInMemoryRepository)(nil)
+var _ Repository[models.Employee] = (*EmployeeInMemoryRepository)(nil)
 
 // EmployeeInMemoryRepository is an in-memory repository for employees
+//
+// It is built on top of the generic InMemoryRepository, which also makes it
+// usable anywhere a Repository[models.Employee] is expected.
 type EmployeeInMemoryRepository struct {
-	mu     *sync.RWMutex                               // Mutex for thread-safety
-	store  *datatypes.OrderedMap[int, models.Employee] // In-memory database
-	nextId int                                         // Next available ID for the next employee
+	*InMemoryRepository[models.Employee]
+	index *employeeIndexes // Sorted secondary indexes on salary and name
 }
 
 // NewEmployeeInMemoryRepository creates a new in-memory repository for employees
+// with sequential IDs
 func NewEmployeeInMemoryRepository() *EmployeeInMemoryRepository {
+	return NewEmployeeInMemoryRepositoryWithIDGenerator(idgen.NewSequential())
+}
+
+// NewEmployeeInMemoryRepositoryWithIDGenerator creates a new in-memory repository for employees
+// with the given ID generation strategy
+func NewEmployeeInMemoryRepositoryWithIDGenerator(
+	idGenerator idgen.IDGenerator,
+) *EmployeeInMemoryRepository {
+	index := newEmployeeIndexes()
 	return &EmployeeInMemoryRepository{
-		mu:     &sync.RWMutex{},
-		store:  datatypes.NewOrderedMap[int, models.Employee](),
-		nextId: 1,
+		InMemoryRepository: NewInMemoryRepository[models.Employee]("employee", idGenerator, index),
+		index:              index,
 	}
 }
 
 // CreateEmployee creates a new employee
 func (repo *EmployeeInMemoryRepository) CreateEmployee(
 	name string,
+	email string,
 	position string,
 	salary float64,
 ) (models.Employee, error) {
-	repo.mu.Lock()         // Lock the mutex
-	defer repo.mu.Unlock() // Unlock the mutex when the function returns
-
-	// Create a new employee
-	employee := models.Employee{
-		ID:       repo.nextId, // Assign the next available ID
-		Name:     name,
-		Position: position,
-		Salary:   salary,
-	}
-
-	// Store the employee in the store (in-memory database)
-	repo.store.Set(employee.ID, employee)
-
-	// Increment the next available ID
-	repo.nextId++
-
-	// Return the created employee
-	return employee, nil
+	return repo.Create(
+		models.Employee{Name: name, Email: email, Position: position, Salary: salary},
+	)
 }
 
 // GetEmployeeByID retrieves an employee by ID
-func (repo *EmployeeInMemoryRepository) GetEmployeeByID(id int) (models.Employee, error) {
-	repo.mu.RLock()         // Lock the mutex for reading
-	defer repo.mu.RUnlock() // Unlock the mutex when the function returns
-
-	// Retrieve the employee from the store
-	employee, ok := repo.store.Get(id)
-	if !ok {
-		return models.Employee{}, fmt.Errorf(
-			"employee with ID %d not found: %w",
-			id,
-			ErrRecordNotFound,
-		)
-	}
-
-	// Return the employee
-	return employee, nil
+func (repo *EmployeeInMemoryRepository) GetEmployeeByID(id models.ID) (models.Employee, error) {
+	return repo.GetByID(id)
 }
 
 // UpdateEmployee updates an employee by ID
 func (repo *EmployeeInMemoryRepository) UpdateEmployee(
-	id int,
+	id models.ID,
 	name string,
+	email string,
 	position string,
 	salary float64,
 ) (models.Employee, error) {
-	repo.mu.Lock()         // Lock the mutex
-	defer repo.mu.Unlock() // Unlock the mutex when the function returns
-
-	// Retrieve the employee from the store
-	employee, ok := repo.store.Get(id)
-	if !ok {
-		return models.Employee{}, fmt.Errorf(
-			"employee with ID %d update failed: %w",
-			id,
-			ErrRecordNotFound,
-		)
-	}
-
-	// Update the employee
-	employee.Name = name
-	employee.Position = position
-	employee.Salary = salary
-
-	// Store the updated employee in the store
-	repo.store.Set(employee.ID, employee)
-
-	// Return the updated employee
-	return employee, nil
+	return repo.Update(
+		id,
+		models.Employee{Name: name, Email: email, Position: position, Salary: salary},
+	)
 }
 
 // DeleteEmployee deletes an employee by ID
-func (repo *EmployeeInMemoryRepository) DeleteEmployee(id int) error {
-	repo.mu.Lock()         // Lock the mutex
-	defer repo.mu.Unlock() // Unlock the mutex when the function returns
-
-	// Delete the employee from the store
-	if ok := repo.store.Delete(id); !ok {
-		return fmt.Errorf(
-			"employee with ID %d delete failed: %w",
-			id,
-			ErrRecordNotFound,
-		)
-	}
+func (repo *EmployeeInMemoryRepository) DeleteEmployee(id models.ID) error {
+	return repo.Delete(id)
+}
+
+// ImportEmployee stores an employee under its own ID
+func (repo *EmployeeInMemoryRepository) ImportEmployee(employee models.Employee) error {
+	return repo.Import(employee)
+}
 
-	// Return nil (no error)
-	return nil
+// ReplaceEmployees replaces every employee with the given ones, in order
+func (repo *EmployeeInMemoryRepository) ReplaceEmployees(employees []models.Employee) error {
+	return repo.ReplaceAll(employees)
 }
 
 // GetAllEmployees retrieves all employees and total count
@@ -128,40 +90,50 @@ func (repo *EmployeeInMemoryRepository) GetAllEmployees(
 	page int,
 	limit int,
 ) ([]models.Employee, int) {
-	repo.mu.RLock()         // Lock the mutex for reading
-	defer repo.mu.RUnlock() // Unlock the mutex when the function returns
-
-	// Handle pagination
-	if page <= 0 {
-		// If page is less than or equal to 0, set it to 1
-		page = 1
-	}
-	if limit <= 0 {
-		// If limit is less than or equal to 0, return all employees
-		limit = repo.store.Len()
-	}
-	// Calculate the offset for pagination
-	offset := (page - 1) * limit
-
-	// Check if the offset is out of bounds
-	if offset >= repo.store.Len() {
-		return []models.Employee{}, repo.store.Len()
-	}
-
-	// Retrieve all employees from the store (in-memory database) with pagination
-	employees := make([]models.Employee, 0, limit)
-
-	empIds := repo.store.Keys()
-	empIds = empIds[offset:]
+	return repo.GetAll(page, limit)
+}
 
-	for i, id := range empIds {
-		employee, _ := repo.store.Get(id)
-		employees = append(employees, employee)
-		if i == limit-1 {
-			break
-		}
-	}
+// GetEmployeesBySalaryRange retrieves all employees with a salary between min and max (inclusive),
+// ordered by salary
+func (repo *EmployeeInMemoryRepository) GetEmployeesBySalaryRange(
+	min float64,
+	max float64,
+) []models.Employee {
+	employees := make([]models.Employee, 0)
+
+	repo.View(func(get func(id models.ID) (models.Employee, bool)) {
+		// Scan the salary index starting at the lowest possible key for min
+		from := salaryIndexKey{salary: min, id: ""}
+		repo.index.salary.AscendGreaterOrEqual(from, func(key salaryIndexKey) bool {
+			if key.salary > max {
+				return false
+			}
+			employee, _ := get(key.id)
+			employees = append(employees, employee)
+			return true
+		})
+	})
+
+	return employees
+}
 
-	// Return all employees
-	return employees, repo.store.Len()
+// GetEmployeesByNamePrefix retrieves all employees whose name starts with the prefix,
+// ordered by name
+func (repo *EmployeeInMemoryRepository) GetEmployeesByNamePrefix(prefix string) []models.Employee {
+	employees := make([]models.Employee, 0)
+
+	repo.View(func(get func(id models.ID) (models.Employee, bool)) {
+		// Names sharing a prefix are contiguous in the name index
+		from := nameIndexKey{name: prefix, id: ""}
+		repo.index.name.AscendGreaterOrEqual(from, func(key nameIndexKey) bool {
+			if !strings.HasPrefix(key.name, prefix) {
+				return false
+			}
+			employee, _ := get(key.id)
+			employees = append(employees, employee)
+			return true
+		})
+	})
+
+	return employees
 }
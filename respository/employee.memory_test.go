@@ -1,8 +1,10 @@
 package respository
 
 import (
+	"fmt"
 	"testing"
 
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/idgen"
 	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
 	"github.com/stretchr/testify/assert"
 )
@@ -12,21 +14,22 @@ func TestEmployeeInMemoryRepository_CreateEmployee(t *testing.T) {
 	repo := NewEmployeeInMemoryRepository()
 
 	// Create an employee
-	name, position, salary := "Ganesh Agrawal", "Software Engineer", 1234.00
-	emp, err := repo.CreateEmployee(name, position, salary)
+	name, email, position, salary := "Ganesh Agrawal", "ganesh@example.com", "Software Engineer", 1234.00
+	emp, err := repo.CreateEmployee(name, email, position, salary)
 
 	assert.Nil(t, err, "error should be nil")
-	assert.Equal(t, 1, emp.ID, "ID should be 1")
+	assert.Equal(t, models.ID("1"), emp.ID, "ID should be 1")
 	assert.Equal(t, name, emp.Name, "Name should be %s", name)
+	assert.Equal(t, email, emp.Email, "Email should be %s", email)
 	assert.Equal(t, position, emp.Position, "Position should be %s", position)
 	assert.Equal(t, salary, emp.Salary, "Salary should be %s", salary)
 
 	// Create another employee
-	name, position, salary = "Harshit Kumar", "DevOps Engineer", 1235.00
-	emp, err = repo.CreateEmployee(name, position, salary)
+	name, email, position, salary = "Harshit Kumar", "harshit@example.com", "DevOps Engineer", 1235.00
+	emp, err = repo.CreateEmployee(name, email, position, salary)
 
 	assert.Nil(t, err, "error should be nil")
-	assert.Equal(t, 2, emp.ID, "ID should be 2")
+	assert.Equal(t, models.ID("2"), emp.ID, "ID should be 2")
 	assert.Equal(t, name, emp.Name, "Name should be %s", name)
 	assert.Equal(t, position, emp.Position, "Position should be %s", position)
 	assert.Equal(t, salary, emp.Salary, "Salary should be %s", salary)
@@ -37,8 +40,8 @@ func TestEmployeeInMemoryRepository_GetEmployeeByID(t *testing.T) {
 	repo := NewEmployeeInMemoryRepository()
 
 	// Create an employee
-	name, position, salary := "Ganesh Agrawal", "Software Engineer", 1234.00
-	emp, _ := repo.CreateEmployee(name, position, salary)
+	name, email, position, salary := "Ganesh Agrawal", "ganesh@example.com", "Software Engineer", 1234.00
+	emp, _ := repo.CreateEmployee(name, email, position, salary)
 
 	// Retrieve the employee by ID
 	empByID, err := repo.GetEmployeeByID(emp.ID)
@@ -50,7 +53,7 @@ func TestEmployeeInMemoryRepository_GetEmployeeByID(t *testing.T) {
 	assert.Equal(t, emp.Salary, empByID.Salary, "Salary should be %s", emp.Salary)
 
 	// Retrieve an employee by an invalid ID
-	empByID, err = repo.GetEmployeeByID(2)
+	empByID, err = repo.GetEmployeeByID("2")
 
 	assert.NotNil(t, err, "error should not be nil")
 	assert.ErrorIs(
@@ -62,8 +65,8 @@ func TestEmployeeInMemoryRepository_GetEmployeeByID(t *testing.T) {
 	assert.Equal(t, models.Employee{}, empByID, "employee should be empty")
 
 	// Create another employee
-	name, position, salary = "Harshit Kumar", "DevOps Engineer", 1235.00
-	emp, _ = repo.CreateEmployee(name, position, salary)
+	name, email, position, salary = "Harshit Kumar", "harshit@example.com", "DevOps Engineer", 1235.00
+	emp, _ = repo.CreateEmployee(name, email, position, salary)
 
 	// Retrieve the employee by ID
 	empByID, err = repo.GetEmployeeByID(emp.ID)
@@ -80,12 +83,12 @@ func TestEmployeeInMemoryRepository_UpdateEmployee(t *testing.T) {
 	repo := NewEmployeeInMemoryRepository()
 
 	// Create an employee
-	name, position, salary := "Ganesh Agrawal", "Software Engineer", 1234.00
-	emp, _ := repo.CreateEmployee(name, position, salary)
+	name, email, position, salary := "Ganesh Agrawal", "ganesh@example.com", "Software Engineer", 1234.00
+	emp, _ := repo.CreateEmployee(name, email, position, salary)
 
 	// Update the employee
-	newName, newPosition, newSalary := "Ganesh Agrawal", "Senior Software Engineer", 1350.00
-	updatedEmp, err := repo.UpdateEmployee(emp.ID, newName, newPosition, newSalary)
+	newName, newEmail, newPosition, newSalary := "Ganesh Agrawal", "ganesh.a@example.com", "Senior Software Engineer", 1350.00
+	updatedEmp, err := repo.UpdateEmployee(emp.ID, newName, newEmail, newPosition, newSalary)
 
 	assert.Nil(t, err, "error should be nil")
 	assert.Equal(t, emp.ID, updatedEmp.ID, "ID should be %d", emp.ID)
@@ -103,7 +106,7 @@ func TestEmployeeInMemoryRepository_UpdateEmployee(t *testing.T) {
 	assert.Equal(t, newSalary, empByID.Salary, "Salary should be %s", newSalary)
 
 	// Update an employee with an invalid ID
-	updatedEmp, err = repo.UpdateEmployee(2, newName, newPosition, newSalary)
+	updatedEmp, err = repo.UpdateEmployee("2", newName, newEmail, newPosition, newSalary)
 
 	assert.NotNil(t, err, "error should not be nil")
 	assert.ErrorIs(t, err, ErrRecordNotFound, "error message should be 'record not found'")
@@ -115,13 +118,13 @@ func TestEmployeeInMemoryRepository_DeleteEmployee(t *testing.T) {
 	repo := NewEmployeeInMemoryRepository()
 
 	// Delete an employee with an invalid ID
-	err := repo.DeleteEmployee(1)
+	err := repo.DeleteEmployee("1")
 	assert.NotNil(t, err, "error should not be nil")
 	assert.ErrorIs(t, err, ErrRecordNotFound, "error message should be 'record not found'")
 
 	// Create an employee
-	name, position, salary := "Ganesh Agrawal", "Software Engineer", 1234.00
-	emp, _ := repo.CreateEmployee(name, position, salary)
+	name, email, position, salary := "Ganesh Agrawal", "ganesh@example.com", "Software Engineer", 1234.00
+	emp, _ := repo.CreateEmployee(name, email, position, salary)
 
 	// Delete the employee
 	err = repo.DeleteEmployee(emp.ID)
@@ -149,21 +152,21 @@ func TestEmployeeInMemoryRepository_GetAllEmployees(t *testing.T) {
 
 	// seed the repository with employees
 	seedData := []models.Employee{
-		{Name: "Ganesh Agrawal", Position: "Software Engineer", Salary: 1234.00},
-		{Name: "Harshit Kumar", Position: "DevOps Engineer", Salary: 1235.00},
-		{Name: "Rahul Singh", Position: "Data Scientist", Salary: 1236.00},
-		{Name: "Rohit Sharma", Position: "Business Analyst", Salary: 1237.00},
-		{Name: "Mahesh Kumar", Position: "QA Engineer", Salary: 1238.00},
-		{Name: "Suresh Kumar", Position: "Technical Writer", Salary: 1239.00},
-		{Name: "Ramesh Kumar", Position: "Network Engineer", Salary: 1240.00},
-		{Name: "Rakesh Kumar", Position: "Security Analyst", Salary: 1241.00},
-		{Name: "Pankaj Sharma", Position: "Software Engineer", Salary: 1242.00},
-		{Name: "Ankit Sharma", Position: "Software Engineer", Salary: 1243.00},
-		{Name: "Anil Sharma", Position: "Software Engineer", Salary: 1244.00},
+		{Name: "Ganesh Agrawal", Email: "ganesh@example.com", Position: "Software Engineer", Salary: 1234.00},
+		{Name: "Harshit Kumar", Email: "harshit@example.com", Position: "DevOps Engineer", Salary: 1235.00},
+		{Name: "Rahul Singh", Email: "rahul@example.com", Position: "Data Scientist", Salary: 1236.00},
+		{Name: "Rohit Sharma", Email: "rohit@example.com", Position: "Business Analyst", Salary: 1237.00},
+		{Name: "Mahesh Kumar", Email: "mahesh@example.com", Position: "QA Engineer", Salary: 1238.00},
+		{Name: "Suresh Kumar", Email: "suresh@example.com", Position: "Technical Writer", Salary: 1239.00},
+		{Name: "Ramesh Kumar", Email: "ramesh@example.com", Position: "Network Engineer", Salary: 1240.00},
+		{Name: "Rakesh Kumar", Email: "rakesh@example.com", Position: "Security Analyst", Salary: 1241.00},
+		{Name: "Pankaj Sharma", Email: "pankaj@example.com", Position: "Software Engineer", Salary: 1242.00},
+		{Name: "Ankit Sharma", Email: "ankit@example.com", Position: "Software Engineer", Salary: 1243.00},
+		{Name: "Anil Sharma", Email: "anil@example.com", Position: "Software Engineer", Salary: 1244.00},
 	}
 
 	for _, employee := range seedData {
-		_, _ = repo.CreateEmployee(employee.Name, employee.Position, employee.Salary)
+		_, _ = repo.CreateEmployee(employee.Name, employee.Email, employee.Position, employee.Salary)
 	}
 
 	// Fetch the all employees
@@ -208,3 +211,222 @@ func TestEmployeeInMemoryRepository_GetAllEmployees(t *testing.T) {
 	assert.Equal(t, len(seedData), total, "total should be %d", len(seedData))
 	assert.Equal(t, len(seedData), len(fetchedEmployees), "employees should be %d", len(seedData))
 }
+
+func TestEmployeeInMemoryRepository_UniqueEmail(t *testing.T) {
+	// Create a new in-memory repository
+	repo := NewEmployeeInMemoryRepository()
+
+	// seed the repository with employees
+	ganesh, _ := repo.CreateEmployee(
+		"Ganesh Agrawal",
+		"ganesh@example.com",
+		"Software Engineer",
+		1234.00,
+	)
+	harshit, _ := repo.CreateEmployee(
+		"Harshit Kumar",
+		"harshit@example.com",
+		"DevOps Engineer",
+		1235.00,
+	)
+
+	// Create an employee with an email that is already used (case-insensitive)
+	emp, err := repo.CreateEmployee("Ganesh A", " Ganesh@Example.com", "Manager", 1236.00)
+
+	var dupErr *DuplicateError
+	assert.ErrorIs(t, err, ErrDuplicate, "error message should be 'duplicate record'")
+	assert.ErrorAs(t, err, &dupErr, "error should be a DuplicateError")
+	assert.Equal(t, "email", dupErr.Field, "Field should be email")
+	assert.Equal(t, ganesh.ID, dupErr.ExistingID, "ExistingID should be %s", ganesh.ID)
+	assert.Equal(t, models.Employee{}, emp, "employee should be empty")
+
+	_, total := repo.GetAllEmployees(0, 0)
+	assert.Equal(t, 2, total, "total should be 2")
+
+	// Rejected creations do not use up an ID
+	emp, err = repo.CreateEmployee("Rahul Singh", "rahul@example.com", "Data Scientist", 1237.00)
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, models.ID("3"), emp.ID, "ID should be 3")
+	_ = repo.DeleteEmployee(emp.ID)
+
+	// Employees without an email never conflict
+	_, err = repo.CreateEmployee("Rohit Sharma", "", "Business Analyst", 1238.00)
+	assert.Nil(t, err, "error should be nil")
+	emp, err = repo.CreateEmployee("Mahesh Kumar", "", "QA Engineer", 1239.00)
+	assert.Nil(t, err, "error should be nil")
+	_ = repo.DeleteEmployee(emp.ID)
+
+	// Update an employee to an email that is already used
+	_, err = repo.UpdateEmployee(
+		harshit.ID,
+		harshit.Name,
+		ganesh.Email,
+		harshit.Position,
+		harshit.Salary,
+	)
+	assert.ErrorAs(t, err, &dupErr, "error should be a DuplicateError")
+	assert.Equal(t, ganesh.ID, dupErr.ExistingID, "ExistingID should be %s", ganesh.ID)
+
+	empByID, _ := repo.GetEmployeeByID(harshit.ID)
+	assert.Equal(t, harshit, empByID, "employee should be unchanged")
+
+	// Update an employee keeping its own email
+	_, err = repo.UpdateEmployee(ganesh.ID, ganesh.Name, ganesh.Email, "Manager", ganesh.Salary)
+	assert.Nil(t, err, "error should be nil")
+
+	// The email can be reused once its employee is deleted
+	_ = repo.DeleteEmployee(ganesh.ID)
+	_, err = repo.CreateEmployee("Ganesh A", "ganesh@example.com", "Manager", 1236.00)
+	assert.Nil(t, err, "error should be nil")
+}
+
+func TestEmployeeInMemoryRepository_GetEmployeesBySalaryRange(t *testing.T) {
+	// Create a new in-memory repository
+	repo := NewEmployeeInMemoryRepository()
+
+	// seed the repository with employees
+	ganesh, _ := repo.CreateEmployee(
+		"Ganesh Agrawal",
+		"ganesh.agrawal@example.com",
+		"Software Engineer",
+		3000.00,
+	)
+	harshit, _ := repo.CreateEmployee(
+		"Harshit Kumar",
+		"harshit.kumar@example.com",
+		"DevOps Engineer",
+		1000.00,
+	)
+	rahul, _ := repo.CreateEmployee(
+		"Rahul Singh",
+		"rahul.singh@example.com",
+		"Data Scientist",
+		2000.00,
+	)
+	rohit, _ := repo.CreateEmployee(
+		"Rohit Sharma",
+		"rohit.sharma@example.com",
+		"Business Analyst",
+		2000.00,
+	)
+
+	// Fetch employees ordered by salary
+	employees := repo.GetEmployeesBySalaryRange(1500.00, 3000.00)
+	assert.Equal(t, []models.Employee{rahul, rohit, ganesh}, employees)
+
+	// Update an employee out of the range
+	ganesh, _ = repo.UpdateEmployee(ganesh.ID, ganesh.Name, ganesh.Email, ganesh.Position, 5000.00)
+	employees = repo.GetEmployeesBySalaryRange(1500.00, 3000.00)
+	assert.Equal(t, []models.Employee{rahul, rohit}, employees)
+
+	// Delete an employee within the range
+	_ = repo.DeleteEmployee(rahul.ID)
+	employees = repo.GetEmployeesBySalaryRange(0, 10000.00)
+	assert.Equal(t, []models.Employee{harshit, rohit, ganesh}, employees)
+
+	// Fetch an empty range
+	employees = repo.GetEmployeesBySalaryRange(6000.00, 7000.00)
+	assert.Empty(t, employees, "employees should be empty")
+}
+
+func TestEmployeeInMemoryRepository_IndexTieBreak(t *testing.T) {
+	// Create a new in-memory repository with sequential IDs
+	repo := NewEmployeeInMemoryRepository()
+
+	// Equal salaries and names come back in the order the employees were created
+	created := make([]models.Employee, 0, 11)
+	for i := 0; i < 11; i++ {
+		employee, err := repo.CreateEmployee(
+			"Same Name",
+			fmt.Sprintf("same.%d@example.com", i),
+			"Engineer",
+			1000.00,
+		)
+		assert.Nil(t, err, "error should be nil")
+		created = append(created, employee)
+	}
+	assert.Equal(t, created, repo.GetEmployeesBySalaryRange(1000.00, 1000.00))
+	assert.Equal(t, created, repo.GetEmployeesByNamePrefix("Same"))
+}
+
+func TestEmployeeInMemoryRepository_GetEmployeesByNamePrefix(t *testing.T) {
+	// Create a new in-memory repository
+	repo := NewEmployeeInMemoryRepository()
+
+	// seed the repository with employees
+	ganesh, _ := repo.CreateEmployee(
+		"Ganesh Agrawal",
+		"ganesh.agrawal@example.com",
+		"Software Engineer",
+		1234.00,
+	)
+	gaurav, _ := repo.CreateEmployee(
+		"Gaurav Jain",
+		"gaurav.jain@example.com",
+		"QA Engineer",
+		1235.00,
+	)
+	_, _ = repo.CreateEmployee(
+		"Harshit Kumar",
+		"harshit.kumar@example.com",
+		"DevOps Engineer",
+		1236.00,
+	)
+
+	// Fetch employees ordered by name
+	employees := repo.GetEmployeesByNamePrefix("Ga")
+	assert.Equal(t, []models.Employee{ganesh, gaurav}, employees)
+
+	// Rename an employee so that it no longer matches
+	gaurav, _ = repo.UpdateEmployee(
+		gaurav.ID,
+		"Saurabh Jain",
+		gaurav.Email,
+		gaurav.Position,
+		gaurav.Salary,
+	)
+	employees = repo.GetEmployeesByNamePrefix("Ga")
+	assert.Equal(t, []models.Employee{ganesh}, employees)
+
+	employees = repo.GetEmployeesByNamePrefix("Sa")
+	assert.Equal(t, []models.Employee{gaurav}, employees)
+
+	// Delete the remaining match
+	_ = repo.DeleteEmployee(ganesh.ID)
+	employees = repo.GetEmployeesByNamePrefix("Ga")
+	assert.Empty(t, employees, "employees should be empty")
+}
+
+func TestEmployeeInMemoryRepository_WithIDGenerator(t *testing.T) {
+	// Create a new in-memory repository with ULIDs
+	repo := NewEmployeeInMemoryRepositoryWithIDGenerator(idgen.NewULID())
+
+	// Create employees
+	first, err := repo.CreateEmployee(
+		"Ganesh Agrawal",
+		"ganesh.agrawal@example.com",
+		"Software Engineer",
+		1234.00,
+	)
+	assert.Nil(t, err, "error should be nil")
+	assert.Len(t, first.ID, 26, "ID should be a ULID")
+
+	second, err := repo.CreateEmployee(
+		"Harshit Kumar",
+		"harshit.kumar@example.com",
+		"DevOps Engineer",
+		1235.00,
+	)
+	assert.Nil(t, err, "error should be nil")
+	assert.NotEqual(t, first.ID, second.ID, "IDs should be unique")
+
+	// Retrieve the employee by its generated ID
+	empByID, err := repo.GetEmployeeByID(second.ID)
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, second, empByID, "employee should be %v", second)
+
+	// Insertion order is preserved regardless of the ID format
+	employees, total := repo.GetAllEmployees(1, 10)
+	assert.Equal(t, 2, total, "total should be 2")
+	assert.Equal(t, []models.Employee{first, second}, employees)
+}
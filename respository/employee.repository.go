@@ -1,14 +1,107 @@
 package respository
 
 import (
+	"context"
+	"time"
+
 	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
 )
 
 // IEmployeeRepository is an interface for employee repository
 type IEmployeeRepository interface {
-	CreateEmployee(name string, position string, salary float64) (models.Employee, error)
-	GetEmployeeByID(id int) (models.Employee, error)
-	UpdateEmployee(id int, name string, position string, salary float64) (models.Employee, error)
-	DeleteEmployee(id int) error
+	CreateEmployee(
+		name string,
+		email string,
+		position string,
+		salary float64,
+	) (models.Employee, error)
+	GetEmployeeByID(id models.ID) (models.Employee, error)
+	UpdateEmployee(
+		id models.ID,
+		name string,
+		email string,
+		position string,
+		salary float64,
+	) (models.Employee, error)
+	DeleteEmployee(id models.ID) error
 	GetAllEmployees(page int, limit int) ([]models.Employee, int)
 }
+
+// IEmployeeImporter is implemented by the employee repositories employees can
+// be copied into under their own IDs, e.g. during a migration between backends
+type IEmployeeImporter interface {
+	// ImportEmployee stores the employee, ErrDuplicate is returned when its ID is used
+	ImportEmployee(employee models.Employee) error
+	// ReplaceEmployees replaces every employee with the given ones, in order
+	ReplaceEmployees(employees []models.Employee) error
+}
+
+// Ensure type implements the interface
+var _ Repository[models.Employee] = (*employeeRepositoryAdapter)(nil)
+var _ ContextBinder[Repository[models.Employee]] = (*employeeRepositoryAdapter)(nil)
+var _ HistoryReader[models.Employee] = (*employeeRepositoryAdapter)(nil)
+
+// employeeRepositoryAdapter exposes an IEmployeeRepository as a generic Repository
+type employeeRepositoryAdapter struct {
+	repo IEmployeeRepository
+}
+
+// AsRepository adapts any IEmployeeRepository (including decorators wrapping one)
+// to the generic Repository interface used by the CRUD handlers
+func AsRepository(repo IEmployeeRepository) Repository[models.Employee] {
+	return &employeeRepositoryAdapter{repo: repo}
+}
+
+// WithContext binds the adapted repository to the context
+func (a *employeeRepositoryAdapter) WithContext(ctx context.Context) Repository[models.Employee] {
+	return &employeeRepositoryAdapter{repo: BindContext(ctx, a.repo)}
+}
+
+func (a *employeeRepositoryAdapter) Create(employee models.Employee) (models.Employee, error) {
+	return a.repo.CreateEmployee(
+		employee.Name,
+		employee.Email,
+		employee.Position,
+		employee.Salary,
+	)
+}
+
+func (a *employeeRepositoryAdapter) GetByID(id models.ID) (models.Employee, error) {
+	return a.repo.GetEmployeeByID(id)
+}
+
+func (a *employeeRepositoryAdapter) Update(
+	id models.ID,
+	employee models.Employee,
+) (models.Employee, error) {
+	return a.repo.UpdateEmployee(
+		id,
+		employee.Name,
+		employee.Email,
+		employee.Position,
+		employee.Salary,
+	)
+}
+
+func (a *employeeRepositoryAdapter) Delete(id models.ID) error {
+	return a.repo.DeleteEmployee(id)
+}
+
+func (a *employeeRepositoryAdapter) GetAll(page int, limit int) ([]models.Employee, int) {
+	return a.repo.GetAllEmployees(page, limit)
+}
+
+func (a *employeeRepositoryAdapter) GetByIDAsOf(
+	id models.ID,
+	asOf time.Time,
+) (models.Employee, error) {
+	return HistoryOf[models.Employee](a.repo).GetByIDAsOf(id, asOf)
+}
+
+func (a *employeeRepositoryAdapter) GetAllAsOf(
+	asOf time.Time,
+	page int,
+	limit int,
+) ([]models.Employee, int, error) {
+	return HistoryOf[models.Employee](a.repo).GetAllAsOf(asOf, page, limit)
+}
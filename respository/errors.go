@@ -1,7 +1,61 @@
 package respository
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+)
 
 var (
 	ErrRecordNotFound = errors.New("record not found")
+	ErrDuplicate      = errors.New("duplicate record")
+
+	// Errors of the reads of past versions, see HistoryReader
+	ErrHistoryNotRetained = errors.New("history not retained")
+	ErrHistoryPruned      = errors.New("history pruned")
 )
+
+// DuplicateError is returned when a write violates a unique constraint
+//
+// It matches ErrDuplicate with errors.Is, use errors.As to get the details.
+type DuplicateError struct {
+	Field      string    // Name of the field holding the conflicting value
+	ExistingID models.ID // ID of the record that already holds the value
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf(
+		"%s is already used by record with ID %s: %s",
+		e.Field,
+		e.ExistingID,
+		ErrDuplicate,
+	)
+}
+
+func (e *DuplicateError) Is(target error) bool {
+	return target == ErrDuplicate
+}
+
+// ErrRuleViolation is matched by the errors of the writes vetoed by a business rule
+var ErrRuleViolation = errors.New("rule violation")
+
+// RuleError is returned when a business rule, e.g. a before-hook, vetoes a write
+//
+// It matches ErrRuleViolation with errors.Is, use errors.As to get the details.
+type RuleError struct {
+	Rule    string // Name of the rule, set to the name of the hook when empty
+	Field   string // Name of the offending field, if any
+	Message string
+}
+
+func (e *RuleError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("%s: %s", e.Message, ErrRuleViolation)
+	}
+	return fmt.Sprintf("%s: %s: %s", e.Field, e.Message, ErrRuleViolation)
+}
+
+func (e *RuleError) Is(target error) bool {
+	return target == ErrRuleViolation
+}
@@ -0,0 +1,234 @@
+package respository
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/datatypes"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+)
+
+// HistoryReader reads the entities as they were at a past time
+//
+// Repository decorators implement it by reading through the repository they
+// wrap, see HistoryOf. ErrHistoryNotRetained is returned by the repositories
+// not retaining past versions, and ErrHistoryPruned for a time before the
+// versions retained.
+type HistoryReader[T any] interface {
+	// GetByIDAsOf retrieves the entity as it was at the time, ErrRecordNotFound
+	// is returned when it did not exist yet or was deleted
+	GetByIDAsOf(id models.ID, asOf time.Time) (T, error)
+	// GetAllAsOf retrieves the entities that existed at the time, as they were,
+	// and their count
+	GetAllAsOf(asOf time.Time, page int, limit int) ([]T, int, error)
+}
+
+// Ensure type implements the interface
+var _ HistoryReader[models.Employee] = (*History[models.Employee])(nil)
+var _ HistoryReader[models.Employee] = (*InMemoryRepository[models.Employee])(nil)
+
+// HistoryOf returns the repository as a HistoryReader, or a reader returning
+// ErrHistoryNotRetained when it does not implement it
+func HistoryOf[T any](repo any) HistoryReader[T] {
+	if reader, ok := repo.(HistoryReader[T]); ok {
+		return reader
+	}
+	return notRetained[T]{}
+}
+
+// notRetained is the HistoryReader of the repositories retaining no version
+type notRetained[T any] struct{}
+
+func (notRetained[T]) GetByIDAsOf(models.ID, time.Time) (T, error) {
+	var zero T
+	return zero, ErrHistoryNotRetained
+}
+
+func (notRetained[T]) GetAllAsOf(time.Time, int, int) ([]T, int, error) {
+	return nil, 0, ErrHistoryNotRetained
+}
+
+// Version is an entity as it was from a time on, until its next version
+type Version[T any] struct {
+	Entity  T         `json:"entity"` // Entity after the write, or before its deletion
+	From    time.Time `json:"from"`
+	Deleted bool      `json:"deleted,omitempty"` // The entity was deleted at From
+}
+
+// History retains every version of the entities of an InMemoryRepository,
+// recorded in the critical section of the writes
+//
+// Versions are kept, along with the snapshots of the repository, until they
+// are pruned.
+type History[T models.Entity[T]] struct {
+	mu       sync.RWMutex
+	versions *datatypes.OrderedMap[models.ID, []Version[T]] // In creation order, oldest version first
+	pruned   time.Time                                      // Versions before are no longer retained
+	now      func() time.Time
+}
+
+// NewHistory creates a new empty history
+func NewHistory[T models.Entity[T]]() *History[T] {
+	return &History[T]{versions: datatypes.NewOrderedMap[models.ID, []Version[T]](), now: time.Now}
+}
+
+// GetByIDAsOf retrieves the entity as it was at the time
+func (h *History[T]) GetByIDAsOf(id models.ID, asOf time.Time) (T, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var zero T
+	if err := h.retained(asOf); err != nil {
+		return zero, err
+	}
+	versions, _ := h.versions.Get(id)
+	if version, ok := versionAt(versions, asOf); ok {
+		return version.Entity, nil
+	}
+	return zero, fmt.Errorf(
+		"ID %s not found as of %s: %w",
+		id,
+		asOf.Format(time.RFC3339),
+		ErrRecordNotFound,
+	)
+}
+
+// GetAllAsOf retrieves the entities that existed at the time, in creation
+// order, paginated like GetAll
+func (h *History[T]) GetAllAsOf(asOf time.Time, page int, limit int) ([]T, int, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if err := h.retained(asOf); err != nil {
+		return nil, 0, err
+	}
+	entities := make([]T, 0)
+	for _, id := range h.versions.Keys() {
+		versions, _ := h.versions.Get(id)
+		if version, ok := versionAt(versions, asOf); ok {
+			entities = append(entities, version.Entity)
+		}
+	}
+
+	// Handle pagination, a limit less than or equal to 0 returns all entities
+	page = max(page, 1)
+	if limit <= 0 {
+		limit = len(entities)
+	}
+	offset := min((page-1)*limit, len(entities))
+	end := min(offset+limit, len(entities))
+	return entities[offset:end], len(entities), nil
+}
+
+// Prune drops the versions superseded before the time, and the entities
+// deleted before it, and returns the number of versions dropped
+//
+// The versions current at the time are kept, reads at the time and after are
+// unchanged, reads before it return ErrHistoryPruned.
+func (h *History[T]) Prune(before time.Time) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !before.After(h.pruned) {
+		return 0
+	}
+	h.pruned = before
+
+	pruned := datatypes.NewOrderedMap[models.ID, []Version[T]]()
+	dropped := 0
+	for _, id := range h.versions.Keys() {
+		versions, _ := h.versions.Get(id)
+		kept := versions
+		for len(kept) > 1 && !kept[1].From.After(before) {
+			kept = kept[1:]
+		}
+		if kept[0].Deleted && !kept[0].From.After(before) {
+			kept = nil
+		}
+		dropped += len(versions) - len(kept)
+		if len(kept) > 0 {
+			pruned.Set(id, slices.Clone(kept))
+		}
+	}
+	h.versions = pruned
+	return dropped
+}
+
+// retained returns ErrHistoryPruned when the versions at the time are no
+// longer retained, the caller must hold the lock
+func (h *History[T]) retained(asOf time.Time) error {
+	if asOf.Before(h.pruned) {
+		return fmt.Errorf(
+			"versions before %s are no longer retained: %w",
+			h.pruned.Format(time.RFC3339),
+			ErrHistoryPruned,
+		)
+	}
+	return nil
+}
+
+// record adds a version of the entity, the caller must hold the write lock of
+// the repository
+func (h *History[T]) record(op WriteOp, entity T) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// Versions of an entity never go back in time
+	versions, _ := h.versions.Get(entity.GetID())
+	from := h.now().UTC()
+	if n := len(versions); n > 0 && from.Before(versions[n-1].From) {
+		from = versions[n-1].From
+	}
+	h.versions.Set(
+		entity.GetID(),
+		append(versions, Version[T]{Entity: entity, From: from, Deleted: op == OpDelete}),
+	)
+}
+
+// state returns every version, grouped by entity in creation order
+func (h *History[T]) state() []Version[T] {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	state := make([]Version[T], 0)
+	for _, id := range h.versions.Keys() {
+		versions, _ := h.versions.Get(id)
+		state = append(state, versions...)
+	}
+	return state
+}
+
+// prunedBefore returns the time the versions were pruned before, the zero time
+// when they never were
+func (h *History[T]) prunedBefore() time.Time {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.pruned
+}
+
+// restore replaces the versions with the ones returned by state, pruned before
+// the time
+func (h *History[T]) restore(state []Version[T], pruned time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.pruned = pruned
+	h.versions = datatypes.NewOrderedMap[models.ID, []Version[T]]()
+	for _, version := range state {
+		versions, _ := h.versions.Get(version.Entity.GetID())
+		h.versions.Set(version.Entity.GetID(), append(versions, version))
+	}
+}
+
+// versionAt returns the version current at the time, unless the entity did
+// not exist yet or was deleted
+func versionAt[T any](versions []Version[T], asOf time.Time) (Version[T], bool) {
+	for i := len(versions) - 1; i >= 0; i-- {
+		if !versions[i].From.After(asOf) {
+			return versions[i], !versions[i].Deleted
+		}
+	}
+	return Version[T]{}, false
+}
@@ -0,0 +1,146 @@
+package respository
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// at returns the time of the day of March 1st 2024, in UTC
+func at(hour int, minute int) time.Time {
+	return time.Date(2024, time.March, 1, hour, minute, 0, 0, time.UTC)
+}
+
+// newHistoryRepository creates a repository retaining its versions, with a
+// clock set by the returned function
+func newHistoryRepository() (*EmployeeInMemoryRepository, *History[models.Employee], func(time.Time)) {
+	repo := NewEmployeeInMemoryRepository()
+	history := NewHistory[models.Employee]()
+	repo.SetHistory(history)
+
+	var now time.Time
+	history.now = func() time.Time { return now }
+	return repo, history, func(t time.Time) { now = t }
+}
+
+func TestHistory(t *testing.T) {
+	repo, history, setNow := newHistoryRepository()
+
+	setNow(at(9, 0))
+	ganesh, _ := repo.CreateEmployee("Ganesh Agrawal", "ganesh@example.com", "Engineer", 1000)
+	setNow(at(10, 0))
+	harshit, _ := repo.CreateEmployee("Harshit Kumar", "harshit@example.com", "Engineer", 1000)
+	setNow(at(11, 0))
+	repo.UpdateEmployee(ganesh.ID, "Ganesh Agrawal", "ganesh@example.com", "Lead", 2000)
+	setNow(at(12, 0))
+	repo.DeleteEmployee(harshit.ID)
+
+	// Versions by ID
+	_, err := history.GetByIDAsOf(ganesh.ID, at(8, 59))
+	assert.ErrorIs(t, err, ErrRecordNotFound, "employee should not exist before its creation")
+	employee, err := history.GetByIDAsOf(ganesh.ID, at(9, 0))
+	assert.Nil(t, err)
+	assert.Equal(t, 1000.0, employee.Salary, "version should start at its write")
+	employee, err = history.GetByIDAsOf(ganesh.ID, at(10, 59))
+	assert.Nil(t, err)
+	assert.Equal(t, "Engineer", employee.Position)
+	employee, err = history.GetByIDAsOf(ganesh.ID, at(11, 30))
+	assert.Nil(t, err)
+	assert.Equal(t, "Lead", employee.Position)
+	_, err = history.GetByIDAsOf(harshit.ID, at(12, 0))
+	assert.ErrorIs(t, err, ErrRecordNotFound, "employee should not exist after its deletion")
+	employee, err = history.GetByIDAsOf(harshit.ID, at(11, 59))
+	assert.Nil(t, err)
+	assert.Equal(t, "Harshit Kumar", employee.Name)
+
+	// Lists, paginated in creation order
+	employees, total, err := history.GetAllAsOf(at(11, 30), 1, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, total)
+	assert.Equal(
+		t,
+		[]models.ID{ganesh.ID, harshit.ID},
+		[]models.ID{employees[0].ID, employees[1].ID},
+	)
+	assert.Equal(t, "Lead", employees[0].Position)
+	employees, total, _ = history.GetAllAsOf(at(11, 30), 2, 1)
+	assert.Equal(t, 2, total)
+	assert.Equal(
+		t,
+		[]models.Employee{employee},
+		employees,
+		"second page should hold the second employee",
+	)
+	employees, total, _ = history.GetAllAsOf(at(11, 30), 3, 1)
+	assert.Equal(t, 2, total)
+	assert.Empty(t, employees, "pages past the end should be empty")
+	employees, total, _ = history.GetAllAsOf(at(12, 0), 1, 10)
+	assert.Equal(t, 1, total)
+	assert.Equal(t, ganesh.ID, employees[0].ID)
+}
+
+func TestHistory_Snapshot(t *testing.T) {
+	repo, history, setNow := newHistoryRepository()
+	setNow(at(9, 0))
+	employee, _ := repo.CreateEmployee("Ganesh Agrawal", "ganesh@example.com", "Engineer", 1000)
+	setNow(at(10, 0))
+	repo.UpdateEmployee(employee.ID, "Ganesh Agrawal", "ganesh@example.com", "Lead", 1000)
+
+	var snapshot bytes.Buffer
+	assert.Nil(t, repo.Snapshot(&snapshot))
+
+	// Versions are restored along with the employees
+	restored, restoredHistory, _ := newHistoryRepository()
+	assert.Nil(t, restored.Restore(&snapshot))
+	assert.Equal(t, history.state(), restoredHistory.state())
+
+	// Snapshots written without a history retain the employees from the restore on
+	plain := `[{"id":"1","name":"Ganesh Agrawal","email":"ganesh@example.com","position":"Engineer","salary":1234}]`
+	restored, restoredHistory, setNow = newHistoryRepository()
+	setNow(at(12, 0))
+	assert.Nil(t, restored.Restore(strings.NewReader(plain)))
+	_, err := restoredHistory.GetByIDAsOf("1", at(11, 0))
+	assert.ErrorIs(t, err, ErrRecordNotFound)
+	_, err = restoredHistory.GetByIDAsOf("1", at(12, 0))
+	assert.Nil(t, err)
+}
+
+func TestHistory_Prune(t *testing.T) {
+	repo, history, setNow := newHistoryRepository()
+	setNow(at(9, 0))
+	ganesh, _ := repo.CreateEmployee("Ganesh Agrawal", "ganesh@example.com", "Engineer", 1000)
+	harshit, _ := repo.CreateEmployee("Harshit Kumar", "harshit@example.com", "Engineer", 1000)
+	setNow(at(10, 0))
+	repo.UpdateEmployee(ganesh.ID, "Ganesh Agrawal", "ganesh@example.com", "Lead", 1000)
+	repo.DeleteEmployee(harshit.ID)
+	setNow(at(12, 0))
+	repo.UpdateEmployee(ganesh.ID, "Ganesh Agrawal", "ganesh@example.com", "Lead", 2000)
+
+	// The versions current at the time are kept, deleted employees are dropped
+	assert.Equal(t, 3, history.Prune(at(11, 0)))
+	assert.Equal(t, 0, history.Prune(at(10, 30)), "pruning never goes back")
+	employee, err := history.GetByIDAsOf(ganesh.ID, at(11, 0))
+	assert.Nil(t, err)
+	assert.Equal(t, 1000.0, employee.Salary)
+	employee, err = history.GetByIDAsOf(ganesh.ID, at(12, 0))
+	assert.Nil(t, err)
+	assert.Equal(t, 2000.0, employee.Salary)
+	_, err = history.GetByIDAsOf(harshit.ID, at(11, 0))
+	assert.ErrorIs(t, err, ErrRecordNotFound)
+	_, err = history.GetByIDAsOf(ganesh.ID, at(10, 0))
+	assert.ErrorIs(t, err, ErrHistoryPruned, "pruned versions should not be read")
+	_, _, err = history.GetAllAsOf(at(9, 0), 1, 0)
+	assert.ErrorIs(t, err, ErrHistoryPruned)
+
+	// The snapshots keep the pruning
+	var snapshot bytes.Buffer
+	assert.Nil(t, repo.Snapshot(&snapshot))
+	restored, restoredHistory, _ := newHistoryRepository()
+	assert.Nil(t, restored.Restore(&snapshot))
+	_, err = restoredHistory.GetByIDAsOf(ganesh.ID, at(10, 0))
+	assert.ErrorIs(t, err, ErrHistoryPruned)
+}
@@ -0,0 +1,389 @@
+package respository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/datatypes"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/idgen"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+)
+
+// Index is a secondary index kept in sync by InMemoryRepository.
+// Add and Remove are always called while the repository holds its write lock.
+type Index[T any] interface {
+	Add(entity T)
+	Remove(entity T)
+}
+
+// UniqueIndex is an Index that enforces a unique constraint.
+// Check is called under the write lock before every create and update, and a
+// non-nil error (usually a *DuplicateError) aborts the write.
+type UniqueIndex[T any] interface {
+	Index[T]
+	Check(entity T) error
+}
+
+// InMemoryRepository is a generic in-memory repository for any entity
+type InMemoryRepository[T models.Entity[T]] struct {
+	name        string                              // Entity name used in error messages
+	mu          *sync.RWMutex                       // Mutex for thread-safety
+	store       *datatypes.OrderedMap[models.ID, T] // In-memory database
+	indexes     []Index[T]                          // Secondary indexes kept in sync on every write
+	idGenerator idgen.IDGenerator                   // Generates the ID of the next entity
+	outbox      *Outbox[T]                          // Records the writes when set
+	history     *History[T]                         // Retains the versions of the entities when set
+}
+
+// NewInMemoryRepository creates a new in-memory repository for the named entity
+func NewInMemoryRepository[T models.Entity[T]](
+	name string,
+	idGenerator idgen.IDGenerator,
+	indexes ...Index[T],
+) *InMemoryRepository[T] {
+	return &InMemoryRepository[T]{
+		name:        name,
+		mu:          &sync.RWMutex{},
+		store:       datatypes.NewOrderedMap[models.ID, T](),
+		indexes:     indexes,
+		idGenerator: idGenerator,
+	}
+}
+
+// SetOutbox records every subsequent write in the outbox, in the critical section
+// of the write, and includes the outbox in the snapshots
+//
+// It must be called before the repository is used or restored.
+func (repo *InMemoryRepository[T]) SetOutbox(outbox *Outbox[T]) {
+	repo.mu.Lock()         // Lock the mutex
+	defer repo.mu.Unlock() // Unlock the mutex when the function returns
+
+	repo.outbox = outbox
+}
+
+// SetHistory retains every subsequent version of the entities in the history,
+// in the critical section of the write, and includes the history in the snapshots
+//
+// It must be called before the repository is used or restored.
+func (repo *InMemoryRepository[T]) SetHistory(history *History[T]) {
+	repo.mu.Lock()         // Lock the mutex
+	defer repo.mu.Unlock() // Unlock the mutex when the function returns
+
+	repo.history = history
+}
+
+// GetByIDAsOf retrieves the entity as it was at the time from the history,
+// ErrHistoryNotRetained is returned when there is none
+func (repo *InMemoryRepository[T]) GetByIDAsOf(id models.ID, asOf time.Time) (T, error) {
+	return repo.historyReader().GetByIDAsOf(id, asOf)
+}
+
+// GetAllAsOf retrieves the entities that existed at the time from the history,
+// ErrHistoryNotRetained is returned when there is none
+func (repo *InMemoryRepository[T]) GetAllAsOf(
+	asOf time.Time,
+	page int,
+	limit int,
+) ([]T, int, error) {
+	return repo.historyReader().GetAllAsOf(asOf, page, limit)
+}
+
+// historyReader returns the history, or a reader failing without one
+func (repo *InMemoryRepository[T]) historyReader() HistoryReader[T] {
+	repo.mu.RLock()         // Lock the mutex for reading
+	defer repo.mu.RUnlock() // Unlock the mutex when the function returns
+
+	if repo.history == nil {
+		return HistoryOf[T](nil)
+	}
+	return repo.history
+}
+
+// Create stores a new entity under a newly generated ID
+func (repo *InMemoryRepository[T]) Create(entity T) (T, error) {
+	repo.mu.Lock()         // Lock the mutex
+	defer repo.mu.Unlock() // Unlock the mutex when the function returns
+
+	// Enforce the unique constraints before an ID is used up
+	if err := repo.checkUnique(entity.WithID("")); err != nil {
+		var zero T
+		return zero, fmt.Errorf("%s create failed: %w", repo.name, err)
+	}
+
+	// Assign a newly generated ID
+	entity = entity.WithID(models.ID(repo.idGenerator.NewID()))
+
+	// Store the entity in the store (in-memory database)
+	repo.store.Set(entity.GetID(), entity)
+	for _, index := range repo.indexes {
+		index.Add(entity)
+	}
+	repo.record(OpCreate, entity)
+
+	// Return the created entity
+	return entity, nil
+}
+
+// Check enforces the unique constraints against the entity as it would be
+// stored under its ID, an empty ID for a new entity, without storing it
+//
+// The result only holds until the next write, e.g. for a single writer that
+// logs the write elsewhere before storing it.
+func (repo *InMemoryRepository[T]) Check(entity T) error {
+	repo.mu.RLock()         // Lock the mutex for reading
+	defer repo.mu.RUnlock() // Unlock the mutex when the function returns
+
+	return repo.checkUnique(entity)
+}
+
+// GetByID retrieves an entity by ID
+func (repo *InMemoryRepository[T]) GetByID(id models.ID) (T, error) {
+	repo.mu.RLock()         // Lock the mutex for reading
+	defer repo.mu.RUnlock() // Unlock the mutex when the function returns
+
+	// Retrieve the entity from the store
+	entity, ok := repo.store.Get(id)
+	if !ok {
+		var zero T
+		return zero, fmt.Errorf("%s with ID %s not found: %w", repo.name, id, ErrRecordNotFound)
+	}
+
+	// Return the entity
+	return entity, nil
+}
+
+// Update replaces an entity by ID
+func (repo *InMemoryRepository[T]) Update(id models.ID, entity T) (T, error) {
+	return repo.UpdateFunc(id, func(T) (T, error) {
+		return entity, nil
+	})
+}
+
+// UpdateFunc atomically replaces an entity by ID with the result of fn
+//
+// fn receives the stored entity and is called while holding the write lock,
+// so it must not call back into the repository. An error returned by fn aborts
+// the update and is returned as is.
+func (repo *InMemoryRepository[T]) UpdateFunc(
+	id models.ID,
+	fn func(current T) (T, error),
+) (T, error) {
+	repo.mu.Lock()         // Lock the mutex
+	defer repo.mu.Unlock() // Unlock the mutex when the function returns
+
+	// Retrieve the current entity from the store
+	current, ok := repo.store.Get(id)
+	if !ok {
+		var zero T
+		return zero, fmt.Errorf("%s with ID %s update failed: %w", repo.name, id, ErrRecordNotFound)
+	}
+
+	// Compute the updated entity, keeping the ID of the stored entity
+	entity, err := fn(current)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	entity = entity.WithID(id)
+
+	// Enforce the unique constraints
+	if err := repo.checkUnique(entity); err != nil {
+		var zero T
+		return zero, fmt.Errorf("%s with ID %s update failed: %w", repo.name, id, err)
+	}
+
+	// Store the updated entity in the store and refresh the indexes
+	repo.store.Set(id, entity)
+	for _, index := range repo.indexes {
+		index.Remove(current)
+		index.Add(entity)
+	}
+	repo.record(OpUpdate, entity)
+
+	// Return the updated entity
+	return entity, nil
+}
+
+// Delete deletes an entity by ID
+func (repo *InMemoryRepository[T]) Delete(id models.ID) error {
+	repo.mu.Lock()         // Lock the mutex
+	defer repo.mu.Unlock() // Unlock the mutex when the function returns
+
+	// Retrieve the entity so it can be dropped from the indexes
+	entity, ok := repo.store.Get(id)
+	if !ok {
+		return fmt.Errorf("%s with ID %s delete failed: %w", repo.name, id, ErrRecordNotFound)
+	}
+
+	// Delete the entity from the store
+	repo.store.Delete(id)
+	for _, index := range repo.indexes {
+		index.Remove(entity)
+	}
+	repo.record(OpDelete, entity)
+
+	// Return nil (no error)
+	return nil
+}
+
+// Ping checks that the store can be read, i.e. that no writer holds the lock
+// until the context is done
+func (repo *InMemoryRepository[T]) Ping(ctx context.Context) error {
+	for !repo.mu.TryRLock() {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s store is locked: %w", repo.name, ctx.Err())
+		case <-time.After(time.Millisecond):
+		}
+	}
+	repo.mu.RUnlock()
+	return nil
+}
+
+// Compact releases the memory still held by the store after deletions
+func (repo *InMemoryRepository[T]) Compact() {
+	repo.mu.Lock()         // Lock the mutex
+	defer repo.mu.Unlock() // Unlock the mutex when the function returns
+
+	repo.store.Compact()
+}
+
+// Len returns the number of stored entities
+func (repo *InMemoryRepository[T]) Len() int {
+	repo.mu.RLock()         // Lock the mutex for reading
+	defer repo.mu.RUnlock() // Unlock the mutex when the function returns
+
+	return repo.store.Len()
+}
+
+// GetAll retrieves all entities and total count
+func (repo *InMemoryRepository[T]) GetAll(page int, limit int) ([]T, int) {
+	repo.mu.RLock()         // Lock the mutex for reading
+	defer repo.mu.RUnlock() // Unlock the mutex when the function returns
+
+	// Handle pagination
+	if page <= 0 {
+		// If page is less than or equal to 0, set it to 1
+		page = 1
+	}
+	if limit <= 0 {
+		// If limit is less than or equal to 0, return all entities
+		limit = repo.store.Len()
+	}
+	// Calculate the offset for pagination
+	offset := (page - 1) * limit
+
+	// Check if the offset is out of bounds
+	if offset >= repo.store.Len() {
+		return []T{}, repo.store.Len()
+	}
+
+	// Retrieve all entities from the store (in-memory database) with pagination
+	entities := make([]T, 0, limit)
+
+	ids := repo.store.Keys()
+	ids = ids[offset:]
+
+	for i, id := range ids {
+		entity, _ := repo.store.Get(id)
+		entities = append(entities, entity)
+		if i == limit-1 {
+			break
+		}
+	}
+
+	// Return all entities
+	return entities, repo.store.Len()
+}
+
+// View runs fn while holding the read lock, so that fn can combine lookups on
+// the secondary indexes with lookups on the store without racing writers
+func (repo *InMemoryRepository[T]) View(fn func(get func(id models.ID) (T, bool))) {
+	repo.mu.RLock()         // Lock the mutex for reading
+	defer repo.mu.RUnlock() // Unlock the mutex when the function returns
+
+	fn(repo.store.Get)
+}
+
+// Import stores the entity under its own ID, e.g. to copy it from another repository
+//
+// The unique constraints are enforced and the write is not recorded in the outbox.
+func (repo *InMemoryRepository[T]) Import(entity T) error {
+	repo.mu.Lock()         // Lock the mutex
+	defer repo.mu.Unlock() // Unlock the mutex when the function returns
+
+	if err := repo.insert(entity); err != nil {
+		return fmt.Errorf("%s with ID %s import failed: %w", repo.name, entity.GetID(), err)
+	}
+	return nil
+}
+
+// ReplaceAll replaces every entity with the given ones, in order, under their own IDs
+//
+// The writes are not recorded in the outbox. When an entity violates a unique
+// constraint, the entities before it are kept and the others are dropped.
+func (repo *InMemoryRepository[T]) ReplaceAll(entities []T) error {
+	repo.mu.Lock()         // Lock the mutex
+	defer repo.mu.Unlock() // Unlock the mutex when the function returns
+
+	// Drop every entity from the store and the indexes
+	for _, id := range repo.store.Keys() {
+		entity, _ := repo.store.Get(id)
+		for _, index := range repo.indexes {
+			index.Remove(entity)
+		}
+	}
+	repo.store = datatypes.NewOrderedMap[models.ID, T]()
+
+	for _, entity := range entities {
+		if err := repo.insert(entity); err != nil {
+			return fmt.Errorf("%s with ID %s import failed: %w", repo.name, entity.GetID(), err)
+		}
+	}
+	return nil
+}
+
+// insert stores the entity under its own ID and tells the ID generator about
+// it, the caller must hold the write lock
+func (repo *InMemoryRepository[T]) insert(entity T) error {
+	if existing, ok := repo.store.Get(entity.GetID()); ok {
+		return &DuplicateError{Field: "id", ExistingID: existing.GetID()}
+	}
+	if err := repo.checkUnique(entity); err != nil {
+		return err
+	}
+
+	repo.store.Set(entity.GetID(), entity)
+	for _, index := range repo.indexes {
+		index.Add(entity)
+	}
+	if observer, ok := repo.idGenerator.(idgen.Observer); ok {
+		observer.Observe(string(entity.GetID()))
+	}
+	return nil
+}
+
+// record appends the write to the outbox and the history, if any, the caller
+// must hold the write lock
+func (repo *InMemoryRepository[T]) record(op WriteOp, entity T) {
+	if repo.outbox != nil {
+		repo.outbox.Record(op, entity)
+	}
+	if repo.history != nil {
+		repo.history.record(op, entity)
+	}
+}
+
+// checkUnique runs the unique indexes against the entity, the caller must hold the write lock
+func (repo *InMemoryRepository[T]) checkUnique(entity T) error {
+	for _, index := range repo.indexes {
+		if unique, ok := index.(UniqueIndex[T]); ok {
+			if err := unique.Check(entity); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package respository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/idgen"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingIndex is an Index that records the IDs it currently holds
+type countingIndex struct {
+	ids map[models.ID]bool
+}
+
+func (idx *countingIndex) Add(employee models.Employee)    { idx.ids[employee.ID] = true }
+func (idx *countingIndex) Remove(employee models.Employee) { delete(idx.ids, employee.ID) }
+
+func TestInMemoryRepository(t *testing.T) {
+	// Create a new generic in-memory repository with an index
+	index := &countingIndex{ids: map[models.ID]bool{}}
+	repo := NewInMemoryRepository[models.Employee]("employee", idgen.NewSequential(), index)
+
+	// Create an entity
+	emp, err := repo.Create(models.Employee{Name: "Ganesh Agrawal", Position: "Software Engineer"})
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, models.ID("1"), emp.ID, "ID should be 1")
+	assert.Equal(t, map[models.ID]bool{"1": true}, index.ids, "index should contain the entity")
+	assert.Equal(t, 1, repo.Len(), "length should be 1")
+
+	// Update ignores the ID of the given entity
+	emp, err = repo.Update(emp.ID, models.Employee{ID: "99", Name: "Ganesh A", Position: "Manager"})
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, models.ID("1"), emp.ID, "ID should be 1")
+	assert.Equal(t, map[models.ID]bool{"1": true}, index.ids, "index should contain the entity")
+
+	// Errors name the entity
+	_, err = repo.GetByID("2")
+	assert.ErrorIs(t, err, ErrRecordNotFound, "error message should be 'record not found'")
+	assert.Contains(t, err.Error(), "employee with ID 2", "error should name the entity")
+
+	// Delete the entity
+	err = repo.Delete(emp.ID)
+	assert.Nil(t, err, "error should be nil")
+	assert.Empty(t, index.ids, "index should be empty")
+	assert.Equal(t, 0, repo.Len(), "length should be 0")
+
+	entities, total := repo.GetAll(1, 10)
+	assert.Equal(t, 0, total, "total should be 0")
+	assert.Empty(t, entities, "entities should be empty")
+
+	// Ping fails while a writer holds the lock
+	assert.Nil(t, repo.Ping(context.Background()), "error should be nil")
+	repo.mu.Lock()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, repo.Ping(ctx), context.DeadlineExceeded, "ping should time out")
+	repo.mu.Unlock()
+}
+
+func TestInMemoryRepository_Import(t *testing.T) {
+	repo := NewEmployeeInMemoryRepository()
+	outbox := NewOutbox[models.Employee]()
+	repo.SetOutbox(outbox)
+
+	// Imported employees keep their IDs, and new IDs follow them
+	assert.Nil(t, repo.ImportEmployee(models.Employee{ID: "7", Email: "ganesh@example.com"}))
+	emp, _ := repo.CreateEmployee("Harshit Kumar", "harshit@example.com", "Engineer", 1235.00)
+	assert.Equal(t, models.ID("8"), emp.ID, "ID should follow the imported one")
+
+	// The unique constraints are enforced
+	err := repo.ImportEmployee(models.Employee{ID: "7", Email: "other@example.com"})
+	assert.ErrorIs(t, err, ErrDuplicate, "ID should be unique")
+	err = repo.ImportEmployee(models.Employee{ID: "9", Email: "GANESH@example.com"})
+	assert.ErrorIs(t, err, ErrDuplicate, "email should be unique")
+
+	// Replacing keeps the given order, and rebuilds the indexes
+	err = repo.ReplaceEmployees([]models.Employee{
+		{ID: "3", Email: "harshit@example.com"},
+		{ID: "1", Email: "rahul@example.com"},
+	})
+	assert.Nil(t, err, "error should be nil")
+	employees, total := repo.GetAllEmployees(1, -1)
+	assert.Equal(t, 2, total, "total should be 2")
+	assert.Equal(t, models.ID("3"), employees[0].ID, "order should be kept")
+	assert.Nil(t, repo.ImportEmployee(models.Employee{ID: "7", Email: "ganesh@example.com"}))
+
+	assert.Equal(t, 1, outbox.Len(), "imports should not be recorded in the outbox")
+}
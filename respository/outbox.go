@@ -0,0 +1,187 @@
+package respository
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/idgen"
+)
+
+// WriteOp is the kind of write recorded in an outbox
+type WriteOp string
+
+// Write operations
+const (
+	OpCreate WriteOp = "create"
+	OpUpdate WriteOp = "update"
+	OpDelete WriteOp = "delete"
+)
+
+// OutboxMessage is a write waiting to be published
+type OutboxMessage[T any] struct {
+	ID     string    `json:"id"`     // Deduplication ID, unique across restarts
+	Seq    uint64    `json:"seq"`    // Position in the outbox, in the order the writes were applied
+	Op     WriteOp   `json:"op"`     // Kind of write
+	Entity T         `json:"entity"` // Entity after the write, or before its deletion
+	Time   time.Time `json:"time"`
+}
+
+// Outbox records the writes of a repository in the critical section
+// of the write, so that a write is never applied without its message
+//
+// Messages stay in the outbox, and in the snapshots of the repository, until
+// they are acknowledged by the relay publishing them, or dead-lettered once
+// the outbox holds more messages than its capacity.
+type Outbox[T any] struct {
+	mu       sync.Mutex
+	seq      uint64             // Sequence number of the last message
+	messages []OutboxMessage[T] // Messages not acknowledged yet, by ascending sequence number
+	ids      idgen.IDGenerator  // Generates the deduplication IDs
+	notify   chan struct{}      // Signals new messages to the relay
+	now      func() time.Time
+
+	capacity   int                    // Messages kept, 0 when unbounded
+	deadLetter func(OutboxMessage[T]) // Called with the messages dropped unpublished
+}
+
+// NewOutbox creates a new empty outbox
+func NewOutbox[T any]() *Outbox[T] {
+	return &Outbox[T]{
+		ids:    idgen.NewULID(),
+		notify: make(chan struct{}, 1),
+		now:    time.Now,
+	}
+}
+
+// SetCapacity bounds the outbox to capacity messages, e.g. while a publisher
+// is failing: the oldest messages are dropped past it, and passed to
+// deadLetter, which must not call back into the outbox
+//
+// It must be called before the outbox is used or restored.
+func (o *Outbox[T]) SetCapacity(capacity int, deadLetter func(OutboxMessage[T])) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.capacity, o.deadLetter = capacity, deadLetter
+}
+
+// Pending returns up to limit messages following the sequence number, oldest
+// first, or all of them when limit is 0
+func (o *Outbox[T]) Pending(after uint64, limit int) []OutboxMessage[T] {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	start := o.search(after)
+	end := len(o.messages)
+	if limit > 0 {
+		end = min(end, start+limit)
+	}
+	return append(make([]OutboxMessage[T], 0, end-start), o.messages[start:end]...)
+}
+
+// Ack drops the messages up to the sequence number, they were published
+func (o *Outbox[T]) Ack(seq uint64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	acked := o.search(seq)
+	o.messages = append([]OutboxMessage[T](nil), o.messages[acked:]...)
+}
+
+// search returns the index of the first message following the sequence
+// number, the caller must hold the lock
+func (o *Outbox[T]) search(seq uint64) int {
+	i, _ := slices.BinarySearchFunc(
+		o.messages,
+		seq,
+		func(message OutboxMessage[T], seq uint64) int {
+			return cmp.Compare(message.Seq, seq+1)
+		},
+	)
+	return i
+}
+
+// trim dead-letters the oldest messages past the capacity, the caller must hold the lock
+func (o *Outbox[T]) trim() {
+	if o.capacity <= 0 || len(o.messages) <= o.capacity {
+		return
+	}
+
+	dropped := len(o.messages) - o.capacity
+	if o.deadLetter != nil {
+		for _, message := range o.messages[:dropped] {
+			o.deadLetter(message)
+		}
+	}
+	clear(o.messages[:dropped]) // Release the entities, the array is reallocated by later appends
+	o.messages = o.messages[dropped:]
+}
+
+// Len returns the number of messages not acknowledged yet
+func (o *Outbox[T]) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.messages)
+}
+
+// Notify returns a channel receiving a value after new messages are appended
+func (o *Outbox[T]) Notify() <-chan struct{} {
+	return o.notify
+}
+
+// Record appends a write, the caller must hold the write lock of the repository
+// so that the messages are in the order the writes were applied
+func (o *Outbox[T]) Record(op WriteOp, entity T) {
+	o.RecordAs(o.ids.NewID(), op, entity)
+}
+
+// RecordAs appends a write under the deduplication ID, e.g. derived from the
+// log the write was appended to, the caller must hold the write lock of the
+// repository
+func (o *Outbox[T]) RecordAs(id string, op WriteOp, entity T) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.seq++
+	o.messages = append(o.messages, OutboxMessage[T]{
+		ID:     id,
+		Seq:    o.seq,
+		Op:     op,
+		Entity: entity,
+		Time:   o.now().UTC(),
+	})
+	o.trim()
+
+	select {
+	case o.notify <- struct{}{}:
+	default: // A notification is already pending
+	}
+}
+
+// OutboxState is the content of an outbox in the snapshots
+type OutboxState[T any] struct {
+	Seq      uint64             `json:"seq"`
+	Messages []OutboxMessage[T] `json:"messages"`
+}
+
+// State returns the content of the outbox
+func (o *Outbox[T]) State() OutboxState[T] {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return OutboxState[T]{Seq: o.seq, Messages: append([]OutboxMessage[T]{}, o.messages...)}
+}
+
+// Restore replaces the content of the outbox
+func (o *Outbox[T]) Restore(state OutboxState[T]) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.seq, o.messages = state.Seq, state.Messages
+	o.trim()
+	if len(o.messages) > 0 {
+		select {
+		case o.notify <- struct{}{}:
+		default:
+		}
+	}
+}
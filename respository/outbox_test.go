@@ -0,0 +1,119 @@
+package respository
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// ops returns the write operations of the messages
+func ops(messages []OutboxMessage[models.Employee]) []WriteOp {
+	result := make([]WriteOp, 0, len(messages))
+	for _, message := range messages {
+		result = append(result, message.Op)
+	}
+	return result
+}
+
+func TestOutbox(t *testing.T) {
+	repo := NewEmployeeInMemoryRepository()
+	outbox := NewOutbox[models.Employee]()
+	repo.SetOutbox(outbox)
+
+	emp, err := repo.CreateEmployee("Ganesh Agrawal", "ganesh@example.com", "Engineer", 1234.00)
+	assert.Nil(t, err, "error should be nil")
+	_, err = repo.UpdateEmployee(emp.ID, "Ganesh Agrawal", "ganesh@example.com", "Lead", 2000.00)
+	assert.Nil(t, err, "error should be nil")
+
+	// Failed writes are not recorded
+	_, err = repo.CreateEmployee("Someone Else", "ganesh@example.com", "Intern", 1.00)
+	assert.ErrorIs(t, err, ErrDuplicate, "duplicate email should be rejected")
+	assert.Nil(t, repo.DeleteEmployee(emp.ID), "error should be nil")
+	assert.NotNil(t, repo.DeleteEmployee(emp.ID), "error should not be nil")
+
+	select {
+	case <-outbox.Notify():
+	default:
+		t.Error("writes should be notified")
+	}
+
+	messages := outbox.Pending(0, 0)
+	assert.Equal(t, []WriteOp{OpCreate, OpUpdate, OpDelete}, ops(messages))
+	assert.Equal(t, uint64(3), messages[2].Seq, "sequence numbers should follow the writes")
+	assert.Equal(t, "Lead", messages[2].Entity.Position, "deletion should carry the last state")
+	assert.NotEqual(t, messages[0].ID, messages[1].ID, "IDs should be unique")
+
+	// Pending messages can be read in batches
+	assert.Equal(t, []WriteOp{OpUpdate}, ops(outbox.Pending(1, 1)))
+
+	// Acknowledged messages are dropped
+	outbox.Ack(2)
+	assert.Equal(t, 1, outbox.Len(), "length should be 1")
+	assert.Equal(t, []WriteOp{OpDelete}, ops(outbox.Pending(0, 0)))
+}
+
+func TestOutbox_Snapshot(t *testing.T) {
+	repo := NewEmployeeInMemoryRepository()
+	outbox := NewOutbox[models.Employee]()
+	repo.SetOutbox(outbox)
+
+	repo.CreateEmployee("Ganesh Agrawal", "ganesh@example.com", "Engineer", 1234.00)
+	repo.CreateEmployee("Harshit Kumar", "harshit@example.com", "DevOps Engineer", 1235.00)
+	outbox.Ack(1)
+
+	var snapshot bytes.Buffer
+	assert.Nil(t, repo.Snapshot(&snapshot), "error should be nil")
+
+	// Messages not acknowledged are restored along with the employees
+	restored := NewEmployeeInMemoryRepository()
+	restoredOutbox := NewOutbox[models.Employee]()
+	restored.SetOutbox(restoredOutbox)
+	assert.Nil(t, restored.Restore(&snapshot), "error should be nil")
+	assert.Equal(t, 2, restored.Len(), "length should be 2")
+	assert.Equal(t, outbox.Pending(0, 0), restoredOutbox.Pending(0, 0))
+
+	// New messages continue after the restored ones
+	restored.CreateEmployee("Rahul Sharma", "rahul@example.com", "QA Engineer", 1236.00)
+	messages := restoredOutbox.Pending(0, 0)
+	assert.Equal(t, uint64(3), messages[len(messages)-1].Seq, "sequence number should be 3")
+
+	// Snapshots written without an outbox can still be restored
+	plain := `[{"id":"1","name":"Ganesh Agrawal","email":"ganesh@example.com","position":"Engineer","salary":1234}]`
+	restored = NewEmployeeInMemoryRepository()
+	restoredOutbox = NewOutbox[models.Employee]()
+	restored.SetOutbox(restoredOutbox)
+	assert.Nil(t, restored.Restore(strings.NewReader(plain)), "error should be nil")
+	assert.Equal(t, 1, restored.Len(), "length should be 1")
+	assert.Equal(t, 0, restoredOutbox.Len(), "outbox should be empty")
+}
+
+func TestOutbox_Capacity(t *testing.T) {
+	outbox := NewOutbox[models.Employee]()
+	var deadLettered []uint64
+	outbox.SetCapacity(3, func(message OutboxMessage[models.Employee]) {
+		deadLettered = append(deadLettered, message.Seq)
+	})
+
+	// The oldest messages are dead-lettered past the capacity
+	for i := 0; i < 5; i++ {
+		outbox.Record(OpCreate, models.Employee{Name: "Ganesh Agrawal"})
+	}
+	assert.Equal(t, []uint64{1, 2}, deadLettered, "oldest messages should be dead-lettered")
+	assert.Equal(t, 3, outbox.Len(), "length should be 3")
+
+	// Readers behind the dead-lettered messages resume from the oldest one kept
+	messages := outbox.Pending(1, 2)
+	assert.Equal(t, uint64(3), messages[0].Seq, "sequence number should be 3")
+	assert.Len(t, messages, 2, "limit should be applied")
+	assert.Equal(t, uint64(5), outbox.Pending(4, 0)[0].Seq, "sequence number should be 5")
+	assert.Empty(t, outbox.Pending(5, 0), "no message should follow the last one")
+
+	// Restored outboxes are bounded too
+	restored := NewOutbox[models.Employee]()
+	restored.SetCapacity(2, nil)
+	restored.Restore(outbox.State())
+	assert.Equal(t, uint64(4), restored.Pending(0, 0)[0].Seq, "sequence number should be 4")
+}
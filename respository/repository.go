@@ -0,0 +1,14 @@
+package respository
+
+import (
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+)
+
+// Repository is a generic interface for CRUD repositories of any entity
+type Repository[T models.Entity[T]] interface {
+	Create(entity T) (T, error)
+	GetByID(id models.ID) (T, error)
+	Update(id models.ID, entity T) (T, error)
+	Delete(id models.ID) error
+	GetAll(page int, limit int) ([]T, int)
+}
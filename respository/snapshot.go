@@ -0,0 +1,164 @@
+package respository
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Snapshotter is a repository whose content can be saved and restored
+type Snapshotter interface {
+	Snapshot(w io.Writer) error
+	Restore(r io.Reader) error
+}
+
+// Ensure type implements the interface
+var _ Snapshotter = (*EmployeeInMemoryRepository)(nil)
+
+// objectSnapshot is the format of the snapshots of a repository with an
+// outbox or a history
+type objectSnapshot[T any] struct {
+	Entities []T             `json:"entities"`
+	Outbox   *OutboxState[T] `json:"outbox,omitempty"`
+	History  []Version[T]    `json:"history,omitempty"`
+	// Versions before are no longer in the history, nil when none was pruned
+	HistoryPruned *time.Time `json:"history_pruned,omitempty"`
+}
+
+// Snapshot writes every entity, in insertion order, as a JSON array
+//
+// When the repository has an outbox or a history, the snapshot is an object
+// holding the entities, the messages not acknowledged yet and the versions.
+func (repo *InMemoryRepository[T]) Snapshot(w io.Writer) error {
+	repo.mu.RLock()         // Lock the mutex for reading
+	defer repo.mu.RUnlock() // Unlock the mutex when the function returns
+
+	entities := make([]T, 0, repo.store.Len())
+	for _, id := range repo.store.Keys() {
+		entity, _ := repo.store.Get(id)
+		entities = append(entities, entity)
+	}
+
+	var snapshot any = entities
+	if repo.outbox != nil || repo.history != nil {
+		object := objectSnapshot[T]{Entities: entities}
+		if repo.outbox != nil {
+			state := repo.outbox.State()
+			object.Outbox = &state
+		}
+		if repo.history != nil {
+			object.History = repo.history.state()
+			if pruned := repo.history.prunedBefore(); !pruned.IsZero() {
+				object.HistoryPruned = &pruned
+			}
+		}
+		snapshot = object
+	}
+
+	if err := json.NewEncoder(w).Encode(snapshot); err != nil {
+		return fmt.Errorf("%s snapshot failed: %w", repo.name, err)
+	}
+	return nil
+}
+
+// Restore loads the entities written by Snapshot into the empty repository
+//
+// The unique constraints are enforced and the ID generator is told about the
+// restored IDs so that new entities do not reuse them. Both formats written
+// by Snapshot are accepted, the outbox and the history are restored when both
+// have one. Without a history in the snapshot, the entities are retained as
+// existing since the restore.
+func (repo *InMemoryRepository[T]) Restore(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("%s restore failed: %w", repo.name, err)
+	}
+
+	// Snapshots without an outbox are a plain array
+	var snapshot objectSnapshot[T]
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &snapshot.Entities)
+	} else {
+		err = json.Unmarshal(trimmed, &snapshot)
+	}
+	if err != nil {
+		return fmt.Errorf("%s restore failed: %w", repo.name, err)
+	}
+	entities := snapshot.Entities
+
+	repo.mu.Lock()         // Lock the mutex
+	defer repo.mu.Unlock() // Unlock the mutex when the function returns
+
+	if repo.store.Len() > 0 {
+		return fmt.Errorf("%s restore failed: repository is not empty", repo.name)
+	}
+
+	for _, entity := range entities {
+		if err := repo.insert(entity); err != nil {
+			return fmt.Errorf("%s with ID %s restore failed: %w", repo.name, entity.GetID(), err)
+		}
+	}
+	if repo.outbox != nil {
+		var state OutboxState[T]
+		if snapshot.Outbox != nil {
+			state = *snapshot.Outbox
+		}
+		repo.outbox.Restore(state)
+	}
+	if repo.history != nil && snapshot.History != nil {
+		var pruned time.Time
+		if snapshot.HistoryPruned != nil {
+			pruned = *snapshot.HistoryPruned
+		}
+		repo.history.restore(snapshot.History, pruned)
+	} else if repo.history != nil {
+		// Snapshots without history only tell what exists from now on
+		for _, entity := range entities {
+			repo.history.record(OpCreate, entity)
+		}
+	}
+	return nil
+}
+
+// SaveSnapshotFile atomically replaces the file with a snapshot of the repository
+func SaveSnapshotFile(path string, repo Snapshotter) error {
+	// Write to a temporary file first so that a crash never leaves a partial snapshot
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if err := repo.Snapshot(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadSnapshotFile restores the repository from the file, a missing file is
+// treated as an empty snapshot
+func LoadSnapshotFile(path string, repo Snapshotter) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return repo.Restore(file)
+}
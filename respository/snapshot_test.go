@@ -0,0 +1,99 @@
+package respository
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/idgen"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "employees.json")
+
+	// Loading a missing snapshot leaves the repository empty
+	repo := NewEmployeeInMemoryRepository()
+	assert.Nil(t, LoadSnapshotFile(path, repo), "error should be nil for a missing snapshot")
+	_, total := repo.GetAllEmployees(1, -1)
+	assert.Equal(t, 0, total, "repository should be empty")
+
+	// Save a snapshot with a gap in the IDs
+	repo.CreateEmployee("Ganesh Agrawal", "ganesh@example.com", "Software Engineer", 1234.00)
+	repo.CreateEmployee("Harshit Kumar", "harshit@example.com", "DevOps Engineer", 1235.00)
+	repo.CreateEmployee("Rahul Sharma", "rahul@example.com", "QA Engineer", 1236.00)
+	repo.DeleteEmployee(models.ID("3"))
+	assert.Nil(t, SaveSnapshotFile(path, repo), "error should be nil")
+
+	// Restore it into a new repository
+	restored := NewEmployeeInMemoryRepository()
+	assert.Nil(t, LoadSnapshotFile(path, restored), "error should be nil")
+	employees, total := restored.GetAllEmployees(1, -1)
+	assert.Equal(t, 2, total, "total should be 2")
+	assert.Equal(t, models.ID("1"), employees[0].ID, "insertion order should be kept")
+	assert.Equal(t, "Harshit Kumar", employees[1].Name, "second employee should be restored")
+
+	// The indexes are rebuilt
+	_, err := restored.CreateEmployee("Someone Else", "GANESH@example.com", "Intern", 1.00)
+	assert.True(t, errors.Is(err, ErrDuplicate), "duplicate email should be rejected")
+	assert.Len(t, restored.GetEmployeesByNamePrefix("Harshit"), 1, "name index should be rebuilt")
+
+	// New IDs continue after the restored ones, the rejected create used none
+	emp, err := restored.CreateEmployee("Someone Else", "someone@example.com", "Intern", 1.00)
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, models.ID("3"), emp.ID, "ID should be 3")
+
+	// A repository can only be restored once
+	assert.NotNil(
+		t,
+		LoadSnapshotFile(path, restored),
+		"error should not be nil for a non-empty repository",
+	)
+
+	// No temporary files are left behind
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	assert.Len(t, entries, 1, "only the snapshot should be in the directory")
+}
+
+func TestAPIKeySnapshotFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api_keys.json")
+
+	repo := NewAPIKeyInMemoryRepository(idgen.NewSequential())
+	key, err := repo.Create(models.APIKey{Name: "payroll-batch", Prefix: "abcd1234", Hash: "f00d"})
+	assert.Nil(t, err, "error should be nil")
+	assert.Nil(t, SaveSnapshotFile(path, repo), "error should be nil")
+
+	// The hashes are saved, so that the keys still authenticate after a restart
+	restored := NewAPIKeyInMemoryRepository(idgen.NewSequential())
+	assert.Nil(t, LoadSnapshotFile(path, restored), "error should be nil")
+	restoredKey, err := restored.GetByPrefix("abcd1234")
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, key, restoredKey, "key should be restored with its hash")
+
+	// New IDs continue after the restored ones
+	next, err := restored.Create(models.APIKey{Name: "reporting", Prefix: "efgh5678"})
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, models.ID("2"), next.ID, "ID should be 2")
+}
+
+func TestWebhookSnapshotFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "webhooks.json")
+
+	repo := NewWebhookInMemoryRepository(idgen.NewSequential())
+	hook, err := repo.Create(models.Webhook{
+		URL:    "https://example.com/hooks",
+		Events: []string{"created"},
+		Secret: "0123456789abcdef",
+	})
+	assert.Nil(t, err, "error should be nil")
+	assert.Nil(t, SaveSnapshotFile(path, repo), "error should be nil")
+
+	// The secrets are saved, so that the deliveries are still signed after a restart
+	restored := NewWebhookInMemoryRepository(idgen.NewSequential())
+	assert.Nil(t, LoadSnapshotFile(path, restored), "error should be nil")
+	restoredHook, err := restored.GetByID(hook.ID)
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, hook, restoredHook, "webhook should be restored with its secret")
+}
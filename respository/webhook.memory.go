@@ -0,0 +1,66 @@
+package respository
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/idgen"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+)
+
+// Ensure type implements the interface
+var _ IWebhookRepository = (*WebhookInMemoryRepository)(nil)
+var _ Snapshotter = (*WebhookInMemoryRepository)(nil)
+
+// WebhookInMemoryRepository is an in-memory repository for webhooks
+type WebhookInMemoryRepository struct {
+	*InMemoryRepository[models.Webhook]
+}
+
+// NewWebhookInMemoryRepository creates a new in-memory repository for webhooks
+func NewWebhookInMemoryRepository(idGenerator idgen.IDGenerator) *WebhookInMemoryRepository {
+	return &WebhookInMemoryRepository{
+		InMemoryRepository: NewInMemoryRepository[models.Webhook]("webhook", idGenerator),
+	}
+}
+
+// storedWebhook is a webhook as saved in the snapshots, with the secret the
+// responses never include
+type storedWebhook struct {
+	models.Webhook
+	Secret string `json:"secret"` // Shadows Webhook.Secret
+}
+
+// Snapshot writes every webhook with its secret, in insertion order, as a JSON array
+func (repo *WebhookInMemoryRepository) Snapshot(w io.Writer) error {
+	hooks, _ := repo.GetAll(1, 0)
+	stored := make([]storedWebhook, 0, len(hooks))
+	for _, hook := range hooks {
+		stored = append(stored, storedWebhook{Webhook: hook, Secret: hook.Secret})
+	}
+
+	if err := json.NewEncoder(w).Encode(stored); err != nil {
+		return fmt.Errorf("webhook snapshot failed: %w", err)
+	}
+	return nil
+}
+
+// Restore loads the webhooks written by Snapshot into the empty repository
+func (repo *WebhookInMemoryRepository) Restore(r io.Reader) error {
+	var stored []storedWebhook
+	if err := json.NewDecoder(r).Decode(&stored); err != nil {
+		return fmt.Errorf("webhook restore failed: %w", err)
+	}
+	if repo.Len() > 0 {
+		return fmt.Errorf("webhook restore failed: repository is not empty")
+	}
+
+	for _, hook := range stored {
+		hook.Webhook.Secret = hook.Secret
+		if err := repo.Import(hook.Webhook); err != nil {
+			return err
+		}
+	}
+	return nil
+}
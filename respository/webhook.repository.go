@@ -0,0 +1,10 @@
+package respository
+
+import (
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+)
+
+// IWebhookRepository is an interface for webhook repository
+type IWebhookRepository interface {
+	Repository[models.Webhook]
+}
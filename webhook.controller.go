@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/config"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/internal/webhook"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/models"
+	"github.com/iamganeshagrawal/go-crud-api-assignment/respository"
+	"github.com/labstack/echo/v4"
+)
+
+// WebhookController is the controller for the webhook admin API
+type WebhookController struct {
+	dispatcher *webhook.Dispatcher
+	pagination config.PaginationConfig // Default and maximum page sizes of list requests
+}
+
+// NewWebhookController creates a new webhook controller
+func NewWebhookController(
+	dispatcher *webhook.Dispatcher,
+	pagination config.PaginationConfig,
+) *WebhookController {
+	return &WebhookController{dispatcher: dispatcher, pagination: pagination}
+}
+
+// Register registers the webhook routes on the group
+func (wc *WebhookController) Register(group *echo.Group) {
+	group.POST("", wc.CreateWebhook).Name = "webhook.create"
+	group.GET("", wc.GetAllWebhooks).Name = "webhook.list"
+	group.GET("/:id", wc.GetWebhook).Name = "webhook.get"
+	group.DELETE("/:id", wc.DeleteWebhook).Name = "webhook.delete"
+	group.GET("/:id/deliveries", wc.GetDeliveries).Name = "webhook.deliveries"
+	group.POST("/:id/deliveries/:delivery_id/redeliver", wc.Redeliver).Name = "webhook.redeliver"
+}
+
+// CreateWebhook registers a new webhook
+//
+// POST /api/v1/admin/webhooks
+func (wc *WebhookController) CreateWebhook(c echo.Context) error {
+	var body CreateWebhookRequest
+	if err := c.Bind(&body); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "invalid request body",
+		})
+	}
+
+	// Validate the request body
+	if err := body.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{"error": err})
+	}
+
+	// Register the webhook
+	hook, err := wc.dispatcher.Create(body.URL, body.Events, body.Secret)
+	if err != nil && errors.Is(err, webhook.ErrInvalidEventType) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err != nil {
+		return err
+	}
+
+	// Return the webhook, without its secret
+	return c.JSON(http.StatusCreated, hook)
+}
+
+// GetAllWebhooks retrieves all webhooks
+//
+// GET /api/v1/admin/webhooks
+func (wc *WebhookController) GetAllWebhooks(c echo.Context) error {
+	// Get the page and limit query parameters
+	page, limit, err := parsePagination(c, wc.pagination)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	// Retrieve the webhooks
+	hooks, total := wc.dispatcher.List(page, limit)
+
+	// Return the list response
+	return c.JSON(http.StatusOK, ListResponse{Page: page, Limit: limit, Total: total, Data: hooks})
+}
+
+// GetWebhook retrieves a webhook by ID
+//
+// GET /api/v1/admin/webhooks/:id
+func (wc *WebhookController) GetWebhook(c echo.Context) error {
+	// Get the webhook ID from the URL
+	id, err := models.ParseID(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid webhook ID"})
+	}
+
+	// Retrieve the webhook
+	hook, err := wc.dispatcher.Get(id)
+	if err != nil && errors.Is(err, respository.ErrRecordNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "webhook not found"})
+	}
+	if err != nil {
+		return err
+	}
+
+	// Return the webhook
+	return c.JSON(http.StatusOK, hook)
+}
+
+// DeleteWebhook removes a webhook, its pending deliveries are dropped
+//
+// DELETE /api/v1/admin/webhooks/:id
+func (wc *WebhookController) DeleteWebhook(c echo.Context) error {
+	// Get the webhook ID from the URL
+	id, err := models.ParseID(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid webhook ID"})
+	}
+
+	// Delete the webhook
+	err = wc.dispatcher.Delete(id)
+	if err != nil && errors.Is(err, respository.ErrRecordNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "webhook not found"})
+	}
+	if err != nil {
+		return err
+	}
+
+	// Return 204 if the webhook is successfully deleted
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetDeliveries retrieves the latest deliveries of a webhook, newest first
+//
+// The deliveries can be filtered by status, e.g. ?status=dead_lettered.
+//
+// GET /api/v1/admin/webhooks/:id/deliveries
+func (wc *WebhookController) GetDeliveries(c echo.Context) error {
+	// Get the webhook ID from the URL
+	id, err := models.ParseID(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid webhook ID"})
+	}
+
+	// Retrieve the deliveries
+	deliveries, err := wc.dispatcher.Deliveries(id)
+	if err != nil && errors.Is(err, respository.ErrRecordNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "webhook not found"})
+	}
+	if err != nil {
+		return err
+	}
+
+	// Filter the deliveries by status
+	if status := c.QueryParam("status"); status != "" {
+		filtered := make([]webhook.Delivery, 0, len(deliveries))
+		for _, delivery := range deliveries {
+			if delivery.Status == status {
+				filtered = append(filtered, delivery)
+			}
+		}
+		deliveries = filtered
+	}
+
+	// Return the deliveries
+	return c.JSON(http.StatusOK, deliveries)
+}
+
+// Redeliver retries a dead-lettered delivery
+//
+// POST /api/v1/admin/webhooks/:id/deliveries/:delivery_id/redeliver
+func (wc *WebhookController) Redeliver(c echo.Context) error {
+	// Get the webhook ID from the URL
+	id, err := models.ParseID(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid webhook ID"})
+	}
+
+	// Schedule the delivery
+	delivery, err := wc.dispatcher.Redeliver(id, c.Param("delivery_id"))
+	if err != nil && errors.Is(err, respository.ErrRecordNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "webhook not found"})
+	}
+	if err != nil && errors.Is(err, webhook.ErrDeliveryNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "delivery not found"})
+	}
+	if err != nil && errors.Is(err, webhook.ErrNotDeadLettered) {
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	}
+	if err != nil {
+		return err
+	}
+
+	// Return the delivery, it is attempted shortly
+	return c.JSON(http.StatusAccepted, delivery)
+}